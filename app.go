@@ -1,582 +1,560 @@
-package main
-
-import (
-	"context"
-	"errors"
-	"time"
-
-	"github.com/google/uuid"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
-)
-
-// App struct
-type App struct {
-	ctx          context.Context
-	vault        *Vault
-	masterKey    []byte
-	storage      *StorageManager
-	ipcServer    *IPCServer
-	isUnlocked   bool
-	passwordHash string
-}
-
-// NewApp creates a new App application struct
-func NewApp() *App {
-	return &App{}
-}
-
-// startup is called when the app starts
-func (a *App) startup(ctx context.Context) {
-	a.ctx = ctx
-	storage, err := NewStorageManager()
-	if err != nil {
-		panic(err)
-	}
-	a.storage = storage
-
-	// Start IPC server for browser extension
-	a.ipcServer = NewIPCServer(a)
-	if err := a.ipcServer.Start(); err != nil {
-		// Log error but don't crash - extension won't work but app will
-		println("Warning: Failed to start IPC server for browser extension:", err.Error())
-	} else {
-		println("IPC server started - browser extension ready")
-	}
-}
-
-// domReady is called after front-end resources have been loaded
-func (a *App) domReady(ctx context.Context) {
-	// Add your action here
-}
-
-// beforeClose is called when the application is about to quit
-func (a *App) beforeClose(ctx context.Context) (prevent bool) {
-	return false
-}
-
-// shutdown is called at application termination
-func (a *App) shutdown(ctx context.Context) {
-	// Stop IPC server
-	if a.ipcServer != nil {
-		a.ipcServer.Stop()
-		println("IPC server stopped")
-	}
-}
-
-// CheckVaultExists checks if a vault file already exists
-func (a *App) CheckVaultExists() bool {
-	return a.storage.VaultExists()
-}
-
-// CreateVault initializes a new vault with a master password
-func (a *App) CreateVault(masterPassword string) error {
-	if a.storage.VaultExists() {
-		return errors.New("vault already exists")
-	}
-
-	// Generate salt
-	salt, err := GenerateSalt()
-	if err != nil {
-		return err
-	}
-
-	// Derive master key
-	a.masterKey = DeriveKey(masterPassword, salt)
-	a.passwordHash = HashPassword(masterPassword)
-
-	// Create empty vault
-	a.vault = &Vault{
-		Credentials: []Credential{},
-		CreditCards: []CreditCard{},
-		Salt:        salt,
-	}
-
-	// Save vault
-	if err := a.storage.SaveVault(a.vault, a.masterKey); err != nil {
-		return err
-	}
-
-	a.isUnlocked = true
-	return nil
-}
-
-// UnlockVault unlocks an existing vault with the master password
-func (a *App) UnlockVault(masterPassword string) error {
-	if !a.storage.VaultExists() {
-		return errors.New("vault does not exist")
-	}
-
-	// Load the salt first (stored separately, unencrypted)
-	salt, err := a.storage.LoadSalt()
-	if err != nil {
-		return errors.New("vault corrupted: salt not found")
-	}
-
-	// Derive the master key using the password and salt
-	a.masterKey = DeriveKey(masterPassword, salt)
-
-	// Now load and decrypt the vault with the derived key
-	vault, err := a.storage.LoadVault(a.masterKey, salt)
-	if err != nil {
-		return err
-	}
-
-	a.vault = vault
-	a.passwordHash = HashPassword(masterPassword)
-	a.isUnlocked = true
-
-	return nil
-}
-
-// IsUnlocked checks if the vault is currently unlocked
-func (a *App) IsUnlocked() bool {
-	return a.isUnlocked
-}
-
-// ChangeMasterPassword changes the master password and re-encrypts the vault
-func (a *App) ChangeMasterPassword(currentPassword, newPassword string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	// Verify current password
-	if !VerifyPassword(currentPassword, a.passwordHash) {
-		return errors.New("current password is incorrect")
-	}
-
-	// Validate new password
-	if len(newPassword) < 8 {
-		return errors.New("new password must be at least 8 characters")
-	}
-
-	// Generate new salt
-	newSalt, err := GenerateSalt()
-	if err != nil {
-		return errors.New("failed to generate new salt")
-	}
-
-	// Derive new master key
-	newMasterKey := DeriveKey(newPassword, newSalt)
-
-	// Update vault salt
-	a.vault.Salt = newSalt
-
-	// Save vault with new key
-	if err := a.storage.SaveVault(a.vault, newMasterKey); err != nil {
-		return errors.New("failed to save vault with new password")
-	}
-
-	// Update in-memory references
-	a.masterKey = newMasterKey
-	a.passwordHash = HashPassword(newPassword)
-
-	return nil
-}
-
-// GetAllCredentials returns all credentials from the vault
-func (a *App) GetAllCredentials() ([]Credential, error) {
-	if !a.isUnlocked {
-		return nil, errors.New("vault is locked")
-	}
-	return a.vault.Credentials, nil
-}
-
-// AddCredential adds a new credential to the vault
-func (a *App) AddCredential(serviceName, urlStr, username, password, category string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	credential := Credential{
-		ID:          uuid.New().String(),
-		ServiceName: serviceName,
-		URL:         urlStr,
-		Username:    username,
-		Password:    password,
-		Category:    category,
-		IconURL:     FetchFavicon(urlStr),
-		CreatedAt:   time.Now(),
-	}
-
-	a.vault.Credentials = append(a.vault.Credentials, credential)
-
-	// Save vault
-	err := a.storage.SaveVault(a.vault, a.masterKey)
-	if err != nil {
-		return err
-	}
-
-	// Emit event to notify frontend
-	runtime.EventsEmit(a.ctx, "credentials-updated")
-
-	return nil
-}
-
-// UpdateCredential updates an existing credential
-func (a *App) UpdateCredential(id, serviceName, urlStr, username, password, category string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	for i, cred := range a.vault.Credentials {
-		if cred.ID == id {
-			a.vault.Credentials[i].ServiceName = serviceName
-			a.vault.Credentials[i].URL = urlStr
-			a.vault.Credentials[i].Username = username
-			a.vault.Credentials[i].Password = password
-			a.vault.Credentials[i].Category = category
-			a.vault.Credentials[i].IconURL = FetchFavicon(urlStr)
-
-			return a.storage.SaveVault(a.vault, a.masterKey)
-		}
-	}
-
-	return errors.New("credential not found")
-}
-
-// DeleteCredential removes a credential from the vault
-func (a *App) DeleteCredential(id string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	for i, cred := range a.vault.Credentials {
-		if cred.ID == id {
-			a.vault.Credentials = append(a.vault.Credentials[:i], a.vault.Credentials[i+1:]...)
-			return a.storage.SaveVault(a.vault, a.masterKey)
-		}
-	}
-
-	return errors.New("credential not found")
-}
-
-// ToggleFavorite toggles the favorite status of a credential
-func (a *App) ToggleFavorite(id string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	for i, cred := range a.vault.Credentials {
-		if cred.ID == id {
-			a.vault.Credentials[i].IsFavorite = !a.vault.Credentials[i].IsFavorite
-			if err := a.storage.SaveVault(a.vault, a.masterKey); err != nil {
-				return err
-			}
-			runtime.EventsEmit(a.ctx, "credentials-updated")
-			return nil
-		}
-	}
-
-	return errors.New("credential not found")
-}
-
-// CopyPassword copies a password to clipboard with auto-clear
-func (a *App) CopyPassword(id string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	for _, cred := range a.vault.Credentials {
-		if cred.ID == id {
-			return ClipboardCopy(cred.Password)
-		}
-	}
-
-	return errors.New("credential not found")
-}
-
-// CopyUsername copies a username to clipboard with auto-clear
-func (a *App) CopyUsername(id string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	for _, cred := range a.vault.Credentials {
-		if cred.ID == id {
-			return ClipboardCopy(cred.Username)
-		}
-	}
-
-	return errors.New("credential not found")
-}
-
-// GeneratePasswordWithOptions generates a password with custom options
-func (a *App) GeneratePasswordWithOptions(options PasswordGeneratorOptions) (string, error) {
-	return GeneratePassword(options)
-}
-
-// GenerateQuickPassword generates a strong password with default settings
-func (a *App) GenerateQuickPassword(length int) (string, error) {
-	if length < 8 {
-		length = 16
-	}
-	return GenerateStrongPassword(length)
-}
-
-// ============ Credit Card Methods ============
-
-// GetAllCreditCards returns all credit cards from the vault
-func (a *App) GetAllCreditCards() ([]CreditCard, error) {
-	if !a.isUnlocked {
-		return nil, errors.New("vault is locked")
-	}
-	return a.vault.CreditCards, nil
-}
-
-// AddCreditCard adds a new credit card to the vault
-func (a *App) AddCreditCard(cardName, cardholderName, cardNumber, expiryMonth, expiryYear, cvv, cardType, billingZip string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	card := CreditCard{
-		ID:             uuid.New().String(),
-		CardName:       cardName,
-		CardholderName: cardholderName,
-		CardNumber:     cardNumber,
-		ExpiryMonth:    expiryMonth,
-		ExpiryYear:     expiryYear,
-		CVV:            cvv,
-		CardType:       cardType,
-		BillingZip:     billingZip,
-		CreatedAt:      time.Now(),
-	}
-
-	a.vault.CreditCards = append(a.vault.CreditCards, card)
-
-	// Save vault
-	err := a.storage.SaveVault(a.vault, a.masterKey)
-	if err != nil {
-		return err
-	}
-
-	// Emit event to notify frontend
-	runtime.EventsEmit(a.ctx, "creditcards-updated")
-
-	return nil
-}
-
-// UpdateCreditCard updates an existing credit card
-func (a *App) UpdateCreditCard(id, cardName, cardholderName, cardNumber, expiryMonth, expiryYear, cvv, cardType, billingZip string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	for i, card := range a.vault.CreditCards {
-		if card.ID == id {
-			a.vault.CreditCards[i].CardName = cardName
-			a.vault.CreditCards[i].CardholderName = cardholderName
-			a.vault.CreditCards[i].CardNumber = cardNumber
-			a.vault.CreditCards[i].ExpiryMonth = expiryMonth
-			a.vault.CreditCards[i].ExpiryYear = expiryYear
-			a.vault.CreditCards[i].CVV = cvv
-			a.vault.CreditCards[i].CardType = cardType
-			a.vault.CreditCards[i].BillingZip = billingZip
-
-			return a.storage.SaveVault(a.vault, a.masterKey)
-		}
-	}
-
-	return errors.New("credit card not found")
-}
-
-// DeleteCreditCard removes a credit card from the vault
-func (a *App) DeleteCreditCard(id string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	for i, card := range a.vault.CreditCards {
-		if card.ID == id {
-			a.vault.CreditCards = append(a.vault.CreditCards[:i], a.vault.CreditCards[i+1:]...)
-			return a.storage.SaveVault(a.vault, a.masterKey)
-		}
-	}
-
-	return errors.New("credit card not found")
-}
-
-// ToggleCreditCardFavorite toggles the favorite status of a credit card
-func (a *App) ToggleCreditCardFavorite(id string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	for i, card := range a.vault.CreditCards {
-		if card.ID == id {
-			a.vault.CreditCards[i].IsFavorite = !a.vault.CreditCards[i].IsFavorite
-			if err := a.storage.SaveVault(a.vault, a.masterKey); err != nil {
-				return err
-			}
-			runtime.EventsEmit(a.ctx, "creditcards-updated")
-			return nil
-		}
-	}
-
-	return errors.New("credit card not found")
-}
-
-// CopyCardNumber copies a card number to clipboard with auto-clear
-func (a *App) CopyCardNumber(id string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	for _, card := range a.vault.CreditCards {
-		if card.ID == id {
-			return ClipboardCopy(card.CardNumber)
-		}
-	}
-
-	return errors.New("credit card not found")
-}
-
-// CopyCVV copies a CVV to clipboard with auto-clear
-func (a *App) CopyCVV(id string) error {
-	if !a.isUnlocked {
-		return errors.New("vault is locked")
-	}
-
-	for _, card := range a.vault.CreditCards {
-		if card.ID == id {
-			return ClipboardCopy(card.CVV)
-		}
-	}
-
-	return errors.New("credit card not found")
-}
-
-// LockVault locks the vault and clears sensitive data from memory
-func (a *App) LockVault() {
-	a.isUnlocked = false
-	a.masterKey = nil
-	a.vault = nil
-}
-
-// DeleteVault permanently deletes the vault (use with caution!)
-func (a *App) DeleteVault() error {
-	// Lock first
-	a.LockVault()
-
-	// Delete vault files
-	return a.storage.DeleteVault()
-}
-
-// ExportToCSV exports all credentials to a CSV file
-func (a *App) ExportToCSV() (string, error) {
-	if !a.isUnlocked {
-		return "", errors.New("vault is locked")
-	}
-
-	// Let user choose where to save
-	filePath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
-		DefaultFilename: "vaultzero-export.csv",
-		Title:           "Export Credentials to CSV",
-		Filters: []runtime.FileFilter{
-			{DisplayName: "CSV Files (*.csv)", Pattern: "*.csv"},
-		},
-	})
-
-	if err != nil || filePath == "" {
-		return "", errors.New("export cancelled")
-	}
-
-	// Export to CSV
-	err = ExportCredentialsToCSV(a.vault.Credentials, filePath)
-	if err != nil {
-		return "", err
-	}
-
-	return filePath, nil
-}
-
-// ExportEncryptedBackup creates an encrypted backup of the entire vault
-func (a *App) ExportEncryptedBackup() (string, error) {
-	if !a.isUnlocked {
-		return "", errors.New("vault is locked")
-	}
-
-	// Let user choose where to save
-	filePath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
-		DefaultFilename: "vaultzero-backup.vault",
-		Title:           "Export Encrypted Backup",
-		Filters: []runtime.FileFilter{
-			{DisplayName: "Vault Backup (*.vault)", Pattern: "*.vault"},
-		},
-	})
-
-	if err != nil || filePath == "" {
-		return "", errors.New("export cancelled")
-	}
-
-	// Create encrypted backup
-	err = a.storage.ExportEncryptedBackup(a.vault, a.masterKey, filePath)
-	if err != nil {
-		return "", err
-	}
-
-	return filePath, nil
-}
-
-// ImportEncryptedBackup imports credentials from an encrypted backup file
-func (a *App) ImportEncryptedBackup() (*ImportResult, error) {
-	if !a.isUnlocked {
-		return nil, errors.New("vault is locked")
-	}
-
-	// Let user choose backup file
-	filePath, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
-		Title: "Import Encrypted Backup",
-		Filters: []runtime.FileFilter{
-			{DisplayName: "Vault Backup (*.vault)", Pattern: "*.vault"},
-		},
-	})
-
-	if err != nil || filePath == "" {
-		return nil, errors.New("import cancelled")
-	}
-
-	// Load and decrypt backup
-	credentials, err := a.storage.ImportEncryptedBackup(filePath, a.masterKey)
-	if err != nil {
-		return nil, err
-	}
-
-	// Import credentials
-	result := &ImportResult{
-		TotalProcessed: len(credentials),
-		Errors:         []string{},
-	}
-
-	for _, cred := range credentials {
-		// Check if credential already exists (by URL + username)
-		exists := false
-		for _, existingCred := range a.vault.Credentials {
-			if existingCred.URL == cred.URL && existingCred.Username == cred.Username {
-				exists = true
-				break
-			}
-		}
-
-		if exists {
-			result.Skipped++
-			continue
-		}
-
-		// Add to vault
-		a.vault.Credentials = append(a.vault.Credentials, cred)
-		result.Imported++
-	}
-
-	// Save vault if any credentials were imported
-	if result.Imported > 0 {
-		if err := a.storage.SaveVault(a.vault, a.masterKey); err != nil {
-			return nil, err
-		}
-
-		// Emit event to notify frontend
-		runtime.EventsEmit(a.ctx, "credentials-updated")
-	}
-
-	return result, nil
-}
\ No newline at end of file
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"vaultzero/vault"
+)
+
+// App struct
+type App struct {
+	ctx       context.Context
+	vault     *vault.Vault
+	openStore func(location string) (vault.VaultStore, error)
+	registry  *vault.VaultRegistry
+	pairing   *pairingManager
+	approvals *approvalManager
+	ipcServer *IPCServer
+}
+
+// NewApp creates a new App application struct
+func NewApp() *App {
+	return &App{openStore: openFileStore}
+}
+
+// startup is called when the app starts
+func (a *App) startup(ctx context.Context) {
+	a.ctx = ctx
+	registry, err := vault.LoadVaultRegistry()
+	if err != nil {
+		panic(err)
+	}
+	a.registry = registry
+
+	storage, err := a.storeAt(registry.Current)
+	if err != nil {
+		panic(err)
+	}
+	a.vault = a.newVault(storage)
+
+	// Browser clients must be paired before the IPC server serves them
+	pairing, err := loadPairingManager()
+	if err != nil {
+		println("Warning: Failed to load paired browser clients:", err.Error())
+	} else {
+		a.pairing = pairing
+	}
+
+	// Paired clients still need the user's approval to read secrets
+	approvals, err := loadApprovalManager(a.promptApproval)
+	if err != nil {
+		println("Warning: Failed to load browser approvals:", err.Error())
+	} else {
+		a.approvals = approvals
+	}
+
+	// Start IPC server for browser extension
+	a.ipcServer = NewIPCServer(a)
+	if err := a.ipcServer.Start(); err != nil {
+		// Log error but don't crash - extension won't work but app will
+		println("Warning: Failed to start IPC server for browser extension:", err.Error())
+	} else {
+		println("IPC server started - browser extension ready")
+	}
+}
+
+// domReady is called after front-end resources have been loaded
+func (a *App) domReady(ctx context.Context) {
+	// Add your action here
+}
+
+// beforeClose is called when the application is about to quit
+func (a *App) beforeClose(ctx context.Context) (prevent bool) {
+	return false
+}
+
+// shutdown is called at application termination
+func (a *App) shutdown(ctx context.Context) {
+	// Stop IPC server
+	if a.ipcServer != nil {
+		a.ipcServer.Stop()
+		println("IPC server stopped")
+	}
+
+	// Clear keys and release the vault lock
+	a.LockVault()
+}
+
+// CheckVaultExists checks if a vault file already exists
+func (a *App) CheckVaultExists() bool {
+	return a.vault.Exists()
+}
+
+// CreateVault initializes a new vault with a master password. A non-empty
+// path creates it at that file or directory and switches to it; name is the
+// display name remembered in the vault registry.
+func (a *App) CreateVault(path, name, masterPassword string) error {
+	// An empty path creates the vault at the currently selected location
+	if path != "" {
+		storage, err := a.storeAt(path)
+		if err != nil {
+			return err
+		}
+		if storage.VaultExists() {
+			return vault.ErrVaultExists
+		}
+		a.switchStorage(storage)
+	}
+
+	if err := a.vault.Create(masterPassword); err != nil {
+		return err
+	}
+
+	if err := a.rememberVault(name); err != nil {
+		println("Warning: Failed to update vault registry:", err.Error())
+	}
+	return nil
+}
+
+// UnlockVault unlocks an existing vault with the master password
+func (a *App) UnlockVault(masterPassword string) error {
+	if err := a.vault.Unlock(masterPassword); err != nil {
+		return err
+	}
+
+	if inUse := a.vault.ReadOnlyReason(); inUse != nil {
+		runtime.EventsEmit(a.ctx, "vault-readonly", inUse.Error())
+	}
+	if damaged := a.vault.DamagedRecords(); len(damaged) > 0 {
+		runtime.EventsEmit(a.ctx, "vault-damaged", damaged)
+	}
+
+	return nil
+}
+
+// IsUnlocked checks if the vault is currently unlocked
+func (a *App) IsUnlocked() bool {
+	return a.vault.IsUnlocked()
+}
+
+// IsReadOnly checks if the vault was opened read-only because another process holds it
+func (a *App) IsReadOnly() bool {
+	return a.vault.IsReadOnly()
+}
+
+// ChangeMasterPassword changes the master password by rewrapping the vault data key
+func (a *App) ChangeMasterPassword(currentPassword, newPassword string) error {
+	return a.vault.ChangeMasterPassword(currentPassword, newPassword)
+}
+
+// GetAllCredentials returns all credentials from the vault
+func (a *App) GetAllCredentials() ([]vault.Credential, error) {
+	return a.vault.Credentials()
+}
+
+// AddCredential adds a new credential to the vault and returns its ID
+func (a *App) AddCredential(serviceName, urlStr, username, password, category string) (string, error) {
+	cred, err := a.vault.AddCredential(vault.Credential{
+		ServiceName: serviceName,
+		URL:         urlStr,
+		Username:    username,
+		Password:    password,
+		Category:    category,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Emit event to notify frontend
+	runtime.EventsEmit(a.ctx, "credentials-updated")
+
+	return cred.ID, nil
+}
+
+// UpdateCredential updates an existing credential
+func (a *App) UpdateCredential(id, serviceName, urlStr, username, password, category string) error {
+	return a.vault.UpdateCredential(id, func(cred *vault.Credential) error {
+		cred.ServiceName = serviceName
+		cred.URL = urlStr
+		cred.Username = username
+		cred.Password = password
+		cred.Category = category
+		cred.IconURL = vault.FetchFavicon(urlStr)
+		return nil
+	})
+}
+
+// DeleteCredential removes a credential from the vault
+func (a *App) DeleteCredential(id string) error {
+	return a.vault.DeleteCredential(id)
+}
+
+// ToggleFavorite toggles the favorite status of a credential
+func (a *App) ToggleFavorite(id string) error {
+	err := a.vault.UpdateCredential(id, func(cred *vault.Credential) error {
+		cred.IsFavorite = !cred.IsFavorite
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "credentials-updated")
+	return nil
+}
+
+// CopyPassword copies a password to clipboard with auto-clear
+func (a *App) CopyPassword(id string) error {
+	cred, err := a.vault.Credential(id)
+	if err != nil {
+		return err
+	}
+	return ClipboardCopy(cred.Password)
+}
+
+// CopyUsername copies a username to clipboard with auto-clear
+func (a *App) CopyUsername(id string) error {
+	cred, err := a.vault.Credential(id)
+	if err != nil {
+		return err
+	}
+	return ClipboardCopy(cred.Username)
+}
+
+// GeneratePasswordWithOptions generates a password with custom options
+func (a *App) GeneratePasswordWithOptions(options vault.PasswordGeneratorOptions) (string, error) {
+	return vault.GeneratePassword(options)
+}
+
+// GenerateQuickPassword generates a strong password with default settings
+func (a *App) GenerateQuickPassword(length int) (string, error) {
+	if length < 8 {
+		length = 16
+	}
+	return vault.GenerateStrongPassword(length)
+}
+
+// ============ Credit Card Methods ============
+
+// GetAllCreditCards returns all credit cards from the vault
+func (a *App) GetAllCreditCards() ([]vault.CreditCard, error) {
+	return a.vault.CreditCards()
+}
+
+// AddCreditCard adds a new credit card to the vault and returns its ID
+func (a *App) AddCreditCard(cardName, cardholderName, cardNumber, expiryMonth, expiryYear, cvv, cardType, billingZip string) (string, error) {
+	card, err := a.vault.AddCreditCard(vault.CreditCard{
+		CardName:       cardName,
+		CardholderName: cardholderName,
+		CardNumber:     cardNumber,
+		ExpiryMonth:    expiryMonth,
+		ExpiryYear:     expiryYear,
+		CVV:            cvv,
+		CardType:       cardType,
+		BillingZip:     billingZip,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Emit event to notify frontend
+	runtime.EventsEmit(a.ctx, "creditcards-updated")
+
+	return card.ID, nil
+}
+
+// UpdateCreditCard updates an existing credit card
+func (a *App) UpdateCreditCard(id, cardName, cardholderName, cardNumber, expiryMonth, expiryYear, cvv, cardType, billingZip string) error {
+	return a.vault.UpdateCreditCard(id, func(card *vault.CreditCard) error {
+		card.CardName = cardName
+		card.CardholderName = cardholderName
+		card.CardNumber = cardNumber
+		card.ExpiryMonth = expiryMonth
+		card.ExpiryYear = expiryYear
+		card.CVV = cvv
+		card.CardType = cardType
+		card.BillingZip = billingZip
+		return nil
+	})
+}
+
+// DeleteCreditCard removes a credit card from the vault
+func (a *App) DeleteCreditCard(id string) error {
+	return a.vault.DeleteCreditCard(id)
+}
+
+// ToggleCreditCardFavorite toggles the favorite status of a credit card
+func (a *App) ToggleCreditCardFavorite(id string) error {
+	err := a.vault.UpdateCreditCard(id, func(card *vault.CreditCard) error {
+		card.IsFavorite = !card.IsFavorite
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "creditcards-updated")
+	return nil
+}
+
+// CopyCardNumber copies a card number to clipboard with auto-clear
+func (a *App) CopyCardNumber(id string) error {
+	card, err := a.vault.CreditCard(id)
+	if err != nil {
+		return err
+	}
+	return ClipboardCopy(card.CardNumber)
+}
+
+// CopyCVV copies a CVV to clipboard with auto-clear
+func (a *App) CopyCVV(id string) error {
+	card, err := a.vault.CreditCard(id)
+	if err != nil {
+		return err
+	}
+	return ClipboardCopy(card.CVV)
+}
+
+// ============ Secure Note Methods ============
+
+// GetAllSecureNotes returns all secure notes from the vault
+func (a *App) GetAllSecureNotes() ([]vault.SecureNote, error) {
+	return a.vault.SecureNotes()
+}
+
+// AddSecureNote adds a new secure note with a Markdown body to the vault
+func (a *App) AddSecureNote(title, body, category string) error {
+	_, err := a.vault.AddSecureNote(vault.SecureNote{
+		Title:    title,
+		Body:     body,
+		Category: category,
+	})
+	if err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "securenotes-updated")
+	return nil
+}
+
+// UpdateSecureNote updates an existing secure note
+func (a *App) UpdateSecureNote(id, title, body, category string) error {
+	err := a.vault.UpdateSecureNote(id, func(note *vault.SecureNote) error {
+		note.Title = title
+		note.Body = body
+		note.Category = category
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "securenotes-updated")
+	return nil
+}
+
+// DeleteSecureNote removes a secure note from the vault
+func (a *App) DeleteSecureNote(id string) error {
+	if err := a.vault.DeleteSecureNote(id); err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "securenotes-updated")
+	return nil
+}
+
+// ToggleSecureNoteFavorite toggles the favorite status of a secure note
+func (a *App) ToggleSecureNoteFavorite(id string) error {
+	err := a.vault.UpdateSecureNote(id, func(note *vault.SecureNote) error {
+		note.IsFavorite = !note.IsFavorite
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "securenotes-updated")
+	return nil
+}
+
+// CopySecureNote copies a secure note's body to clipboard with auto-clear
+func (a *App) CopySecureNote(id string) error {
+	note, err := a.vault.SecureNote(id)
+	if err != nil {
+		return err
+	}
+	return ClipboardCopy(note.Body)
+}
+
+// ============ Identity Methods ============
+
+// GetAllIdentities returns all identities from the vault
+func (a *App) GetAllIdentities() ([]vault.Identity, error) {
+	return a.vault.Identities()
+}
+
+// AddIdentity adds a new identity to the vault; its ID and creation time are assigned
+func (a *App) AddIdentity(identity vault.Identity) error {
+	if _, err := a.vault.AddIdentity(identity); err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "identities-updated")
+	return nil
+}
+
+// UpdateIdentity replaces the details of the identity with the same ID,
+// keeping its favorite status, creation time and custom fields
+func (a *App) UpdateIdentity(identity vault.Identity) error {
+	err := a.vault.UpdateIdentity(identity.ID, func(stored *vault.Identity) error {
+		identity.IsFavorite = stored.IsFavorite
+		identity.CreatedAt = stored.CreatedAt
+		identity.Fields = stored.Fields
+		*stored = identity
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "identities-updated")
+	return nil
+}
+
+// DeleteIdentity removes an identity from the vault
+func (a *App) DeleteIdentity(id string) error {
+	if err := a.vault.DeleteIdentity(id); err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "identities-updated")
+	return nil
+}
+
+// ToggleIdentityFavorite toggles the favorite status of an identity
+func (a *App) ToggleIdentityFavorite(id string) error {
+	err := a.vault.UpdateIdentity(id, func(identity *vault.Identity) error {
+		identity.IsFavorite = !identity.IsFavorite
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "identities-updated")
+	return nil
+}
+
+// LockVault locks the vault and clears sensitive data from memory
+func (a *App) LockVault() {
+	// Nothing may be handed out once the vault is locked
+	if a.approvals != nil {
+		a.approvals.denyAll()
+	}
+
+	// Clear the keys and let other processes open the vault
+	if a.vault != nil {
+		a.vault.Lock()
+	}
+}
+
+// DeleteVault permanently deletes the vault (use with caution!)
+func (a *App) DeleteVault() error {
+	// Lock first
+	a.LockVault()
+
+	return a.vault.Delete()
+}
+
+// ExportToCSV exports all credentials to a CSV file
+func (a *App) ExportToCSV() (string, error) {
+	if !a.vault.IsUnlocked() {
+		return "", vault.ErrLocked
+	}
+
+	// Let user choose where to save
+	filePath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: "vaultzero-export.csv",
+		Title:           "Export Credentials to CSV",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "CSV Files (*.csv)", Pattern: "*.csv"},
+		},
+	})
+
+	if err != nil || filePath == "" {
+		return "", errors.New("export cancelled")
+	}
+
+	// Export to CSV
+	if _, err := a.vault.ExportCSV(filePath); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// ExportEncryptedBackup creates an encrypted backup of the entire vault
+func (a *App) ExportEncryptedBackup() (string, error) {
+	if !a.vault.IsUnlocked() {
+		return "", vault.ErrLocked
+	}
+
+	// Let user choose where to save
+	filePath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: "vaultzero-backup.vault",
+		Title:           "Export Encrypted Backup",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Vault Backup (*.vault)", Pattern: "*.vault"},
+		},
+	})
+
+	if err != nil || filePath == "" {
+		return "", errors.New("export cancelled")
+	}
+
+	// Create encrypted backup
+	if _, err := a.vault.ExportEncryptedBackup(filePath); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// ImportEncryptedBackup imports the items of an encrypted backup file.
+// masterPassword is only needed for backups of another vault or exported
+// under an earlier master password.
+func (a *App) ImportEncryptedBackup(masterPassword string) (*vault.ImportResult, error) {
+	if !a.vault.IsUnlocked() {
+		return nil, vault.ErrLocked
+	}
+
+	// Let user choose backup file
+	filePath, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Import Encrypted Backup",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Vault Backup (*.vault)", Pattern: "*.vault"},
+		},
+	})
+
+	if err != nil || filePath == "" {
+		return nil, errors.New("import cancelled")
+	}
+
+	result, err := a.vault.ImportEncryptedBackup(filePath, masterPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	// Emit events to notify frontend
+	if result.Imported > 0 {
+		runtime.EventsEmit(a.ctx, "credentials-updated")
+		runtime.EventsEmit(a.ctx, "creditcards-updated")
+		runtime.EventsEmit(a.ctx, "securenotes-updated")
+		runtime.EventsEmit(a.ctx, "identities-updated")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"vaultzero/ipcproto"
+	"vaultzero/vault"
+)
+
+// The browser extension only receives secrets the user approved. A request
+// that no stored grant covers emits an "approval-requested" event and blocks
+// until the user answers in the app or the prompt times out. Grants can last
+// for one request, for an origin during a time window, or always; card and
+// identity data is never covered by a stored grant and is confirmed every time.
+
+const (
+	approvalsFileName = "approvals.json"
+
+	AccessCredentials = "credentials"
+	AccessCreditCards = "creditCards"
+	AccessIdentities  = "identities"
+
+	ApprovalOnce   = "once"   // this request only
+	ApprovalWindow = "window" // the origin for ApprovalPolicy.Window
+	ApprovalAlways = "always" // the origin until revoked
+)
+
+// ApprovalPolicy controls when the extension needs the user's approval
+type ApprovalPolicy struct {
+	PromptForCredentials bool          `json:"promptForCredentials"` // false hands out logins without asking
+	Window               time.Duration `json:"window"`               // how long a "window" grant lasts
+	Timeout              time.Duration `json:"timeout"`              // how long a prompt waits for an answer
+}
+
+// DefaultApprovalPolicy prompts for everything, with 15-minute windows and
+// prompts that expire after a minute
+func DefaultApprovalPolicy() ApprovalPolicy {
+	return ApprovalPolicy{
+		PromptForCredentials: true,
+		Window:               15 * time.Minute,
+		Timeout:              time.Minute,
+	}
+}
+
+// ApprovalGrant is a stored decision letting an origin access secrets
+type ApprovalGrant struct {
+	ID        string    `json:"id"`
+	Origin    string    `json:"origin"`
+	Access    string    `json:"access"`
+	Scope     string    `json:"scope"` // ApprovalWindow or ApprovalAlways
+	Client    string    `json:"client"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt,omitempty"` // zero for ApprovalAlways
+}
+
+// ApprovalRequest is shown to the user when the extension asks for secrets
+type ApprovalRequest struct {
+	ID        string    `json:"id"`
+	Origin    string    `json:"origin"`
+	Access    string    `json:"access"`
+	Client    string    `json:"client"`
+	Scopes    []string  `json:"scopes"` // the scopes the user may choose from
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// approvalAnswer is the user's response to a pending request
+type approvalAnswer struct {
+	allow bool
+	scope string
+}
+
+// approvalManager decides which requests need a prompt and keeps the grants
+type approvalManager struct {
+	mu      sync.Mutex
+	path    string
+	policy  ApprovalPolicy
+	grants  []ApprovalGrant
+	pending map[string]chan approvalAnswer
+	prompt  func(ApprovalRequest) // shows a request to the user
+}
+
+// approvalsFile is the on-disk form of the policy and grants
+type approvalsFile struct {
+	Policy ApprovalPolicy  `json:"policy"`
+	Grants []ApprovalGrant `json:"grants"`
+}
+
+// loadApprovalManager reads the policy and grants from the app settings directory
+func loadApprovalManager(prompt func(ApprovalRequest)) (*approvalManager, error) {
+	vaultDir, err := vault.DefaultVaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	am := &approvalManager{
+		path:    filepath.Join(vaultDir, approvalsFileName),
+		policy:  DefaultApprovalPolicy(),
+		pending: make(map[string]chan approvalAnswer),
+		prompt:  prompt,
+	}
+
+	data, err := os.ReadFile(am.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return am, nil
+		}
+		return nil, err
+	}
+
+	file := approvalsFile{Policy: am.policy}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.New("approvals file is corrupted")
+	}
+	am.policy = file.Policy
+	am.grants = file.Grants
+
+	// A hand-edited or older file must not make prompts expire at once
+	defaults := DefaultApprovalPolicy()
+	if am.policy.Window <= 0 {
+		am.policy.Window = defaults.Window
+	}
+	if am.policy.Timeout <= 0 {
+		am.policy.Timeout = defaults.Timeout
+	}
+
+	return am, nil
+}
+
+// save writes the policy and grants to disk; the caller holds am.mu
+func (am *approvalManager) save() error {
+	data, err := json.MarshalIndent(approvalsFile{Policy: am.policy, Grants: am.grants}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(am.path), 0700); err != nil {
+		return err
+	}
+	return vault.WriteFileAtomic(am.path, data, 0600)
+}
+
+// authorize returns nil once access is approved, by policy, by a stored
+// grant or by the user; otherwise an ipcproto error saying why not
+func (am *approvalManager) authorize(rawURL, access, client string) error {
+	origin := originOf(rawURL)
+	if origin == "" {
+		return ipcproto.NewError(ipcproto.CodeBadRequest, "Request has no valid origin")
+	}
+
+	am.mu.Lock()
+	if access == AccessCredentials {
+		if !am.policy.PromptForCredentials || am.hasGrant(origin, access) {
+			am.mu.Unlock()
+			return nil
+		}
+	}
+
+	// Cards and identities are confirmed every time; logins may be remembered
+	scopes := []string{ApprovalOnce}
+	if access == AccessCredentials {
+		scopes = append(scopes, ApprovalWindow, ApprovalAlways)
+	}
+
+	request := ApprovalRequest{
+		ID:        uuid.New().String(),
+		Origin:    origin,
+		Access:    access,
+		Client:    client,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(am.policy.Timeout),
+	}
+	answers := make(chan approvalAnswer, 1)
+	am.pending[request.ID] = answers
+	timeout := am.policy.Timeout
+	am.mu.Unlock()
+
+	defer func() {
+		am.mu.Lock()
+		delete(am.pending, request.ID)
+		am.mu.Unlock()
+	}()
+
+	if am.prompt == nil {
+		return ipcproto.NewError(ipcproto.CodeDenied, "Approval is unavailable")
+	}
+	am.prompt(request)
+
+	select {
+	case answer := <-answers:
+		if !answer.allow {
+			return ipcproto.NewError(ipcproto.CodeDenied, "Access was denied in VaultZero")
+		}
+		// Only the scopes the prompt offered are remembered
+		if answer.scope != ApprovalOnce && offers(scopes, answer.scope) {
+			if err := am.grant(origin, access, answer.scope, client); err != nil {
+				println("Warning: Failed to store approval:", err.Error())
+			}
+		}
+		return nil
+
+	case <-time.After(timeout):
+		return ipcproto.NewError(ipcproto.CodeApprovalTimeout, "No approval in VaultZero before the prompt expired")
+	}
+}
+
+// offers reports whether scope is one of the scopes a prompt offered
+func offers(scopes []string, scope string) bool {
+	for _, offered := range scopes {
+		if offered == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGrant reports whether a live grant covers the origin; the caller holds am.mu
+func (am *approvalManager) hasGrant(origin, access string) bool {
+	now := time.Now()
+	for _, grant := range am.grants {
+		if grant.Origin != origin || grant.Access != access {
+			continue
+		}
+		if grant.Scope == ApprovalAlways || now.Before(grant.ExpiresAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// grant stores a decision covering later requests from the origin
+func (am *approvalManager) grant(origin, access, scope, client string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	grant := ApprovalGrant{
+		ID:        uuid.New().String(),
+		Origin:    origin,
+		Access:    access,
+		Scope:     scope,
+		Client:    client,
+		CreatedAt: time.Now(),
+	}
+	if scope == ApprovalWindow {
+		grant.ExpiresAt = grant.CreatedAt.Add(am.policy.Window)
+	}
+
+	am.grants = append(am.liveGrants(), grant)
+	return am.save()
+}
+
+// liveGrants drops expired grants; the caller holds am.mu
+func (am *approvalManager) liveGrants() []ApprovalGrant {
+	now := time.Now()
+	grants := []ApprovalGrant{}
+	for _, grant := range am.grants {
+		if grant.Scope == ApprovalAlways || now.Before(grant.ExpiresAt) {
+			grants = append(grants, grant)
+		}
+	}
+	return grants
+}
+
+// respond delivers the user's answer to a pending request
+func (am *approvalManager) respond(id string, allow bool, scope string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	answers, ok := am.pending[id]
+	if !ok {
+		return errors.New("approval request has expired")
+	}
+
+	select {
+	case answers <- approvalAnswer{allow: allow, scope: scope}:
+	default:
+		return errors.New("approval request was already answered")
+	}
+	return nil
+}
+
+// denyAll rejects every pending request, e.g. when the vault is locked
+func (am *approvalManager) denyAll() {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	for _, answers := range am.pending {
+		select {
+		case answers <- approvalAnswer{allow: false}:
+		default:
+		}
+	}
+}
+
+// originOf reduces a page URL to scheme://host[:port]; bare hosts are taken as https
+func originOf(rawURL string) string {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host)
+}
+
+// ListApprovals returns the stored grants that are still in effect
+func (a *App) ListApprovals() []ApprovalGrant {
+	if a.approvals == nil {
+		return []ApprovalGrant{}
+	}
+
+	a.approvals.mu.Lock()
+	defer a.approvals.mu.Unlock()
+
+	grants := a.approvals.liveGrants()
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].CreatedAt.After(grants[j].CreatedAt)
+	})
+	return grants
+}
+
+// RevokeApproval deletes a stored grant; the origin is prompted again next time
+func (a *App) RevokeApproval(id string) error {
+	if a.approvals == nil {
+		return errors.New("approval not found")
+	}
+
+	a.approvals.mu.Lock()
+	defer a.approvals.mu.Unlock()
+
+	grants := []ApprovalGrant{}
+	found := false
+	for _, grant := range a.approvals.grants {
+		if grant.ID == id {
+			found = true
+			continue
+		}
+		grants = append(grants, grant)
+	}
+	if !found {
+		return errors.New("approval not found")
+	}
+
+	a.approvals.grants = grants
+	return a.approvals.save()
+}
+
+// RespondToApproval answers a pending "approval-requested" prompt. Scope is
+// one of the request's scopes and only matters when allow is true.
+func (a *App) RespondToApproval(id string, allow bool, scope string) error {
+	if a.approvals == nil {
+		return errors.New("approval request has expired")
+	}
+	return a.approvals.respond(id, allow, scope)
+}
+
+// GetApprovalPolicy returns when the extension needs approval
+func (a *App) GetApprovalPolicy() ApprovalPolicy {
+	if a.approvals == nil {
+		return DefaultApprovalPolicy()
+	}
+
+	a.approvals.mu.Lock()
+	defer a.approvals.mu.Unlock()
+	return a.approvals.policy
+}
+
+// SetApprovalPolicy changes when the extension needs approval
+func (a *App) SetApprovalPolicy(policy ApprovalPolicy) error {
+	if a.approvals == nil {
+		return errors.New("approvals are unavailable")
+	}
+	if policy.Window <= 0 || policy.Timeout <= 0 {
+		return errors.New("approval window and timeout must be positive")
+	}
+
+	a.approvals.mu.Lock()
+	defer a.approvals.mu.Unlock()
+
+	a.approvals.policy = policy
+	return a.approvals.save()
+}
+
+// promptApproval shows an approval request in the UI
+func (a *App) promptApproval(request ApprovalRequest) {
+	runtime.EventsEmit(a.ctx, "approval-requested", request)
+}
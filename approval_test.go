@@ -0,0 +1,321 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"vaultzero/ipcproto"
+	"vaultzero/vault"
+)
+
+// newTestApprovals returns an approval manager keeping its grants in a
+// temporary settings directory. answer decides each prompt; returning nil
+// leaves it unanswered. The prompts shown are recorded in order.
+func newTestApprovals(t *testing.T, answer func(ApprovalRequest) *approvalAnswer) (*approvalManager, *[]ApprovalRequest) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	var prompts []ApprovalRequest
+	var am *approvalManager
+	am, err := loadApprovalManager(func(request ApprovalRequest) {
+		prompts = append(prompts, request)
+		if a := answer(request); a != nil {
+			if err := am.respond(request.ID, a.allow, a.scope); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return am, &prompts
+}
+
+// allowWith answers every prompt by allowing it with scope
+func allowWith(scope string) func(ApprovalRequest) *approvalAnswer {
+	return func(ApprovalRequest) *approvalAnswer {
+		return &approvalAnswer{allow: true, scope: scope}
+	}
+}
+
+func TestApprovalOnce(t *testing.T) {
+	am, prompts := newTestApprovals(t, allowWith(ApprovalOnce))
+
+	for i := 0; i < 2; i++ {
+		if err := am.authorize("https://github.com/login", AccessCredentials, "Chrome"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*prompts) != 2 {
+		t.Fatalf("got %d prompts, want one per request", len(*prompts))
+	}
+
+	request := (*prompts)[0]
+	if request.Origin != "https://github.com" || request.Access != AccessCredentials || request.Client != "Chrome" {
+		t.Errorf("prompt %+v", request)
+	}
+	if len(request.Scopes) != 3 {
+		t.Errorf("login prompt offers %v", request.Scopes)
+	}
+	if len(am.grants) != 0 {
+		t.Errorf("a once approval was stored: %+v", am.grants)
+	}
+}
+
+func TestApprovalWindow(t *testing.T) {
+	am, prompts := newTestApprovals(t, allowWith(ApprovalWindow))
+
+	if err := am.authorize("https://github.com/login", AccessCredentials, "Chrome"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The grant covers the origin, whatever the path or host case
+	if err := am.authorize("https://GitHub.com/settings", AccessCredentials, "Chrome"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*prompts) != 1 {
+		t.Fatalf("got %d prompts, want 1", len(*prompts))
+	}
+
+	// Other origins of the same site are asked separately
+	for _, url := range []string{"http://github.com", "https://gist.github.com", "https://github.com:8443"} {
+		if err := am.authorize(url, AccessCredentials, "Chrome"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*prompts) != 4 {
+		t.Fatalf("got %d prompts, want one for each new origin", len(*prompts))
+	}
+
+	// Expired grants no longer count
+	am.grants[0].ExpiresAt = time.Now().Add(-time.Second)
+	if err := am.authorize("https://github.com", AccessCredentials, "Chrome"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*prompts) != 5 {
+		t.Errorf("expired grant was used")
+	}
+}
+
+func TestApprovalAlwaysIsStored(t *testing.T) {
+	am, _ := newTestApprovals(t, allowWith(ApprovalAlways))
+	if err := am.authorize("https://github.com", AccessCredentials, "Chrome"); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := loadApprovalManager(func(ApprovalRequest) {
+		t.Error("prompted for an origin approved always")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.authorize("https://github.com/login", AccessCredentials, "Chrome"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Revoking the grant brings the prompt back
+	a := &App{approvals: reloaded}
+	grants := a.ListApprovals()
+	if len(grants) != 1 || grants[0].Scope != ApprovalAlways || !grants[0].ExpiresAt.IsZero() {
+		t.Fatalf("grants %+v", grants)
+	}
+	if err := a.RevokeApproval(grants[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RevokeApproval(grants[0].ID); err == nil {
+		t.Error("revoked a grant twice")
+	}
+	if reloaded, _ = loadApprovalManager(nil); len(reloaded.grants) != 0 {
+		t.Errorf("revoked grant is still stored: %+v", reloaded.grants)
+	}
+}
+
+func TestApprovalForCardsAndIdentities(t *testing.T) {
+	// Asking to remember the answer has no effect for cards and identities
+	am, prompts := newTestApprovals(t, allowWith(ApprovalAlways))
+
+	for _, access := range []string{AccessCreditCards, AccessCreditCards, AccessIdentities} {
+		if err := am.authorize("https://shop.example", access, "Chrome"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*prompts) != 3 {
+		t.Fatalf("got %d prompts, want one per request", len(*prompts))
+	}
+	for _, request := range *prompts {
+		if len(request.Scopes) != 1 || request.Scopes[0] != ApprovalOnce {
+			t.Errorf("%s prompt offers %v", request.Access, request.Scopes)
+		}
+	}
+	if len(am.grants) != 0 {
+		t.Errorf("stored grants %+v", am.grants)
+	}
+}
+
+func TestApprovalRefused(t *testing.T) {
+	am, _ := newTestApprovals(t, func(ApprovalRequest) *approvalAnswer {
+		return &approvalAnswer{allow: false, scope: ApprovalAlways}
+	})
+	if err := am.authorize("https://github.com", AccessCredentials, ""); errorCode(err) != ipcproto.CodeDenied {
+		t.Fatalf("denied request: %v", err)
+	}
+	if len(am.grants) != 0 {
+		t.Errorf("a denial was stored: %+v", am.grants)
+	}
+
+	if err := am.authorize("not a url", AccessCredentials, ""); errorCode(err) != ipcproto.CodeBadRequest {
+		t.Errorf("request without an origin: %v", err)
+	}
+}
+
+func TestApprovalTimeout(t *testing.T) {
+	am, _ := newTestApprovals(t, func(ApprovalRequest) *approvalAnswer { return nil })
+	am.policy.Timeout = 50 * time.Millisecond
+
+	var pending ApprovalRequest
+	am.prompt = func(request ApprovalRequest) { pending = request }
+	if err := am.authorize("https://github.com", AccessCredentials, ""); errorCode(err) != ipcproto.CodeApprovalTimeout {
+		t.Fatalf("unanswered request: %v", err)
+	}
+
+	// Answers that come too late are refused
+	if err := am.respond(pending.ID, true, ApprovalOnce); err == nil {
+		t.Error("answered an expired request")
+	}
+}
+
+func TestApprovalDeniedOnLock(t *testing.T) {
+	am, _ := newTestApprovals(t, func(ApprovalRequest) *approvalAnswer { return nil })
+	am.prompt = func(ApprovalRequest) {
+		go am.denyAll()
+	}
+	if err := am.authorize("https://github.com", AccessCredentials, ""); errorCode(err) != ipcproto.CodeDenied {
+		t.Fatalf("request pending while locking: %v", err)
+	}
+}
+
+func TestApprovalPolicy(t *testing.T) {
+	am, prompts := newTestApprovals(t, allowWith(ApprovalOnce))
+	a := &App{approvals: am}
+
+	if err := a.SetApprovalPolicy(ApprovalPolicy{Window: time.Minute}); err == nil {
+		t.Error("accepted a policy without a timeout")
+	}
+	if err := a.SetApprovalPolicy(ApprovalPolicy{Timeout: time.Minute, Window: -time.Minute}); err == nil {
+		t.Error("accepted a negative window")
+	}
+
+	policy := ApprovalPolicy{PromptForCredentials: false, Window: time.Hour, Timeout: 30 * time.Second}
+	if err := a.SetApprovalPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	// Logins are handed out without asking; cards still need a prompt
+	if err := am.authorize("https://github.com", AccessCredentials, ""); err != nil || len(*prompts) != 0 {
+		t.Fatalf("login without prompting: %v, %d prompts", err, len(*prompts))
+	}
+	if err := am.authorize("https://github.com", AccessCreditCards, ""); err != nil || len(*prompts) != 1 {
+		t.Fatalf("card with prompting off: %v, %d prompts", err, len(*prompts))
+	}
+
+	reloaded, err := loadApprovalManager(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.policy != policy {
+		t.Errorf("stored policy %+v, want %+v", reloaded.policy, policy)
+	}
+}
+
+func TestStoredPolicyIsClamped(t *testing.T) {
+	am, _ := newTestApprovals(t, allowWith(ApprovalOnce))
+	if err := os.MkdirAll(filepath.Dir(am.path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, stored := range []string{
+		`{"policy":{"promptForCredentials":true,"window":0,"timeout":0}}`,
+		`{"policy":{"promptForCredentials":true,"window":-1,"timeout":-60000000000}}`,
+		`{"policy":{"promptForCredentials":true}}`,
+	} {
+		if err := os.WriteFile(am.path, []byte(stored), 0600); err != nil {
+			t.Fatal(err)
+		}
+		reloaded, err := loadApprovalManager(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reloaded.policy != DefaultApprovalPolicy() {
+			t.Errorf("policy loaded from %s: %+v", stored, reloaded.policy)
+		}
+	}
+}
+
+func TestFillNeedsApproval(t *testing.T) {
+	allow := false
+	am, prompts := newTestApprovals(t, func(ApprovalRequest) *approvalAnswer {
+		return &approvalAnswer{allow: allow, scope: ApprovalOnce}
+	})
+
+	v := vault.New(vault.NewMemoryStore(t.Name()))
+	if err := v.Create("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(v.Lock)
+	cred, err := v.AddCredential(vault.Credential{ServiceName: "GitHub", URL: "https://github.com", Username: "octocat", Password: "hunter22"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewIPCServer(&App{vault: v, approvals: am})
+	client := &PairedClient{ID: "client", Name: "Chrome"}
+	fill := func(url string) *ipcproto.Response {
+		return s.handleRequest(client, &ipcproto.Request{
+			Version: ipcproto.Version,
+			Action:  ipcproto.ActionFill,
+			Fill:    &ipcproto.FillRequest{ID: cred.ID, URL: url},
+		})
+	}
+
+	if response := fill("https://github.com/login"); response.Success || response.Fill != nil || response.Error.Code != ipcproto.CodeDenied {
+		t.Fatalf("denied fill: %+v", response)
+	}
+
+	allow = true
+	response := fill("https://github.com/login")
+	if !response.Success || response.Fill.Password != "hunter22" {
+		t.Fatalf("approved fill: %+v", response)
+	}
+	if len(*prompts) != 2 || (*prompts)[1].Client != "Chrome" {
+		t.Fatalf("prompts %+v", *prompts)
+	}
+
+	// Another site's page is refused before the user is asked
+	if response := fill("https://gitlab.com/login"); response.Success || response.Error.Code != ipcproto.CodeNotFound {
+		t.Fatalf("fill on another site: %+v", response)
+	}
+	if len(*prompts) != 2 {
+		t.Error("prompted for another site's credential")
+	}
+}
+
+func TestOriginOf(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://github.com/login?next=/", "https://github.com"},
+		{"HTTPS://GitHub.COM", "https://github.com"},
+		{"http://github.com", "http://github.com"},
+		{"https://github.com:8443/x", "https://github.com:8443"},
+		{"github.com:8443/x", "https://github.com:8443"},
+		{"github.com", "https://github.com"},
+		{"", ""},
+		{"https://", ""},
+	}
+	for _, tt := range tests {
+		if got := originOf(tt.url); got != tt.want {
+			t.Errorf("originOf(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"vaultzero/vault"
+)
+
+// ListBackups returns the vault's backup generations with their item counts
+func (a *App) ListBackups() ([]vault.BackupInfo, error) {
+	return a.vault.ListBackups()
+}
+
+// RestoreBackup replaces the vault contents with a backup generation. The
+// backup must decrypt with the current data key; the current master password
+// stays in effect and the replaced contents become a backup themselves.
+func (a *App) RestoreBackup(id string) error {
+	if err := a.vault.RestoreBackup(id); err != nil {
+		return err
+	}
+
+	// Emit events to notify frontend
+	a.emitItemsUpdated()
+
+	return nil
+}
@@ -6,6 +6,10 @@ let nativePort = null;
 let pendingRequests = new Map();
 let requestId = 0;
 
+// Requests for secrets wait until the user approves them in VaultZero
+const REQUEST_TIMEOUT = 5000;
+const APPROVAL_TIMEOUT = 90000;
+
 // Initialize native messaging connection
 function connectNative() {
   if (nativePort) {
@@ -42,7 +46,7 @@ function connectNative() {
 }
 
 // Send message to native host
-function sendToNative(message) {
+function sendToNative(message, timeout = REQUEST_TIMEOUT) {
   return new Promise((resolve) => {
     if (!nativePort) {
       connectNative();
@@ -65,13 +69,12 @@ function sendToNative(message) {
       resolve({ success: false, error: error.message });
     }
 
-    // Timeout after 5 seconds
     setTimeout(() => {
       if (pendingRequests.has(id)) {
         pendingRequests.delete(id);
         resolve({ success: false, error: 'Request timeout' });
       }
-    }, 5000);
+    }, timeout);
   });
 }
 
@@ -85,6 +88,25 @@ function handleNativeMessage(message) {
   }
 }
 
+// The page a request is for. Content scripts run in every frame, so they are
+// trusted only for the origin of their own frame, which may be an embedded
+// site rather than the tab's page. Only extension pages may name a URL.
+function requestUrl(request, sender) {
+  if (sender.tab) {
+    return sender.origin || sender.url;
+  }
+  return request.url;
+}
+
+// The address a login is saved under: the full URL of the requesting frame
+// when it has one, otherwise its origin
+function saveUrl(request, sender) {
+  if (sender.tab && sender.url && /^https?:/.test(sender.url)) {
+    return sender.url;
+  }
+  return requestUrl(request.data || {}, sender);
+}
+
 // Handle messages from content scripts
 chrome.runtime.onMessage.addListener((request, sender, sendResponse) => {
   console.log('[VaultZero] Message from content script:', request.action);
@@ -92,20 +114,49 @@ chrome.runtime.onMessage.addListener((request, sender, sendResponse) => {
   if (request.action === 'getCredentials') {
     sendToNative({
       type: 'getCredentials',
-      data: { url: request.url }
+      data: { url: requestUrl(request, sender) }
     }).then(sendResponse);
     return true; // Keep channel open for async response
 
+  } else if (request.action === 'fill') {
+    sendToNative({
+      type: 'fill',
+      data: { id: request.id, url: requestUrl(request, sender) }
+    }, APPROVAL_TIMEOUT).then(sendResponse);
+    return true;
+
+  } else if (request.action === 'getOneTimeCode') {
+    sendToNative({
+      type: 'getOneTimeCode',
+      data: { id: request.id, url: requestUrl(request, sender) }
+    }, APPROVAL_TIMEOUT).then(sendResponse);
+    return true;
+
   } else if (request.action === 'saveCredential') {
     sendToNative({
       type: 'saveCredential',
-      data: request.data
+      data: { ...request.data, url: saveUrl(request, sender) }
     }).then(sendResponse);
     return true;
 
   } else if (request.action === 'getCreditCards') {
     sendToNative({
-      type: 'getCreditCards'
+      type: 'getCreditCards',
+      data: { url: requestUrl(request, sender) }
+    }, APPROVAL_TIMEOUT).then(sendResponse);
+    return true;
+
+  } else if (request.action === 'getIdentities') {
+    sendToNative({
+      type: 'getIdentities',
+      data: { url: requestUrl(request, sender) }
+    }, APPROVAL_TIMEOUT).then(sendResponse);
+    return true;
+
+  } else if (request.action === 'pair') {
+    sendToNative({
+      type: 'pair',
+      data: { code: request.code }
     }).then(sendResponse);
     return true;
 
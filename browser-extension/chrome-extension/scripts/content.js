@@ -34,6 +34,8 @@
     // Detect forms on page load
     detectLoginForms();
     detectPaymentForms();
+    detectIdentityForms();
+    detectOneTimeCodeFields();
 
     // Watch for dynamically added forms (SPAs)
     observeFormChanges();
@@ -361,21 +363,90 @@
     }, 100);
   }
 
-  // Fill credentials into form
+  // Fill credentials into form. Search results carry no password, so the
+  // chosen credential is fetched from VaultZero first.
   function fillCredentials(credential) {
-    if (detectedFields.username) {
-      detectedFields.username.value = credential.username;
-      detectedFields.username.dispatchEvent(new Event('input', { bubbles: true }));
-      detectedFields.username.dispatchEvent(new Event('change', { bubbles: true }));
-    }
+    chrome.runtime.sendMessage({
+      action: 'fill',
+      id: credential.id,
+      url: currentUrl
+    }, (response) => {
+      if (!response || !response.success || !response.data || !response.data.credential) {
+        showNotification(response?.error || 'VaultZero is locked or not running');
+        return;
+      }
+
+      const filled = response.data.credential;
+
+      if (detectedFields.username) {
+        detectedFields.username.value = filled.username;
+        detectedFields.username.dispatchEvent(new Event('input', { bubbles: true }));
+        detectedFields.username.dispatchEvent(new Event('change', { bubbles: true }));
+      }
+
+      if (detectedFields.password) {
+        detectedFields.password.value = filled.password;
+        detectedFields.password.dispatchEvent(new Event('input', { bubbles: true }));
+        detectedFields.password.dispatchEvent(new Event('change', { bubbles: true }));
+      }
+
+      showNotification('Auto-filled from VaultZero');
+    });
+  }
+
+  // Detect fields for two-step verification codes
+  function detectOneTimeCodeFields() {
+    const codeFields = document.querySelectorAll(
+      'input[autocomplete="one-time-code"], input[name*="otp" i], input[id*="otp" i], ' +
+      'input[name*="totp" i], input[name*="2fa" i]'
+    );
 
-    if (detectedFields.password) {
-      detectedFields.password.value = credential.password;
-      detectedFields.password.dispatchEvent(new Event('input', { bubbles: true }));
-      detectedFields.password.dispatchEvent(new Event('change', { bubbles: true }));
+    codeFields.forEach((field) => {
+      if (field.type === 'hidden' || field.dataset.vaultzeroOtpListener) {
+        return;
+      }
+      field.dataset.vaultzeroOtpListener = 'true';
+
+      field.addEventListener('focus', () => {
+        fillOneTimeCode(field);
+      });
+    });
+  }
+
+  // Fill the one-time password of this site's login. Codes are only filled
+  // when exactly one matching credential has a one-time password.
+  function fillOneTimeCode(field) {
+    if (!isVaultZeroReady || field.value) {
+      return;
     }
 
-    showNotification('Auto-filled from VaultZero');
+    chrome.runtime.sendMessage({
+      action: 'getCredentials',
+      url: currentUrl
+    }, (response) => {
+      const credentials = response?.data?.credentials || [];
+      const withCode = credentials.filter((credential) => credential.hasOneTimeCode);
+      if (withCode.length !== 1) {
+        return;
+      }
+
+      chrome.runtime.sendMessage({
+        action: 'getOneTimeCode',
+        id: withCode[0].id,
+        url: currentUrl
+      }, (response) => {
+        if (!response || !response.success || !response.data || !response.data.oneTimeCode) {
+          showNotification(response?.error || 'VaultZero is locked or not running');
+          return;
+        }
+
+        field.value = response.data.oneTimeCode.code;
+        field.dispatchEvent(new Event('input', { bubbles: true }));
+        field.dispatchEvent(new Event('change', { bubbles: true }));
+
+        showNotification('One-time code filled from VaultZero');
+      });
+    });
   }
 
   // Handle form submission (to offer saving)
@@ -521,6 +592,8 @@
   function observeFormChanges() {
     const observer = new MutationObserver(() => {
       detectLoginForms();
+      detectIdentityForms();
+      detectOneTimeCodeFields();
     });
 
     observer.observe(document.body, {
@@ -676,6 +749,8 @@
         } else {
           showCreditCardMenu(cards, cardField);
         }
+      } else if (response && response.error) {
+        showNotification(response.error);
       } else {
         showNotification('VaultZero is locked or not running');
       }
@@ -813,6 +888,239 @@
     showNotification('Credit card auto-filled from VaultZero');
   }
 
+  // ===== IDENTITY AUTO-FILL =====
+
+  // Identity values by the autocomplete tokens of the fields they fill
+  const IDENTITY_FIELDS = {
+    'given-name': (identity) => identity.firstName,
+    'additional-name': (identity) => identity.middleName,
+    'family-name': (identity) => identity.lastName,
+    'name': (identity) => [identity.firstName, identity.middleName, identity.lastName].filter(Boolean).join(' '),
+    'email': (identity) => identity.email,
+    'tel': (identity) => identity.phone,
+    'bday': (identity) => identity.dateOfBirth,
+    'street-address': (identity) => [identity.address.street1, identity.address.street2].filter(Boolean).join('\n'),
+    'address-line1': (identity) => identity.address.street1,
+    'address-line2': (identity) => identity.address.street2,
+    'address-level2': (identity) => identity.address.city,
+    'address-level1': (identity) => identity.address.region,
+    'postal-code': (identity) => identity.address.postalCode,
+    'country': (identity) => identity.address.country,
+    'country-name': (identity) => identity.address.country
+  };
+
+  // Fallback selectors for address fields without autocomplete attributes
+  const IDENTITY_FALLBACKS = {
+    'given-name': 'input[name*="first" i], input[id*="first" i]',
+    'family-name': 'input[name*="last" i], input[id*="last" i], input[name*="surname" i]',
+    'address-line1': 'input[name*="address1" i], input[name*="street" i], input[id*="street" i]',
+    'address-line2': 'input[name*="address2" i]',
+    'address-level2': 'input[name*="city" i], input[id*="city" i]',
+    'address-level1': 'input[name*="state" i], select[name*="state" i], input[name*="region" i], select[name*="region" i]',
+    'postal-code': 'input[name*="zip" i], input[id*="zip" i], input[name*="postal" i], input[name*="postcode" i]',
+    'country': 'select[name*="country" i], input[name*="country" i]'
+  };
+
+  // Fields that only make a form an address form together with others
+  const IDENTITY_ANCHORS = ['address-line1', 'street-address', 'given-name', 'name', 'postal-code'];
+
+  // Detect address and personal details forms. A form needs at least one
+  // name or address field besides the rest, so login forms asking only for an
+  // email address are left to credential filling.
+  function detectIdentityForms() {
+    const containers = new Set();
+    document.querySelectorAll('input[autocomplete], select[autocomplete]').forEach((field) => {
+      containers.add(field.closest('form') || document);
+    });
+    document.querySelectorAll('form').forEach((form) => containers.add(form));
+
+    containers.forEach((container) => {
+      const fields = findIdentityFields(container);
+      const anchor = IDENTITY_ANCHORS.map((token) => fields[token]).find(Boolean);
+      if (!anchor || Object.keys(fields).length < 2 || anchor.dataset.vaultzeroIdentityListener) {
+        return;
+      }
+
+      addIdentityIcon(anchor, container);
+
+      anchor.dataset.vaultzeroIdentityListener = 'true';
+      anchor.addEventListener('focus', () => {
+        showIdentitySelector(anchor, container);
+      });
+    });
+  }
+
+  // Map the visible identity fields of a form to autocomplete tokens
+  function findIdentityFields(container) {
+    const fields = {};
+    container.querySelectorAll('input[autocomplete], select[autocomplete], textarea[autocomplete]').forEach((field) => {
+      if (field.offsetParent === null) {
+        return;
+      }
+      // Tokens may carry section and shipping/billing prefixes
+      const tokens = field.getAttribute('autocomplete').toLowerCase().split(/\s+/);
+      const token = tokens.find((t) => IDENTITY_FIELDS[t]);
+      if (token && !fields[token]) {
+        fields[token] = field;
+      }
+    });
+
+    for (const [token, selector] of Object.entries(IDENTITY_FALLBACKS)) {
+      if (!fields[token]) {
+        const field = findPaymentField(container, selector.split(', '));
+        if (field && !field.dataset.vaultzeroCardListener) {
+          fields[token] = field;
+        }
+      }
+    }
+    return fields;
+  }
+
+  // Add VaultZero identity icon
+  function addIdentityIcon(field, container) {
+    if (field.dataset.vaultzeroIdentityIcon) {
+      return;
+    }
+    field.dataset.vaultzeroIdentityIcon = 'true';
+
+    const icon = document.createElement('div');
+    icon.className = 'vaultzero-icon vaultzero-identity-icon';
+    icon.innerHTML = `<img src="${VAULTZERO_ICON}" alt="VaultZero" />`;
+    icon.title = 'Auto-fill identity from VaultZero';
+
+    positionIcon(icon, field);
+
+    icon.addEventListener('click', (e) => {
+      e.preventDefault();
+      e.stopPropagation();
+      showIdentitySelector(field, container);
+    });
+
+    window.addEventListener('resize', () => positionIcon(icon, field));
+  }
+
+  // Show identity selector
+  function showIdentitySelector(field, container) {
+    if (!isVaultZeroReady) {
+      showNotification('VaultZero is not running or locked');
+      return;
+    }
+
+    chrome.runtime.sendMessage({
+      action: 'getIdentities'
+    }, (response) => {
+      if (response && response.success && response.data && response.data.identities) {
+        const identities = response.data.identities;
+
+        if (identities.length === 0) {
+          showNotification('No identities found in VaultZero');
+        } else if (identities.length === 1) {
+          fillIdentity(identities[0], container);
+        } else {
+          showIdentityMenu(identities, field, container);
+        }
+      } else if (response && response.error) {
+        showNotification(response.error);
+      } else {
+        showNotification('VaultZero is locked or not running');
+      }
+    });
+  }
+
+  // Show menu to select an identity
+  function showIdentityMenu(identities, field, container) {
+    const existing = document.querySelector('.vaultzero-identity-menu');
+    if (existing) {
+      existing.remove();
+    }
+
+    const menu = document.createElement('div');
+    menu.className = 'vaultzero-menu vaultzero-identity-menu';
+
+    identities.forEach((identity) => {
+      const item = document.createElement('div');
+      item.className = 'vaultzero-menu-item';
+
+      const fullName = [identity.firstName, identity.lastName].filter(Boolean).join(' ');
+      const detail = [fullName, identity.address.city].filter(Boolean).join(' • ');
+
+      const content = document.createElement('div');
+      content.innerHTML = `
+        <div class="vaultzero-menu-item-name">${escapeHtml(identity.name || fullName)}</div>
+        <div class="vaultzero-menu-item-username">${escapeHtml(detail)}</div>
+      `;
+      item.appendChild(content);
+
+      item.addEventListener('click', (e) => {
+        e.preventDefault();
+        e.stopPropagation();
+        fillIdentity(identity, container);
+        menu.remove();
+      });
+
+      menu.appendChild(item);
+    });
+
+    const rect = field.getBoundingClientRect();
+    menu.style.position = 'absolute';
+    menu.style.top = `${rect.bottom + window.scrollY + 2}px`;
+    menu.style.left = `${rect.left + window.scrollX}px`;
+    menu.style.minWidth = `${rect.width}px`;
+
+    document.body.appendChild(menu);
+
+    setTimeout(() => {
+      const closeMenu = (e) => {
+        if (!menu.contains(e.target) && e.target !== field) {
+          menu.remove();
+          document.removeEventListener('click', closeMenu);
+          document.removeEventListener('keydown', handleEscape);
+        }
+      };
+
+      const handleEscape = (e) => {
+        if (e.key === 'Escape') {
+          menu.remove();
+          document.removeEventListener('click', closeMenu);
+          document.removeEventListener('keydown', handleEscape);
+        }
+      };
+
+      document.addEventListener('click', closeMenu);
+      document.addEventListener('keydown', handleEscape);
+    }, 100);
+  }
+
+  // Fill an identity into the form's name, contact and address fields
+  function fillIdentity(identity, container) {
+    const fields = findIdentityFields(container);
+
+    for (const [token, field] of Object.entries(fields)) {
+      const value = IDENTITY_FIELDS[token](identity);
+      if (!value) {
+        continue;
+      }
+
+      if (field.tagName === 'SELECT') {
+        // Match regions and countries by option value or label
+        const wanted = value.toLowerCase();
+        const option = Array.from(field.options).find((opt) =>
+          opt.value.toLowerCase() === wanted || opt.text.trim().toLowerCase() === wanted
+        );
+        if (!option) {
+          continue;
+        }
+        field.value = option.value;
+      } else {
+        field.value = value;
+      }
+      field.dispatchEvent(new Event('input', { bubbles: true }));
+      field.dispatchEvent(new Event('change', { bubbles: true }));
+    }
+
+    showNotification('Identity auto-filled from VaultZero');
+  }
+
   // Escape HTML
   function escapeHtml(text) {
     const div = document.createElement('div');
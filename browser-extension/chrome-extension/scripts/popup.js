@@ -38,6 +38,9 @@ async function init() {
     if (!response || !response.success) {
       error.style.display = 'block';
       error.textContent = response?.error || 'Failed to get credentials';
+      if (response?.error?.startsWith('not paired')) {
+        showPairing();
+      }
       return;
     }
 
@@ -52,6 +55,27 @@ async function init() {
   });
 }
 
+// Ask for the pairing code shown in the VaultZero app and pair with it
+function showPairing() {
+  const pairing = document.getElementById('pairing');
+  const codeInput = document.getElementById('pairing-code');
+  const error = document.getElementById('error');
+
+  pairing.style.display = 'block';
+  document.getElementById('pair-button').addEventListener('click', () => {
+    chrome.runtime.sendMessage({
+      action: 'pair',
+      code: codeInput.value
+    }, (response) => {
+      if (response && response.success) {
+        window.location.reload();
+        return;
+      }
+      error.textContent = response?.error || 'Pairing failed';
+    });
+  });
+}
+
 function renderCredentials(credentials, tabId) {
   const credentialsList = document.getElementById('credentials');
   credentialsList.innerHTML = '';
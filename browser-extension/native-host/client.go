@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"vaultzero/ipcproto"
+)
+
+const clientName = "vaultzero-native-host"
+
+// vaultConn is a connection to the app. It carries one exchange at a time;
+// the host opens more while messages are handled concurrently.
+type vaultConn struct {
+	conn   net.Conn
+	frames *ipcproto.Conn
+	hello  *ipcproto.HelloResponse
+}
+
+// callVault sends an authenticated, encrypted request to the app and
+// returns its decrypted response
+func (h *host) callVault(request *ipcproto.Request) (*ipcproto.Response, error) {
+	p, err := h.loadPairing()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	request.Version = ipcproto.Version
+	request.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	request.Timestamp = time.Now().Unix()
+
+	plaintext, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := ipcproto.Seal(plaintext, p.Key, ipcproto.RequestAAD(p.ClientID))
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, err := h.exchange(&ipcproto.Request{
+		Action: ipcproto.ActionSealed,
+		Sealed: &ipcproto.SealedMessage{ClientID: p.ClientID, Payload: payload},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if envelope.Sealed == nil {
+		return nil, errors.New("invalid response from VaultZero")
+	}
+
+	decrypted, err := ipcproto.Open(envelope.Sealed.Payload, p.Key, ipcproto.ResponseAAD(p.ClientID, request.Nonce))
+	if err != nil {
+		return nil, errors.New("response from VaultZero could not be verified")
+	}
+
+	var response ipcproto.Response
+	if err := json.Unmarshal(decrypted, &response); err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, responseError(&response)
+	}
+
+	return &response, nil
+}
+
+// exchange sends one request to the app and returns its response, failing
+// with the response's error if it was not successful and not sealed. An idle
+// connection is reused; if the app went away since the last message, the
+// request is sent once more on a fresh connection.
+func (h *host) exchange(request *ipcproto.Request) (*ipcproto.Response, error) {
+	request.Version = ipcproto.Version
+
+	for attempt := 0; ; attempt++ {
+		vc, err := h.takeVaultConn()
+		if err != nil {
+			return nil, err
+		}
+
+		err = vc.frames.Send(request)
+		if err == nil {
+			var response ipcproto.Response
+			if err := vc.frames.Receive(&response); err != nil {
+				// Never resend once the app may have acted on the request
+				vc.close()
+				return nil, err
+			}
+			h.putVaultConn(vc)
+
+			// A sealed failure carries its error inside the sealed payload
+			if !response.Success && response.Sealed == nil {
+				return nil, responseError(&response)
+			}
+			return &response, nil
+		}
+
+		// The other idle connections went away with the app too
+		vc.close()
+		h.closeVaultConns()
+		if attempt > 0 {
+			return nil, err
+		}
+	}
+}
+
+// takeVaultConn returns an idle connection to the app, or opens a new one
+// if they are all busy
+func (h *host) takeVaultConn() (*vaultConn, error) {
+	h.mu.Lock()
+	if n := len(h.idle); n > 0 {
+		vc := h.idle[n-1]
+		h.idle = h.idle[:n-1]
+		h.mu.Unlock()
+		return vc, nil
+	}
+	h.mu.Unlock()
+
+	return h.openVaultConn()
+}
+
+// putVaultConn keeps a connection for the next message
+func (h *host) putVaultConn(vc *vaultConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.idle = append(h.idle, vc)
+}
+
+// openVaultConn connects to the app and negotiates the protocol version
+func (h *host) openVaultConn() (*vaultConn, error) {
+	conn, err := h.connect()
+	if err != nil {
+		return nil, fmt.Errorf("VaultZero is not running: %v", err)
+	}
+	frames := ipcproto.NewConn(conn)
+
+	hello := &ipcproto.HelloRequest{
+		Client:   clientName,
+		Versions: []int{ipcproto.Version},
+	}
+	if p, err := h.loadPairing(); err == nil {
+		hello.ClientID = p.ClientID
+	}
+
+	var response ipcproto.Response
+	err = frames.Send(&ipcproto.Request{Version: ipcproto.Version, Action: ipcproto.ActionHello, Hello: hello})
+	if err == nil {
+		err = frames.Receive(&response)
+	}
+	if err == nil && !response.Success {
+		err = responseError(&response)
+	}
+	if err == nil && response.Hello == nil {
+		err = errors.New("invalid hello from VaultZero")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	h.log.Printf("Connected to %s, protocol version %d", response.Hello.App, response.Hello.Version)
+
+	return &vaultConn{conn: conn, frames: frames, hello: response.Hello}, nil
+}
+
+// close hangs up on the app
+func (vc *vaultConn) close() {
+	vc.conn.Close()
+}
+
+// closeVaultConns drops the idle connections to the app
+func (h *host) closeVaultConns() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, vc := range h.idle {
+		vc.close()
+	}
+	h.idle = nil
+}
+
+// responseError turns a failed response into an error for the extension
+func responseError(response *ipcproto.Response) error {
+	if response.Error == nil {
+		return errors.New("request failed")
+	}
+
+	switch response.Error.Code {
+	case ipcproto.CodeNotPaired:
+		return errNotPaired
+	case ipcproto.CodeUnsupportedVersion:
+		return fmt.Errorf("VaultZero speaks an incompatible protocol version: %s", response.Error.Message)
+	}
+	return response.Error
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const socketName = "vaultzero.sock"
+
+// socketPath mirrors where the app creates its socket: $XDG_RUNTIME_DIR, or
+// a private per-user directory under the temp dir where that is not set
+func socketPath() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("vaultzero-%d", os.Getuid()))
+
+		// In a shared temp dir another user could have created the directory
+		// to stand in for the app; the app refuses such a directory too
+		info, err := os.Lstat(dir)
+		if err != nil {
+			return "", err
+		}
+		if !info.IsDir() || info.Mode().Perm() != 0700 || !ownedByCurrentUser(info) {
+			return "", errors.New("insecure IPC directory " + dir)
+		}
+	}
+	return filepath.Join(dir, socketName), nil
+}
+
+// ownedByCurrentUser reports whether a file belongs to the user running the host
+func ownedByCurrentUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
+
+// dialVault connects to the VaultZero Unix domain socket
+func dialVault() (net.Conn, error) {
+	path, err := socketPath()
+	if err != nil {
+		return nil, err
+	}
+	return net.DialTimeout("unix", path, 1*time.Second)
+}
@@ -0,0 +1,100 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vaultzero/ipcproto"
+)
+
+func TestUnixTransport(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	path, err := socketPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	vault := newFakeVault(t)
+	vault.code = strings.ReplaceAll(testPairingCode, "-", "")
+	vault.credentials = []fakeCredential{
+		{Credential: ipcproto.Credential{ID: "1", ServiceName: "GitHub", Username: "octocat"}, URL: "https://github.com", Password: "hunter2"},
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go vault.serve(conn)
+		}
+	}()
+
+	h := newHost(dialVault, filepath.Join(t.TempDir(), pairingFileName), nil)
+	defer h.closeVaultConns()
+
+	// Messages are handled concurrently, so the fill waits for the pairing
+	responses := exchangeMessages(t, h, Message{Type: "pair", ID: 1, Data: map[string]interface{}{"code": testPairingCode}})
+	if !responses[0].Success {
+		t.Fatalf("pairing over the socket failed: %s", responses[0].Error)
+	}
+
+	responses = exchangeMessages(t, h, Message{Type: "fill", ID: 2, Data: map[string]interface{}{"id": "1", "url": "https://github.com"}})
+	var filled ipcproto.FillResponse
+	responseData(t, responses[0], "credential", &filled)
+	if filled.Password != "hunter2" {
+		t.Fatalf("unexpected fill %+v", responses[0])
+	}
+}
+
+func TestSocketDirectoryIsChecked(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("TMPDIR", tempDir)
+	private := filepath.Join(tempDir, fmt.Sprintf("vaultzero-%d", os.Getuid()))
+
+	// The app creates the directory; without it there is nothing to dial
+	if _, err := socketPath(); err == nil {
+		t.Error("no error without the IPC directory")
+	}
+
+	if err := os.Mkdir(private, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := socketPath(); err != nil || path != filepath.Join(private, socketName) {
+		t.Fatalf("private directory: %q, %v", path, err)
+	}
+
+	// A directory others can write to may hold someone else's socket
+	if err := os.Chmod(private, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := socketPath(); err == nil {
+		t.Error("accepted a world-writable IPC directory")
+	}
+	if _, err := dialVault(); err == nil {
+		t.Error("dialed through a world-writable IPC directory")
+	}
+
+	// So may a link to a directory elsewhere
+	if err := os.Remove(private); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(t.TempDir(), private); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := socketPath(); err == nil {
+		t.Error("accepted a symlinked IPC directory")
+	}
+}
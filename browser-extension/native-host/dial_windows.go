@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import (
+	"net"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+)
+
+const (
+	pipeName = `\\.\pipe\vaultzero`
+)
+
+// dialVault connects to the VaultZero named pipe
+func dialVault() (net.Conn, error) {
+	timeout := 1 * time.Second
+	return winio.DialPipe(pipeName, &timeout)
+}
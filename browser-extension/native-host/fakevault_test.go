@@ -0,0 +1,247 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+
+	"vaultzero/ipcproto"
+)
+
+// fakeCredential is a login held by the fake vault
+type fakeCredential struct {
+	ipcproto.Credential
+	URL         string
+	Password    string
+	OneTimeCode string
+}
+
+// fakeVault speaks the app's side of the IPC protocol in process: hello,
+// pairing and sealed requests, with just enough of a vault behind it to
+// exercise the native host end to end.
+type fakeVault struct {
+	t *testing.T
+
+	mu          sync.Mutex
+	code        string            // pairing code on offer, empty if none
+	clients     map[string][]byte // paired client keys by ID
+	credentials []fakeCredential
+	cards       []ipcproto.CreditCard
+	identities  []ipcproto.Identity
+	saved       []ipcproto.SaveRequest
+	locked      bool
+	approval    chan struct{} // if set, fills wait for it to be closed
+	dials       int
+	conns       []net.Conn
+}
+
+func newFakeVault(t *testing.T) *fakeVault {
+	return &fakeVault{
+		t:       t,
+		clients: make(map[string][]byte),
+	}
+}
+
+// dial is the host's transport: an in-memory connection served by the fake
+func (f *fakeVault) dial() (net.Conn, error) {
+	client, server := net.Pipe()
+
+	f.mu.Lock()
+	f.dials++
+	f.conns = append(f.conns, server)
+	f.mu.Unlock()
+
+	go f.serve(server)
+	return client, nil
+}
+
+// dropConnections hangs up on every client, as if the app had restarted
+func (f *fakeVault) dropConnections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+	f.conns = nil
+}
+
+// serve answers framed requests on one connection
+func (f *fakeVault) serve(conn net.Conn) {
+	defer conn.Close()
+	frames := ipcproto.NewConn(conn)
+
+	for {
+		var request ipcproto.Request
+		if err := frames.Receive(&request); err != nil {
+			return
+		}
+
+		response := f.handle(&request)
+		response.Version = ipcproto.Version
+		if err := frames.Send(response); err != nil {
+			return
+		}
+	}
+}
+
+// handle answers the messages a client may send in the clear
+func (f *fakeVault) handle(request *ipcproto.Request) *ipcproto.Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch request.Action {
+	case ipcproto.ActionHello:
+		_, paired := f.clients[request.Hello.ClientID]
+		return &ipcproto.Response{
+			Success: true,
+			Hello: &ipcproto.HelloResponse{
+				Version: ipcproto.Version,
+				App:     "fake vault",
+				Paired:  paired,
+			},
+		}
+
+	case ipcproto.ActionPair:
+		return f.pair(request.Pair)
+
+	case ipcproto.ActionSealed:
+		key, ok := f.clients[request.Sealed.ClientID]
+		if !ok {
+			return failure(ipcproto.CodeNotPaired, "client is not paired")
+		}
+		plaintext, err := ipcproto.Open(request.Sealed.Payload, key, ipcproto.RequestAAD(request.Sealed.ClientID))
+		if err != nil {
+			return failure(ipcproto.CodeAuthFailed, "request authentication failed")
+		}
+		var inner ipcproto.Request
+		if err := json.Unmarshal(plaintext, &inner); err != nil {
+			return failure(ipcproto.CodeBadRequest, "invalid request format")
+		}
+
+		response := f.dispatch(&inner)
+		response.Version = ipcproto.Version
+		sealedResponse, err := json.Marshal(response)
+		if err != nil {
+			f.t.Error(err)
+		}
+		payload, err := ipcproto.Seal(sealedResponse, key, ipcproto.ResponseAAD(request.Sealed.ClientID, inner.Nonce))
+		if err != nil {
+			f.t.Error(err)
+		}
+		return &ipcproto.Response{
+			Success: response.Success,
+			Sealed:  &ipcproto.SealedMessage{ClientID: request.Sealed.ClientID, Payload: payload},
+		}
+
+	default:
+		return failure(ipcproto.CodeNotPaired, "requests must be sealed")
+	}
+}
+
+// pair checks the proof of the pairing code and hands out a client key
+func (f *fakeVault) pair(request *ipcproto.PairRequest) *ipcproto.Response {
+	if f.code == "" {
+		return failure(ipcproto.CodePairingFailed, "no pairing in progress")
+	}
+	pairingKey, err := ipcproto.PairingKey(f.code, request.Nonce)
+	if err != nil {
+		return failure(ipcproto.CodePairingFailed, err.Error())
+	}
+	if !hmac.Equal(request.Proof, ipcproto.PairingProof(pairingKey, request.ClientID)) {
+		return failure(ipcproto.CodePairingFailed, "wrong pairing code")
+	}
+	f.code = ""
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return failure(ipcproto.CodeFailed, err.Error())
+	}
+	sealedKey, err := ipcproto.Seal(key, pairingKey, ipcproto.PairResponseAAD(request.ClientID))
+	if err != nil {
+		return failure(ipcproto.CodeFailed, err.Error())
+	}
+	f.clients[request.ClientID] = key
+
+	return &ipcproto.Response{Success: true, Pairing: &ipcproto.PairResponse{Key: sealedKey}}
+}
+
+// dispatch answers an authenticated request
+func (f *fakeVault) dispatch(request *ipcproto.Request) *ipcproto.Response {
+	if request.Version != ipcproto.Version {
+		return failure(ipcproto.CodeUnsupportedVersion, "unsupported protocol version")
+	}
+	if f.locked {
+		return failure(ipcproto.CodeVaultLocked, "Vault is locked")
+	}
+
+	switch request.Action {
+	case ipcproto.ActionSearch:
+		matching := []ipcproto.Credential{}
+		for _, cred := range f.credentials {
+			if sameHost(cred.URL, request.Search.URL) {
+				matching = append(matching, cred.Credential)
+			}
+		}
+		return &ipcproto.Response{Success: true, Credentials: matching}
+
+	case ipcproto.ActionFill:
+		// Other connections are served while the user decides
+		if approval := f.approval; approval != nil {
+			f.mu.Unlock()
+			<-approval
+			f.mu.Lock()
+		}
+		for _, cred := range f.credentials {
+			if cred.ID == request.Fill.ID && sameHost(cred.URL, request.Fill.URL) {
+				return &ipcproto.Response{Success: true, Fill: &ipcproto.FillResponse{
+					ID:       cred.ID,
+					Username: cred.Username,
+					Password: cred.Password,
+				}}
+			}
+		}
+		return failure(ipcproto.CodeNotFound, "No such credential for this site")
+
+	case ipcproto.ActionOneTimeCode:
+		for _, cred := range f.credentials {
+			if cred.ID == request.OneTimeCode.ID && sameHost(cred.URL, request.OneTimeCode.URL) && cred.OneTimeCode != "" {
+				return &ipcproto.Response{Success: true, OneTimeCode: &ipcproto.OneTimeCodeResponse{
+					ID:               cred.ID,
+					Code:             cred.OneTimeCode,
+					SecondsRemaining: 17,
+				}}
+			}
+		}
+		return failure(ipcproto.CodeNotFound, "No one-time password for this site")
+
+	case ipcproto.ActionSave:
+		f.saved = append(f.saved, *request.Save)
+		return &ipcproto.Response{Success: true}
+
+	case ipcproto.ActionGetCreditCards:
+		return &ipcproto.Response{Success: true, CreditCards: f.cards}
+
+	case ipcproto.ActionGetIdentities:
+		return &ipcproto.Response{Success: true, Identities: f.identities}
+
+	default:
+		return failure(ipcproto.CodeUnknownAction, "Unknown action: "+request.Action)
+	}
+}
+
+// sameHost is the fake's stand-in for the app's URL matching
+func sameHost(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	return errA == nil && errB == nil && ua.Host != "" && ua.Host == ub.Host
+}
+
+func failure(code ipcproto.ErrorCode, message string) *ipcproto.Response {
+	return &ipcproto.Response{Success: false, Error: ipcproto.NewError(code, message)}
+}
@@ -2,10 +2,16 @@ module vaultzero-native-host
 
 go 1.21
 
-require github.com/Microsoft/go-winio v0.6.1
+require (
+	github.com/Microsoft/go-winio v0.6.1
+	vaultzero/ipcproto v0.0.0
+)
 
 require (
+	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/mod v0.8.0 // indirect
-	golang.org/x/sys v0.9.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/tools v0.6.0 // indirect
 )
+
+replace vaultzero/ipcproto => ../../ipcproto
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"vaultzero/ipcproto"
+)
+
+// host answers the extension's native messages by talking to the VaultZero
+// app. The way it reaches the app is injected, so the messaging core is the
+// same on every platform and can run against a fake app in tests.
+type host struct {
+	dial        func() (net.Conn, error) // opens a connection to the app
+	dialTimeout time.Duration            // how long to keep trying while the app starts
+	pairingPath string                   // where the pairing with the app is stored
+	log         *log.Logger
+
+	mu   sync.Mutex
+	idle []*vaultConn // connections to the app kept open across messages
+}
+
+// newHost creates a host that reaches the app with dial
+func newHost(dial func() (net.Conn, error), pairingPath string, logger *log.Logger) *host {
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+	return &host{
+		dial:        dial,
+		dialTimeout: 2 * time.Second,
+		pairingPath: pairingPath,
+		log:         logger,
+	}
+}
+
+// serve answers messages from in on out until in is closed. Messages are
+// handled concurrently and answered as they complete, so a fill waiting for
+// the user's approval holds up nothing else; the extension matches responses
+// to its requests by ID. Connections to the app stay open for the whole
+// browser session.
+func (h *host) serve(in io.Reader, out io.Writer) error {
+	defer h.closeVaultConns()
+
+	var (
+		wg      sync.WaitGroup
+		outMu   sync.Mutex
+		sendErr error
+	)
+	send := func(response *Response) error {
+		outMu.Lock()
+		defer outMu.Unlock()
+		if sendErr == nil {
+			sendErr = sendMessage(out, response)
+		}
+		return sendErr
+	}
+
+	for {
+		msg, err := readMessage(in)
+		if err != nil {
+			if err == io.EOF {
+				wg.Wait()
+				return sendErr
+			}
+			// A broken or oversized length prefix leaves the stream out
+			// of step for good
+			if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, errMessageTooLarge) {
+				wg.Wait()
+				return err
+			}
+			h.log.Printf("Failed to read message: %v", err)
+			if err := send(&Response{
+				Type:    "error",
+				Success: false,
+				Error:   fmt.Sprintf("Failed to read message: %v", err),
+			}); err != nil {
+				wg.Wait()
+				return err
+			}
+			continue
+		}
+
+		h.log.Printf("Received: %s", msg.Type)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := send(h.handleMessage(msg)); err != nil {
+				h.log.Printf("Failed to send response: %v", err)
+			}
+		}()
+	}
+}
+
+// handleMessage processes a message and returns a response
+func (h *host) handleMessage(msg *Message) *Response {
+	switch msg.Type {
+	case "ping":
+		return &Response{
+			Type:    "pong",
+			ID:      msg.ID,
+			Success: true,
+			Data:    map[string]interface{}{"status": "alive"},
+		}
+
+	case "getCredentials":
+		url, _ := msg.Data["url"].(string)
+		credentials, err := h.getCredentialsFromVault(url)
+		if err != nil {
+			return &Response{
+				Type:    "credentials",
+				ID:      msg.ID,
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		return &Response{
+			Type:    "credentials",
+			ID:      msg.ID,
+			Success: true,
+			Data:    map[string]interface{}{"credentials": credentials},
+		}
+
+	case "fill":
+		id, _ := msg.Data["id"].(string)
+		url, _ := msg.Data["url"].(string)
+		credential, err := h.fillCredentialFromVault(id, url)
+		if err != nil {
+			return &Response{
+				Type:    "filled",
+				ID:      msg.ID,
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		return &Response{
+			Type:    "filled",
+			ID:      msg.ID,
+			Success: true,
+			Data:    map[string]interface{}{"credential": credential},
+		}
+
+	case "getOneTimeCode":
+		id, _ := msg.Data["id"].(string)
+		url, _ := msg.Data["url"].(string)
+		code, err := h.getOneTimeCodeFromVault(id, url)
+		if err != nil {
+			return &Response{
+				Type:    "oneTimeCode",
+				ID:      msg.ID,
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		return &Response{
+			Type:    "oneTimeCode",
+			ID:      msg.ID,
+			Success: true,
+			Data:    map[string]interface{}{"oneTimeCode": code},
+		}
+
+	case "saveCredential":
+		err := h.saveCredentialToVault(msg.Data)
+		if err != nil {
+			return &Response{
+				Type:    "saved",
+				ID:      msg.ID,
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		return &Response{
+			Type:    "saved",
+			ID:      msg.ID,
+			Success: true,
+		}
+
+	case "getCreditCards":
+		url, _ := msg.Data["url"].(string)
+		cards, err := h.getCreditCardsFromVault(url)
+		if err != nil {
+			return &Response{
+				Type:    "creditCards",
+				ID:      msg.ID,
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		return &Response{
+			Type:    "creditCards",
+			ID:      msg.ID,
+			Success: true,
+			Data:    map[string]interface{}{"cards": cards},
+		}
+
+	case "getIdentities":
+		url, _ := msg.Data["url"].(string)
+		identities, err := h.getIdentitiesFromVault(url)
+		if err != nil {
+			return &Response{
+				Type:    "identities",
+				ID:      msg.ID,
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		return &Response{
+			Type:    "identities",
+			ID:      msg.ID,
+			Success: true,
+			Data:    map[string]interface{}{"identities": identities},
+		}
+
+	case "pair":
+		code, _ := msg.Data["code"].(string)
+		if err := h.pairWithVault(code); err != nil {
+			return &Response{
+				Type:    "paired",
+				ID:      msg.ID,
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+		return &Response{
+			Type:    "paired",
+			ID:      msg.ID,
+			Success: true,
+		}
+
+	default:
+		return &Response{
+			Type:    "error",
+			ID:      msg.ID,
+			Success: false,
+			Error:   "Unknown message type: " + msg.Type,
+		}
+	}
+}
+
+// getCredentialsFromVault asks the VaultZero app for credentials matching url
+func (h *host) getCredentialsFromVault(url string) ([]ipcproto.Credential, error) {
+	response, err := h.callVault(&ipcproto.Request{
+		Action: ipcproto.ActionSearch,
+		Search: &ipcproto.SearchRequest{URL: url},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Credentials == nil {
+		return []ipcproto.Credential{}, nil
+	}
+	return response.Credentials, nil
+}
+
+// fillCredentialFromVault asks the VaultZero app for the password of one
+// credential, to fill it into the page at url
+func (h *host) fillCredentialFromVault(id, url string) (*ipcproto.FillResponse, error) {
+	response, err := h.callVault(&ipcproto.Request{
+		Action: ipcproto.ActionFill,
+		Fill:   &ipcproto.FillRequest{ID: id, URL: url},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Fill == nil {
+		return nil, errors.New("invalid response from VaultZero")
+	}
+	return response.Fill, nil
+}
+
+// getOneTimeCodeFromVault asks the VaultZero app for the current one-time
+// password of one credential, to fill it into the page at url
+func (h *host) getOneTimeCodeFromVault(id, url string) (*ipcproto.OneTimeCodeResponse, error) {
+	response, err := h.callVault(&ipcproto.Request{
+		Action:      ipcproto.ActionOneTimeCode,
+		OneTimeCode: &ipcproto.OneTimeCodeRequest{ID: id, URL: url},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.OneTimeCode == nil {
+		return nil, errors.New("invalid response from VaultZero")
+	}
+	return response.OneTimeCode, nil
+}
+
+// saveCredentialToVault saves a credential through the VaultZero app
+func (h *host) saveCredentialToVault(data map[string]interface{}) error {
+	save := &ipcproto.SaveRequest{}
+	save.ServiceName, _ = data["serviceName"].(string)
+	save.URL, _ = data["url"].(string)
+	save.Username, _ = data["username"].(string)
+	save.Password, _ = data["password"].(string)
+	save.Category, _ = data["category"].(string)
+
+	_, err := h.callVault(&ipcproto.Request{
+		Action: ipcproto.ActionSave,
+		Save:   save,
+	})
+	return err
+}
+
+// getCreditCardsFromVault gets all credit cards from the VaultZero app for
+// filling the page at url
+func (h *host) getCreditCardsFromVault(url string) ([]ipcproto.CreditCard, error) {
+	response, err := h.callVault(&ipcproto.Request{
+		Action:      ipcproto.ActionGetCreditCards,
+		CreditCards: &ipcproto.CreditCardsRequest{URL: url},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.CreditCards == nil {
+		return []ipcproto.CreditCard{}, nil
+	}
+	return response.CreditCards, nil
+}
+
+// getIdentitiesFromVault gets all identities from the VaultZero app for
+// filling address and personal details forms on the page at url
+func (h *host) getIdentitiesFromVault(url string) ([]ipcproto.Identity, error) {
+	response, err := h.callVault(&ipcproto.Request{
+		Action:     ipcproto.ActionGetIdentities,
+		Identities: &ipcproto.IdentitiesRequest{URL: url},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Identities == nil {
+		return []ipcproto.Identity{}, nil
+	}
+	return response.Identities, nil
+}
+
+// connect reaches the running VaultZero app through the host's transport,
+// retrying briefly while it starts up
+func (h *host) connect() (net.Conn, error) {
+	deadline := time.Now().Add(h.dialTimeout)
+
+	for time.Now().Before(deadline) {
+		conn, err := h.dial()
+		if err == nil {
+			return conn, nil
+		}
+
+		h.log.Printf("Connection attempt failed: %v", err)
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return nil, fmt.Errorf("connection timeout - is VaultZero running and unlocked?")
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"vaultzero/ipcproto"
+)
+
+const testPairingCode = "ABCD-EFGH"
+
+// newTestHost creates a host that talks to the fake vault
+func newTestHost(t *testing.T, vault *fakeVault) *host {
+	h := newHost(vault.dial, filepath.Join(t.TempDir(), pairingFileName), nil)
+	h.dialTimeout = 200 * time.Millisecond
+	t.Cleanup(h.closeVaultConns)
+	return h
+}
+
+// exchangeMessages runs the host over native messages as the browser sends
+// them and returns its responses
+func exchangeMessages(t *testing.T, h *host, messages ...Message) []Response {
+	t.Helper()
+
+	var in bytes.Buffer
+	for _, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binary.Write(&in, binary.LittleEndian, uint32(len(data)))
+		in.Write(data)
+	}
+
+	var out bytes.Buffer
+	if err := h.serve(&in, &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	var responses []Response
+	for out.Len() > 0 {
+		var length uint32
+		if err := binary.Read(&out, binary.LittleEndian, &length); err != nil {
+			t.Fatal(err)
+		}
+		var response Response
+		if err := json.Unmarshal(out.Next(int(length)), &response); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, response)
+	}
+
+	if len(responses) != len(messages) {
+		t.Fatalf("got %d responses to %d messages", len(responses), len(messages))
+	}
+
+	// Responses come back as they are ready; put them in the order asked
+	byID := make(map[int]Response)
+	for _, response := range responses {
+		byID[response.ID] = response
+	}
+	for i, msg := range messages {
+		response, ok := byID[msg.ID]
+		if !ok {
+			t.Fatalf("no response to message %d", msg.ID)
+		}
+		responses[i] = response
+	}
+	return responses
+}
+
+// responseData decodes one entry of a response's data
+func responseData(t *testing.T, response Response, key string, v interface{}) {
+	t.Helper()
+
+	data, err := json.Marshal(response.Data[key])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pairedHost returns a host that has paired with the fake vault
+func pairedHost(t *testing.T, vault *fakeVault) *host {
+	t.Helper()
+
+	vault.code = strings.ReplaceAll(testPairingCode, "-", "")
+	h := newTestHost(t, vault)
+	responses := exchangeMessages(t, h, Message{Type: "pair", ID: 1, Data: map[string]interface{}{"code": strings.ToLower(testPairingCode)}})
+	if !responses[0].Success {
+		t.Fatalf("pairing failed: %s", responses[0].Error)
+	}
+	return h
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	response := &Response{Type: "pong", ID: 7, Success: true, Data: map[string]interface{}{"status": "alive"}}
+	if err := sendMessage(&buf, response); err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages are prefixed with their length in native byte order
+	if got := binary.LittleEndian.Uint32(buf.Bytes()); int(got) != buf.Len()-4 {
+		t.Fatalf("length prefix %d, body %d bytes", got, buf.Len()-4)
+	}
+
+	msg, err := readMessage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "pong" || msg.ID != 7 || msg.Data["status"] != "alive" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	if _, err := readMessage(&buf); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	h := newTestHost(t, newFakeVault(t))
+
+	// The length is refused before anything is allocated or read
+	var in bytes.Buffer
+	binary.Write(&in, binary.LittleEndian, uint32(maxMessageSize+1))
+	in.WriteString("{}")
+	if _, err := readMessage(bytes.NewReader(in.Bytes())); !errors.Is(err, errMessageTooLarge) {
+		t.Fatalf("got %v, want errMessageTooLarge", err)
+	}
+
+	var out bytes.Buffer
+	if err := h.serve(&in, &out); !errors.Is(err, errMessageTooLarge) {
+		t.Fatalf("serve: got %v, want errMessageTooLarge", err)
+	}
+}
+
+func TestPingDoesNotNeedTheApp(t *testing.T) {
+	h := newHost(func() (net.Conn, error) {
+		t.Fatal("ping dialed the app")
+		return nil, nil
+	}, filepath.Join(t.TempDir(), pairingFileName), nil)
+
+	responses := exchangeMessages(t, h, Message{Type: "ping", ID: 1})
+	if responses[0].Type != "pong" || !responses[0].Success || responses[0].ID != 1 {
+		t.Fatalf("unexpected response %+v", responses[0])
+	}
+}
+
+func TestUnknownMessageType(t *testing.T) {
+	h := newTestHost(t, newFakeVault(t))
+
+	responses := exchangeMessages(t, h, Message{Type: "selfDestruct", ID: 4})
+	if responses[0].Success || responses[0].ID != 4 || !strings.Contains(responses[0].Error, "selfDestruct") {
+		t.Fatalf("unexpected response %+v", responses[0])
+	}
+}
+
+func TestInvalidMessageIsAnsweredAndSkipped(t *testing.T) {
+	h := newTestHost(t, newFakeVault(t))
+
+	var in bytes.Buffer
+	garbage := []byte("{not json")
+	binary.Write(&in, binary.LittleEndian, uint32(len(garbage)))
+	in.Write(garbage)
+	ping, _ := json.Marshal(Message{Type: "ping", ID: 2})
+	binary.Write(&in, binary.LittleEndian, uint32(len(ping)))
+	in.Write(ping)
+
+	var out bytes.Buffer
+	if err := h.serve(&in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := readMessage(&out)
+	if err != nil || first.Type != "error" {
+		t.Fatalf("expected an error response, got %+v, %v", first, err)
+	}
+	second, err := readMessage(&out)
+	if err != nil || second.Type != "pong" {
+		t.Fatalf("expected pong after the bad message, got %+v, %v", second, err)
+	}
+}
+
+func TestRequestsNeedPairing(t *testing.T) {
+	vault := newFakeVault(t)
+	h := newTestHost(t, vault)
+
+	responses := exchangeMessages(t, h, Message{Type: "getCredentials", ID: 1, Data: map[string]interface{}{"url": "https://github.com"}})
+	if responses[0].Success || !strings.HasPrefix(responses[0].Error, "not paired") {
+		t.Fatalf("expected a not paired error, got %+v", responses[0])
+	}
+}
+
+func TestWrongPairingCode(t *testing.T) {
+	vault := newFakeVault(t)
+	vault.code = "ABCDEFGH"
+	h := newTestHost(t, vault)
+
+	responses := exchangeMessages(t, h, Message{Type: "pair", ID: 1, Data: map[string]interface{}{"code": "ABCD-EFGX"}})
+	if responses[0].Success {
+		t.Fatal("paired with the wrong code")
+	}
+	if _, err := h.loadPairing(); !errors.Is(err, errNotPaired) {
+		t.Fatalf("expected no pairing to be stored, got %v", err)
+	}
+}
+
+func TestSearchAndFill(t *testing.T) {
+	vault := newFakeVault(t)
+	vault.credentials = []fakeCredential{
+		{Credential: ipcproto.Credential{ID: "1", ServiceName: "GitHub", Username: "octocat"}, URL: "https://github.com", Password: "hunter2"},
+		{Credential: ipcproto.Credential{ID: "2", ServiceName: "GitLab", Username: "tanuki"}, URL: "https://gitlab.com", Password: "secret"},
+	}
+	h := pairedHost(t, vault)
+
+	responses := exchangeMessages(t, h,
+		Message{Type: "getCredentials", ID: 2, Data: map[string]interface{}{"url": "https://github.com"}},
+		Message{Type: "fill", ID: 3, Data: map[string]interface{}{"id": "1", "url": "https://github.com"}},
+		Message{Type: "fill", ID: 4, Data: map[string]interface{}{"id": "2", "url": "https://github.com"}},
+	)
+
+	var found []map[string]interface{}
+	responseData(t, responses[0], "credentials", &found)
+	if len(found) != 1 || found[0]["username"] != "octocat" {
+		t.Fatalf("unexpected search result %v", found)
+	}
+	if _, ok := found[0]["password"]; ok {
+		t.Fatal("search result carries a password")
+	}
+
+	var filled ipcproto.FillResponse
+	responseData(t, responses[1], "credential", &filled)
+	if !responses[1].Success || filled.Password != "hunter2" {
+		t.Fatalf("unexpected fill %+v", responses[1])
+	}
+
+	if responses[2].Success {
+		t.Fatal("filled another site's password")
+	}
+}
+
+func TestOneTimeCode(t *testing.T) {
+	vault := newFakeVault(t)
+	vault.credentials = []fakeCredential{
+		{Credential: ipcproto.Credential{ID: "1", ServiceName: "GitHub", HasOneTimeCode: true}, URL: "https://github.com", OneTimeCode: "287082"},
+		{Credential: ipcproto.Credential{ID: "2", ServiceName: "GitLab"}, URL: "https://gitlab.com"},
+	}
+	h := pairedHost(t, vault)
+
+	responses := exchangeMessages(t, h,
+		Message{Type: "getOneTimeCode", ID: 2, Data: map[string]interface{}{"id": "1", "url": "https://github.com"}},
+		Message{Type: "getOneTimeCode", ID: 3, Data: map[string]interface{}{"id": "2", "url": "https://gitlab.com"}},
+	)
+
+	var code ipcproto.OneTimeCodeResponse
+	responseData(t, responses[0], "oneTimeCode", &code)
+	if responses[0].Type != "oneTimeCode" || !responses[0].Success || code.Code != "287082" || code.SecondsRemaining != 17 {
+		t.Fatalf("unexpected one-time code %+v", responses[0])
+	}
+
+	if responses[1].Success || responses[1].Error == "" {
+		t.Fatalf("got a code for a credential without one: %+v", responses[1])
+	}
+}
+
+func TestSaveAndCreditCards(t *testing.T) {
+	vault := newFakeVault(t)
+	vault.cards = []ipcproto.CreditCard{{ID: "c1", CardName: "Personal", CardNumber: "4111111111111111"}}
+	h := pairedHost(t, vault)
+
+	responses := exchangeMessages(t, h,
+		Message{Type: "saveCredential", ID: 2, Data: map[string]interface{}{
+			"serviceName": "Example", "url": "https://example.com", "username": "me", "password": "pw",
+		}},
+		Message{Type: "getCreditCards", ID: 3, Data: map[string]interface{}{"url": "https://shop.example"}},
+	)
+
+	if !responses[0].Success || len(vault.saved) != 1 || vault.saved[0].Password != "pw" {
+		t.Fatalf("credential was not saved: %+v, %+v", responses[0], vault.saved)
+	}
+
+	var cards []ipcproto.CreditCard
+	responseData(t, responses[1], "cards", &cards)
+	if len(cards) != 1 || cards[0].CardNumber != "4111111111111111" {
+		t.Fatalf("unexpected cards %+v", cards)
+	}
+}
+
+func TestIdentities(t *testing.T) {
+	vault := newFakeVault(t)
+	vault.identities = []ipcproto.Identity{{
+		ID:        "i1",
+		Name:      "Home",
+		FirstName: "Jane",
+		Address:   ipcproto.Address{Street1: "1 Main St", PostalCode: "12345"},
+	}}
+	h := pairedHost(t, vault)
+
+	responses := exchangeMessages(t, h, Message{Type: "getIdentities", ID: 2, Data: map[string]interface{}{"url": "https://shop.example"}})
+
+	var identities []ipcproto.Identity
+	responseData(t, responses[0], "identities", &identities)
+	if len(identities) != 1 || identities[0].FirstName != "Jane" || identities[0].Address.PostalCode != "12345" {
+		t.Fatalf("unexpected identities %+v", identities)
+	}
+}
+
+func TestFillAwaitingApprovalDoesNotBlock(t *testing.T) {
+	vault := newFakeVault(t)
+	vault.credentials = []fakeCredential{
+		{Credential: ipcproto.Credential{ID: "1", ServiceName: "GitHub", Username: "octocat"}, URL: "https://github.com", Password: "hunter2"},
+	}
+	h := pairedHost(t, vault)
+	vault.approval = make(chan struct{})
+
+	inReader, in := io.Pipe()
+	out, outWriter := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- h.serve(inReader, outWriter)
+		outWriter.Close()
+	}()
+	send := func(msg Message) {
+		data, _ := json.Marshal(msg)
+		binary.Write(in, binary.LittleEndian, uint32(len(data)))
+		in.Write(data)
+	}
+
+	send(Message{Type: "fill", ID: 2, Data: map[string]interface{}{"id": "1", "url": "https://github.com"}})
+	send(Message{Type: "getCredentials", ID: 3, Data: map[string]interface{}{"url": "https://github.com"}})
+
+	// The search is answered while the fill waits for the user
+	response, err := readMessage(out)
+	if err != nil || response.ID != 3 {
+		t.Fatalf("first response %+v, %v", response, err)
+	}
+
+	close(vault.approval)
+	response, err = readMessage(out)
+	if err != nil || response.ID != 2 || response.Type != "filled" {
+		t.Fatalf("fill response %+v, %v", response, err)
+	}
+
+	in.Close()
+	if err := <-served; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+}
+
+func TestVaultErrorsReachTheExtension(t *testing.T) {
+	vault := newFakeVault(t)
+	h := pairedHost(t, vault)
+	vault.locked = true
+
+	responses := exchangeMessages(t, h, Message{Type: "getCredentials", ID: 2, Data: map[string]interface{}{"url": "https://github.com"}})
+	if responses[0].Success || responses[0].Error != "Vault is locked" {
+		t.Fatalf("unexpected response %+v", responses[0])
+	}
+}
+
+func TestConnectionIsReused(t *testing.T) {
+	vault := newFakeVault(t)
+	h := pairedHost(t, vault)
+	dials := vault.dials
+
+	for i := 0; i < 2; i++ {
+		if _, err := h.getCredentialsFromVault("https://github.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vault.dials != dials+1 {
+		t.Fatalf("expected one connection for the session, dialed %d times", vault.dials-dials)
+	}
+}
+
+func TestReconnectsAfterAppRestart(t *testing.T) {
+	vault := newFakeVault(t)
+	h := pairedHost(t, vault)
+
+	// Keep the connection open across the restart, as in a browser session
+	if _, err := h.getCredentialsFromVault("https://github.com"); err != nil {
+		t.Fatal(err)
+	}
+	vault.dropConnections()
+
+	if _, err := h.getCredentialsFromVault("https://github.com"); err != nil {
+		t.Fatalf("request after restart: %v", err)
+	}
+}
+
+func TestAppNotRunning(t *testing.T) {
+	h := newHost(func() (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}, filepath.Join(t.TempDir(), pairingFileName), nil)
+	h.dialTimeout = 50 * time.Millisecond
+
+	if err := h.savePairing(&pairing{ClientID: "c1", Key: make([]byte, 32)}); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := exchangeMessages(t, h, Message{Type: "getCredentials", ID: 1, Data: map[string]interface{}{"url": "https://github.com"}})
+	if responses[0].Success || !strings.Contains(responses[0].Error, "not running") {
+		t.Fatalf("unexpected response %+v", responses[0])
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"vaultzero/ipcproto"
+)
+
+// maxMessageSize bounds a message from the extension. Anything larger could
+// not be passed on to the app anyway.
+const maxMessageSize = ipcproto.MaxFrameSize
+
+// errMessageTooLarge is returned for a length prefix over maxMessageSize.
+// The message is not read, so the stream is out of step afterwards.
+var errMessageTooLarge = errors.New("native message exceeds maximum size")
+
+// Message represents a native messaging message
+type Message struct {
+	Type string                 `json:"type"`
+	ID   int                    `json:"id,omitempty"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// Response represents a response to the browser
+type Response struct {
+	Type    string                 `json:"type"`
+	ID      int                    `json:"id,omitempty"`
+	Success bool                   `json:"success"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// readMessage reads a native messaging format message from reader
+func readMessage(reader io.Reader) (*Message, error) {
+	// Read message length (4 bytes, little-endian)
+	var length uint32
+	if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	if length > maxMessageSize {
+		return nil, errMessageTooLarge
+	}
+
+	// Read message content
+	msgBytes := make([]byte, length)
+	if _, err := io.ReadFull(reader, msgBytes); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	// Parse JSON
+	var msg Message
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// sendMessage sends a native messaging format message to writer
+func sendMessage(writer io.Writer, response *Response) error {
+	// Marshal response to JSON
+	msgBytes, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	// Write message length (4 bytes, little-endian)
+	length := uint32(len(msgBytes))
+	if err := binary.Write(writer, binary.LittleEndian, length); err != nil {
+		return err
+	}
+
+	// Write message content
+	if _, err := writer.Write(msgBytes); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"vaultzero/ipcproto"
+)
+
+const pairingFileName = "native-host.json"
+
+// errNotPaired tells the extension to ask the user for a pairing code
+var errNotPaired = errors.New("not paired with VaultZero - enter the pairing code shown in the VaultZero app")
+
+// pairing is the key this native host shares with the VaultZero app
+type pairing struct {
+	ClientID string `json:"clientId"`
+	Key      []byte `json:"key"`
+}
+
+// defaultPairingPath returns where the pairing is stored for the current user
+func defaultPairingPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "VaultZero", pairingFileName), nil
+}
+
+// loadPairing reads the stored pairing, failing with errNotPaired if there is none
+func (h *host) loadPairing() (*pairing, error) {
+	data, err := os.ReadFile(h.pairingPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errNotPaired
+		}
+		return nil, err
+	}
+
+	var p pairing
+	if err := json.Unmarshal(data, &p); err != nil || p.ClientID == "" || len(p.Key) == 0 {
+		return nil, errNotPaired
+	}
+	return &p, nil
+}
+
+// savePairing stores the pairing readable only by the current user
+func (h *host) savePairing(p *pairing) error {
+	if err := os.MkdirAll(filepath.Dir(h.pairingPath), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(h.pairingPath, data, 0600)
+}
+
+// pairWithVault proves knowledge of the pairing code to the app and stores
+// the client key it hands out
+func (h *host) pairWithVault(code string) error {
+	clientID := make([]byte, 16)
+	nonce := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, clientID); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	id := hex.EncodeToString(clientID)
+
+	pairingKey, err := ipcproto.PairingKey(code, nonce)
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	response, err := h.exchange(&ipcproto.Request{
+		Action: ipcproto.ActionPair,
+		Pair: &ipcproto.PairRequest{
+			ClientID:   id,
+			ClientName: "Browser extension on " + hostname,
+			Nonce:      nonce,
+			Proof:      ipcproto.PairingProof(pairingKey, id),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if response.Pairing == nil {
+		return errors.New("invalid response from VaultZero")
+	}
+
+	key, err := ipcproto.Open(response.Pairing.Key, pairingKey, ipcproto.PairResponseAAD(id))
+	if err != nil {
+		return errors.New("pairing response could not be verified")
+	}
+
+	return h.savePairing(&pairing{ClientID: id, Key: key})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Browsers find the native host through a manifest named after the host in
+// a per-browser directory. Chromium-family browsers only let the extension
+// IDs in allowed_origins connect; Firefox uses allowed_extensions with the
+// add-on ID from the extension's manifest.json.
+const (
+	hostName           = "com.vaultzero.host"
+	hostDescription    = "VaultZero Native Messaging Host"
+	firefoxExtensionID = "vaultzero@vaultzero.app"
+)
+
+// browser is a browser that can launch the native host
+type browser struct {
+	ID          string // used on the command line, e.g. "chrome"
+	Name        string
+	ProfileDir  string // present if the browser is installed for the user
+	ManifestDir string // where the browser looks for host manifests
+	Firefox     bool   // uses allowed_extensions instead of allowed_origins
+}
+
+// manifestPath returns where the host's manifest goes for the browser
+func (b browser) manifestPath() string {
+	return filepath.Join(b.ManifestDir, hostName+".json")
+}
+
+// hostManifest is a native messaging host manifest
+type hostManifest struct {
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	Path              string   `json:"path"`
+	Type              string   `json:"type"`
+	AllowedOrigins    []string `json:"allowed_origins,omitempty"`
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"`
+}
+
+// newHostManifest builds the manifest letting the extension launch hostPath
+func newHostManifest(b browser, hostPath string, extensionIDs []string) (*hostManifest, error) {
+	if !filepath.IsAbs(hostPath) {
+		return nil, errors.New("host path must be absolute: " + hostPath)
+	}
+
+	manifest := &hostManifest{
+		Name:        hostName,
+		Description: hostDescription,
+		Path:        hostPath,
+		Type:        "stdio",
+	}
+
+	if b.Firefox {
+		manifest.AllowedExtensions = []string{firefoxExtensionID}
+		return manifest, nil
+	}
+
+	if len(extensionIDs) == 0 {
+		return nil, errors.New("needs the extension ID shown on the browser's extensions page (--extension-id)")
+	}
+	for _, id := range extensionIDs {
+		manifest.AllowedOrigins = append(manifest.AllowedOrigins, "chrome-extension://"+id+"/")
+	}
+	return manifest, nil
+}
+
+// registerHost writes the host manifest for each browser and returns the
+// browsers registered. Browsers that fail are reported in the error; the
+// others are still registered.
+func registerHost(browsers []browser, hostPath string, extensionIDs []string) ([]browser, error) {
+	var registered []browser
+	var failures []string
+
+	for _, b := range browsers {
+		if err := writeHostManifest(b, hostPath, extensionIDs); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", b.Name, err))
+			continue
+		}
+		registered = append(registered, b)
+	}
+
+	if len(failures) > 0 {
+		return registered, errors.New(strings.Join(failures, "; "))
+	}
+	return registered, nil
+}
+
+// writeHostManifest writes the manifest for one browser
+func writeHostManifest(b browser, hostPath string, extensionIDs []string) error {
+	manifest, err := newHostManifest(b, hostPath, extensionIDs)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(b.ManifestDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(b.manifestPath(), append(data, '\n'), 0644)
+}
+
+// unregisterHost removes the host manifest from each browser and returns the
+// browsers it was removed from
+func unregisterHost(browsers []browser) ([]browser, error) {
+	var removed []browser
+
+	for _, b := range browsers {
+		err := os.Remove(b.manifestPath())
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return removed, fmt.Errorf("%s: %v", b.Name, err)
+		}
+		removed = append(removed, b)
+	}
+	return removed, nil
+}
+
+// selectBrowsers picks the browsers named in a comma-separated list, or the
+// installed ones if the list is empty
+func selectBrowsers(all []browser, list string) ([]browser, error) {
+	if list == "" {
+		var installed []browser
+		for _, b := range all {
+			if info, err := os.Stat(b.ProfileDir); err == nil && info.IsDir() {
+				installed = append(installed, b)
+			}
+		}
+		return installed, nil
+	}
+
+	var selected []browser
+	for _, id := range strings.Split(list, ",") {
+		id = strings.ToLower(strings.TrimSpace(id))
+		found := false
+		for _, b := range all {
+			if b.ID == id {
+				selected = append(selected, b)
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.New("unknown browser: " + id)
+		}
+	}
+	return selected, nil
+}
+
+// runRegistration handles "register" and "unregister" on the command line
+// and returns the process exit code
+func runRegistration(args []string, stdout, stderr io.Writer) int {
+	command := args[0]
+
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	browserList := flags.String("browsers", "", "comma-separated browsers (chrome, chromium, brave, edge, firefox); default: all installed")
+	extensionIDs := flags.String("extension-id", "", "comma-separated IDs of the extension in Chromium-family browsers")
+	hostPath := flags.String("host", "", "absolute path of the native host; default: this executable")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	all, err := nativeMessagingBrowsers()
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return 1
+	}
+	browsers, err := selectBrowsers(all, *browserList)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return 2
+	}
+	if len(browsers) == 0 {
+		fmt.Fprintln(stderr, "No supported browsers found")
+		return 1
+	}
+
+	if command == "unregister" {
+		removed, err := unregisterHost(browsers)
+		for _, b := range removed {
+			fmt.Fprintf(stdout, "Unregistered from %s (%s)\n", b.Name, b.manifestPath())
+		}
+		if len(removed) == 0 && err == nil {
+			fmt.Fprintln(stdout, "Not registered with any browser")
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "Error:", err)
+			return 1
+		}
+		return 0
+	}
+
+	if *hostPath == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			fmt.Fprintln(stderr, "Error:", err)
+			return 1
+		}
+		*hostPath = executable
+	}
+
+	var ids []string
+	for _, id := range strings.Split(*extensionIDs, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	registered, err := registerHost(browsers, *hostPath, ids)
+	for _, b := range registered {
+		fmt.Fprintf(stdout, "Registered with %s (%s)\n", b.Name, b.manifestPath())
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
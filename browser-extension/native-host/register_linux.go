@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// nativeMessagingBrowsers lists the browsers and their per-user manifest
+// directories on Linux. Chromium-family browsers keep their profiles under
+// $XDG_CONFIG_HOME (usually ~/.config); Firefox uses ~/.mozilla.
+func nativeMessagingBrowsers() ([]browser, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	chromium := func(id, name, profile string) browser {
+		profileDir := filepath.Join(configDir, profile)
+		return browser{
+			ID:          id,
+			Name:        name,
+			ProfileDir:  profileDir,
+			ManifestDir: filepath.Join(profileDir, "NativeMessagingHosts"),
+		}
+	}
+
+	return []browser{
+		chromium("chrome", "Google Chrome", "google-chrome"),
+		chromium("chromium", "Chromium", "chromium"),
+		chromium("brave", "Brave", filepath.Join("BraveSoftware", "Brave-Browser")),
+		chromium("edge", "Microsoft Edge", "microsoft-edge"),
+		{
+			ID:          "firefox",
+			Name:        "Firefox",
+			ProfileDir:  filepath.Join(homeDir, ".mozilla"),
+			ManifestDir: filepath.Join(homeDir, ".mozilla", "native-messaging-hosts"),
+			Firefox:     true,
+		},
+	}, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// nativeMessagingBrowsers is only implemented for Linux; Windows uses
+// registry entries written by install-extension.ps1
+func nativeMessagingBrowsers() ([]browser, error) {
+	return nil, errors.New("registering the native host is only supported on Linux - on Windows run install-extension.ps1")
+}
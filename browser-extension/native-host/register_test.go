@@ -0,0 +1,142 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeHome points the browser directories at a temporary home with the
+// given browser profiles present
+func fakeHome(t *testing.T, profiles ...string) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	for _, profile := range profiles {
+		if err := os.MkdirAll(filepath.Join(home, profile), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+func readManifest(t *testing.T, path string) hostManifest {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest hostManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	return manifest
+}
+
+func TestRegisterInstalledBrowsers(t *testing.T) {
+	home := fakeHome(t, ".config/google-chrome", ".config/BraveSoftware/Brave-Browser", ".mozilla")
+
+	var stdout, stderr bytes.Buffer
+	code := runRegistration([]string{"register", "--host", "/opt/vaultzero/host", "--extension-id", "abcdefghijklmnop"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+
+	chrome := readManifest(t, filepath.Join(home, ".config/google-chrome/NativeMessagingHosts", hostName+".json"))
+	if chrome.Path != "/opt/vaultzero/host" || chrome.Type != "stdio" ||
+		len(chrome.AllowedOrigins) != 1 || chrome.AllowedOrigins[0] != "chrome-extension://abcdefghijklmnop/" {
+		t.Fatalf("unexpected Chrome manifest %+v", chrome)
+	}
+
+	firefox := readManifest(t, filepath.Join(home, ".mozilla/native-messaging-hosts", hostName+".json"))
+	if len(firefox.AllowedExtensions) != 1 || firefox.AllowedExtensions[0] != firefoxExtensionID || firefox.AllowedOrigins != nil {
+		t.Fatalf("unexpected Firefox manifest %+v", firefox)
+	}
+
+	// Browsers that are not installed are left alone
+	if _, err := os.Stat(filepath.Join(home, ".config/chromium")); !os.IsNotExist(err) {
+		t.Fatal("registered with Chromium, which is not installed")
+	}
+
+	report := stdout.String()
+	for _, name := range []string{"Google Chrome", "Brave", "Firefox"} {
+		if !strings.Contains(report, "Registered with "+name) {
+			t.Errorf("%s missing from report:\n%s", name, report)
+		}
+	}
+}
+
+func TestRegisterChromiumNeedsExtensionID(t *testing.T) {
+	home := fakeHome(t, ".config/chromium", ".mozilla")
+
+	var stdout, stderr bytes.Buffer
+	code := runRegistration([]string{"register", "--host", "/opt/vaultzero/host"}, &stdout, &stderr)
+	if code == 0 || !strings.Contains(stderr.String(), "Chromium") {
+		t.Fatalf("expected Chromium to fail without an extension ID, got %d: %s", code, stderr.String())
+	}
+
+	// Firefox does not need one and is still registered
+	if _, err := os.Stat(filepath.Join(home, ".mozilla/native-messaging-hosts", hostName+".json")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterNamedBrowsers(t *testing.T) {
+	home := fakeHome(t)
+
+	var stdout, stderr bytes.Buffer
+	code := runRegistration([]string{"register", "--browsers", "edge", "--host", "/opt/vaultzero/host", "--extension-id", "a,b"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+
+	edge := readManifest(t, filepath.Join(home, ".config/microsoft-edge/NativeMessagingHosts", hostName+".json"))
+	if len(edge.AllowedOrigins) != 2 {
+		t.Fatalf("unexpected Edge manifest %+v", edge)
+	}
+
+	if code := runRegistration([]string{"register", "--browsers", "netscape"}, &stdout, &stderr); code == 0 {
+		t.Fatal("registered with an unknown browser")
+	}
+}
+
+func TestRegisterNeedsAbsoluteHostPath(t *testing.T) {
+	fakeHome(t, ".mozilla")
+
+	var stdout, stderr bytes.Buffer
+	if code := runRegistration([]string{"register", "--host", "host"}, &stdout, &stderr); code == 0 {
+		t.Fatal("registered a relative host path")
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	home := fakeHome(t, ".config/google-chrome", ".mozilla")
+
+	var stdout, stderr bytes.Buffer
+	if code := runRegistration([]string{"register", "--host", "/opt/vaultzero/host", "--extension-id", "abc"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+
+	stdout.Reset()
+	if code := runRegistration([]string{"unregister"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Unregistered from Google Chrome") || !strings.Contains(stdout.String(), "Unregistered from Firefox") {
+		t.Fatalf("unexpected report:\n%s", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(home, ".mozilla/native-messaging-hosts", hostName+".json")); !os.IsNotExist(err) {
+		t.Fatal("Firefox manifest was not removed")
+	}
+
+	stdout.Reset()
+	runRegistration([]string{"unregister"}, &stdout, &stderr)
+	if !strings.Contains(stdout.String(), "Not registered") {
+		t.Fatalf("unexpected report:\n%s", stdout.String())
+	}
+}
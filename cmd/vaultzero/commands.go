@@ -0,0 +1,644 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"vaultzero/vault"
+)
+
+// open unlocks the selected vault. Commands that write fail while another
+// process, such as the app, has the vault open; the others read it anyway.
+func (c *cli) open(write bool) (*vault.Vault, error) {
+	store, err := c.storage()
+	if err != nil {
+		return nil, err
+	}
+	v := vault.New(store)
+	if !v.Exists() {
+		return nil, fmt.Errorf("no vault at %s; create one with 'vaultzero init'", v.Location())
+	}
+
+	password, err := c.passwords.master("Master password: ")
+	if err != nil {
+		return nil, err
+	}
+	if err := v.Unlock(password); err != nil {
+		return nil, err
+	}
+
+	if inUse := v.ReadOnlyReason(); inUse != nil && write {
+		v.Lock()
+		return nil, inUse
+	}
+	if damaged := v.DamagedRecords(); len(damaged) > 0 {
+		fmt.Fprintf(c.stderr, "Warning: %d damaged records could not be read\n", len(damaged))
+	}
+	return v, nil
+}
+
+// find returns the credential with the given ID, or the only one whose
+// service name matches
+func find(v *vault.Vault, ref string) (vault.Credential, error) {
+	credentials, err := v.Credentials()
+	if err != nil {
+		return vault.Credential{}, err
+	}
+
+	var matches []vault.Credential
+	for _, cred := range credentials {
+		if cred.ID == ref {
+			return cred, nil
+		}
+		if strings.EqualFold(cred.ServiceName, ref) {
+			matches = append(matches, cred)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return vault.Credential{}, errors.New("credential not found: " + ref)
+	case 1:
+		return matches[0], nil
+	}
+	return vault.Credential{}, fmt.Errorf("%d credentials are named %s; use the ID", len(matches), ref)
+}
+
+// listedCredential is a credential as listed, without its password
+type listedCredential struct {
+	ID          string    `json:"id"`
+	ServiceName string    `json:"serviceName"`
+	URL         string    `json:"url"`
+	Username    string    `json:"username"`
+	Category    string    `json:"category"`
+	IsFavorite  bool      `json:"isFavorite"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// vaultSummary describes an unlocked vault
+type vaultSummary struct {
+	Path        string `json:"path"`
+	Credentials int    `json:"credentials"`
+	CreditCards int    `json:"creditCards"`
+	SecureNotes int    `json:"secureNotes"`
+	Identities  int    `json:"identities"`
+	ReadOnly    bool   `json:"readOnly"`
+}
+
+func (c *cli) initVault(args []string) error {
+	flags := c.commandFlags("init", "")
+	name := flags.String("name", "", "name the app shows for the vault")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	store, err := c.storage()
+	if err != nil {
+		return err
+	}
+	v := vault.New(store)
+	if v.Exists() {
+		return errors.New("vault already exists at " + v.Location())
+	}
+
+	password, err := c.passwords.newMaster()
+	if err != nil {
+		return err
+	}
+	if len(password) < 8 {
+		return errors.New("master password must be at least 8 characters")
+	}
+
+	if err := v.Create(password); err != nil {
+		return err
+	}
+	v.Lock()
+
+	// Let the app offer the new vault
+	registry, err := vault.LoadVaultRegistry()
+	if err == nil {
+		registry.Add(*name, v.Location())
+		err = registry.Save()
+	}
+	if err != nil {
+		fmt.Fprintln(c.stderr, "Warning: Failed to update vault registry:", err)
+	}
+
+	return c.print(vaultSummary{Path: v.Location()}, func(w io.Writer) {
+		fmt.Fprintln(w, "Created vault", v.Location())
+	})
+}
+
+func (c *cli) unlock(args []string) error {
+	if err := parse(c.commandFlags("unlock", ""), args, 0); err != nil {
+		return err
+	}
+
+	v, err := c.open(false)
+	if err != nil {
+		return err
+	}
+	defer v.Lock()
+
+	credentials, err := v.Credentials()
+	if err != nil {
+		return err
+	}
+	creditCards, err := v.CreditCards()
+	if err != nil {
+		return err
+	}
+	secureNotes, err := v.SecureNotes()
+	if err != nil {
+		return err
+	}
+	identities, err := v.Identities()
+	if err != nil {
+		return err
+	}
+
+	summary := vaultSummary{
+		Path:        v.Location(),
+		Credentials: len(credentials),
+		CreditCards: len(creditCards),
+		SecureNotes: len(secureNotes),
+		Identities:  len(identities),
+		ReadOnly:    v.IsReadOnly(),
+	}
+	return c.print(summary, func(w io.Writer) {
+		fmt.Fprintln(w, "Vault:       ", summary.Path)
+		fmt.Fprintln(w, "Credentials: ", summary.Credentials)
+		fmt.Fprintln(w, "Credit cards:", summary.CreditCards)
+		fmt.Fprintln(w, "Secure notes:", summary.SecureNotes)
+		fmt.Fprintln(w, "Identities:  ", summary.Identities)
+		if summary.ReadOnly {
+			fmt.Fprintln(w, "Opened read-only: the vault is in use by another VaultZero process")
+		}
+	})
+}
+
+func (c *cli) list(args []string) error {
+	flags := c.commandFlags("ls", "")
+	category := flags.String("category", "", "only list credentials in this category")
+	favorites := flags.Bool("favorites", false, "only list favorites")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	v, err := c.open(false)
+	if err != nil {
+		return err
+	}
+	defer v.Lock()
+
+	credentials, err := v.Credentials()
+	if err != nil {
+		return err
+	}
+
+	listed := []listedCredential{}
+	for _, cred := range credentials {
+		if *category != "" && !strings.EqualFold(cred.Category, *category) {
+			continue
+		}
+		if *favorites && !cred.IsFavorite {
+			continue
+		}
+		listed = append(listed, listedCredential{
+			ID:          cred.ID,
+			ServiceName: cred.ServiceName,
+			URL:         cred.URL,
+			Username:    cred.Username,
+			Category:    cred.Category,
+			IsFavorite:  cred.IsFavorite,
+			CreatedAt:   cred.CreatedAt,
+		})
+	}
+
+	return c.print(listed, func(w io.Writer) {
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "ID\tNAME\tUSERNAME\tURL\tCATEGORY")
+		for _, cred := range listed {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", cred.ID, cred.ServiceName, cred.Username, cred.URL, cred.Category)
+		}
+		table.Flush()
+	})
+}
+
+func (c *cli) get(args []string) error {
+	flags := c.commandFlags("get", "<id|name>")
+	field := flags.String("field", "", "only print this field: name, url, username, password, category, otp (the current one-time password) or the label of a custom field")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	v, err := c.open(false)
+	if err != nil {
+		return err
+	}
+	defer v.Lock()
+
+	cred, err := find(v, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *field == "otp" {
+		// An HOTP code advances the counter, which must be saved
+		if otp, err := vault.ParseOTPAuthURI(cred.OTPAuth); err == nil && otp.Type == vault.OTPTypeHOTP {
+			if inUse := v.ReadOnlyReason(); inUse != nil {
+				return inUse
+			}
+		}
+		code, err := v.OneTimeCode(cred.ID)
+		if err != nil {
+			return err
+		}
+		return c.print(code, func(w io.Writer) {
+			fmt.Fprintln(w, code.Code)
+		})
+	}
+
+	if *field != "" {
+		fields := map[string]string{
+			"name":     cred.ServiceName,
+			"url":      cred.URL,
+			"username": cred.Username,
+			"password": cred.Password,
+			"category": cred.Category,
+		}
+		value, ok := fields[*field]
+		if !ok {
+			custom, err := customField(cred, *field)
+			if err != nil {
+				return err
+			}
+			value = custom
+		}
+		return c.print(map[string]string{*field: value}, func(w io.Writer) {
+			fmt.Fprintln(w, value)
+		})
+	}
+
+	return c.print(cred, func(w io.Writer) {
+		fmt.Fprintln(w, "ID:      ", cred.ID)
+		fmt.Fprintln(w, "Name:    ", cred.ServiceName)
+		fmt.Fprintln(w, "URL:     ", cred.URL)
+		fmt.Fprintln(w, "Username:", cred.Username)
+		fmt.Fprintln(w, "Password:", cred.Password)
+		fmt.Fprintln(w, "Category:", cred.Category)
+		for _, field := range cred.Fields {
+			fmt.Fprintf(w, "%s: %s\n", field.Label, field.Value)
+		}
+	})
+}
+
+// customField returns the value of the credential's custom field with the
+// given label; a TOTP field gives its current one-time password
+func customField(cred vault.Credential, label string) (string, error) {
+	for _, field := range cred.Fields {
+		if !strings.EqualFold(field.Label, label) {
+			continue
+		}
+		if field.Type == vault.FieldTypeTOTP {
+			code, err := field.Code(time.Now())
+			if err != nil {
+				return "", err
+			}
+			return code.Code, nil
+		}
+		return field.Value, nil
+	}
+	return "", errors.New("unknown field: " + label)
+}
+
+// fieldFlags collects the custom fields given as repeated -field options,
+// each "label=value" or "label=type:value" with a type such as hidden, url,
+// email, date or totp
+type fieldFlags []vault.CustomField
+
+func (f *fieldFlags) String() string { return "" }
+
+func (f *fieldFlags) Set(option string) error {
+	label, value, ok := strings.Cut(option, "=")
+	if !ok || strings.TrimSpace(label) == "" {
+		return errors.New("fields are given as label=value or label=type:value")
+	}
+
+	field := vault.CustomField{Label: label, Type: vault.FieldTypeText, Value: value}
+	if kind, rest, ok := strings.Cut(value, ":"); ok {
+		switch kind {
+		case vault.FieldTypeText, vault.FieldTypeHidden, vault.FieldTypeURL,
+			vault.FieldTypeEmail, vault.FieldTypeDate, vault.FieldTypeTOTP:
+			field.Type = kind
+			field.Value = rest
+		}
+	}
+	*f = append(*f, field)
+	return nil
+}
+
+// setFields replaces the fields with the labels given, in place, and adds
+// the others at the end
+func setFields(fields []vault.CustomField, set fieldFlags) []vault.CustomField {
+	for _, field := range set {
+		replaced := false
+		for i := range fields {
+			if strings.EqualFold(fields[i].Label, strings.TrimSpace(field.Label)) {
+				field.ID = fields[i].ID
+				fields[i] = field
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// removeFields drops the fields with the given labels
+func removeFields(fields []vault.CustomField, labels []string) []vault.CustomField {
+	kept := []vault.CustomField{}
+	for _, field := range fields {
+		removed := false
+		for _, label := range labels {
+			if strings.EqualFold(field.Label, label) {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
+
+// fieldLabels collects the labels of repeated -remove-field options
+type fieldLabels []string
+
+func (l *fieldLabels) String() string { return "" }
+
+func (l *fieldLabels) Set(label string) error {
+	*l = append(*l, label)
+	return nil
+}
+
+// passwordFlags are the ways add and edit take a credential's password
+type passwordFlags struct {
+	generate *bool
+	length   *int
+}
+
+func addPasswordFlags(flags *flag.FlagSet) passwordFlags {
+	return passwordFlags{
+		generate: flags.Bool("generate", false, "generate a strong password instead of reading one"),
+		length:   flags.Int("length", 20, "length of a generated password"),
+	}
+}
+
+// password generates the credential's password or reads it from the
+// terminal or stdin
+func (c *cli) password(flags passwordFlags) (string, error) {
+	if *flags.generate {
+		return vault.GenerateStrongPassword(*flags.length)
+	}
+	return c.passwords.secret("Password: ")
+}
+
+func (c *cli) add(args []string) error {
+	flags := c.commandFlags("add", "")
+	name := flags.String("name", "", "service name (required)")
+	url := flags.String("url", "", "login page")
+	username := flags.String("username", "", "username or email")
+	category := flags.String("category", "Other", "category")
+	var fields fieldFlags
+	flags.Var(&fields, "field", "add a custom field, as label=value or label=type:value (repeatable)")
+	passwordOptions := addPasswordFlags(flags)
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *name == "" {
+		flags.Usage()
+		return errUsage
+	}
+
+	v, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer v.Lock()
+
+	password, err := c.password(passwordOptions)
+	if err != nil {
+		return err
+	}
+
+	credential, err := v.AddCredential(vault.Credential{
+		ServiceName: *name,
+		URL:         *url,
+		Username:    *username,
+		Password:    password,
+		Category:    *category,
+		Fields:      fields,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.print(map[string]string{"id": credential.ID}, func(w io.Writer) {
+		fmt.Fprintln(w, credential.ID)
+	})
+}
+
+func (c *cli) edit(args []string) error {
+	flags := c.commandFlags("edit", "<id|name>")
+	name := flags.String("name", "", "new service name")
+	url := flags.String("url", "", "new login page")
+	username := flags.String("username", "", "new username")
+	category := flags.String("category", "", "new category")
+	newPassword := flags.Bool("password", false, "read a new password from the terminal or stdin")
+	var fields fieldFlags
+	flags.Var(&fields, "field", "set a custom field, as label=value or label=type:value (repeatable)")
+	var removed fieldLabels
+	flags.Var(&removed, "remove-field", "remove the custom field with this label (repeatable)")
+	passwordOptions := addPasswordFlags(flags)
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	v, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer v.Lock()
+
+	cred, err := find(v, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	password := ""
+	if *newPassword || *passwordOptions.generate {
+		if password, err = c.password(passwordOptions); err != nil {
+			return err
+		}
+	}
+
+	err = v.UpdateCredential(cred.ID, func(cred *vault.Credential) error {
+		// Only the options given on the command line change
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "name":
+				cred.ServiceName = *name
+			case "url":
+				cred.URL = *url
+				cred.IconURL = vault.FetchFavicon(*url)
+			case "username":
+				cred.Username = *username
+			case "category":
+				cred.Category = *category
+			}
+		})
+		if password != "" {
+			cred.Password = password
+		}
+		cred.Fields = setFields(removeFields(cred.Fields, removed), fields)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.print(map[string]string{"id": cred.ID}, func(w io.Writer) {
+		fmt.Fprintln(w, "Updated", cred.ServiceName)
+	})
+}
+
+func (c *cli) remove(args []string) error {
+	flags := c.commandFlags("rm", "<id|name>")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	v, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer v.Lock()
+
+	removed, err := find(v, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := v.DeleteCredential(removed.ID); err != nil {
+		return err
+	}
+	return c.print(map[string]string{"id": removed.ID}, func(w io.Writer) {
+		fmt.Fprintln(w, "Deleted", removed.ServiceName)
+	})
+}
+
+func (c *cli) generate(args []string) error {
+	flags := c.commandFlags("generate", "")
+	options := vault.PasswordGeneratorOptions{}
+	flags.IntVar(&options.Length, "length", 20, "password length (8-128)")
+	flags.BoolVar(&options.IncludeUppercase, "upper", true, "include uppercase letters")
+	flags.BoolVar(&options.IncludeLowercase, "lower", true, "include lowercase letters")
+	flags.BoolVar(&options.IncludeNumbers, "numbers", true, "include numbers")
+	flags.BoolVar(&options.IncludeSymbols, "symbols", true, "include symbols")
+	flags.BoolVar(&options.ExcludeAmbiguous, "exclude-ambiguous", false, "leave out characters that look alike, such as l, 1 and O")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	password, err := vault.GeneratePassword(options)
+	if err != nil {
+		return err
+	}
+	return c.print(map[string]string{"password": password}, func(w io.Writer) {
+		fmt.Fprintln(w, password)
+	})
+}
+
+func (c *cli) importCSV(args []string) error {
+	flags := c.commandFlags("import", "<file.csv|->")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	var content []byte
+	var err error
+	if flags.Arg(0) == "-" {
+		content, err = io.ReadAll(c.stdin)
+	} else {
+		content, err = os.ReadFile(flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	// Fail on unreadable files before asking for the password
+	if _, err := vault.ParseCSV(string(content)); err != nil {
+		return err
+	}
+
+	v, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer v.Lock()
+
+	result, err := v.ImportCSV(string(content))
+	if err != nil {
+		return err
+	}
+
+	return c.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "Imported %d of %d credentials, skipped %d\n", result.Imported, result.TotalProcessed, result.Skipped)
+		for _, message := range result.Errors {
+			fmt.Fprintln(w, message)
+		}
+	})
+}
+
+func (c *cli) export(args []string) error {
+	flags := c.commandFlags("export", "<file>")
+	encrypted := flags.Bool("encrypted", false, "write an encrypted backup instead of a CSV file")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	v, err := c.open(false)
+	if err != nil {
+		return err
+	}
+	defer v.Lock()
+
+	var result *vault.ExportResult
+	if *encrypted {
+		result, err = v.ExportEncryptedBackup(flags.Arg(0))
+	} else {
+		result, err = v.ExportCSV(flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.print(result, func(w io.Writer) {
+		if *encrypted {
+			fmt.Fprintf(w, "Exported %d credentials, %d cards, %d secure notes and %d identities to %s\n",
+				result.CredentialCount, result.CreditCardCount, result.SecureNoteCount, result.IdentityCount, result.FilePath)
+			return
+		}
+		fmt.Fprintf(w, "Exported %d credentials to %s\n", result.CredentialCount, result.FilePath)
+	})
+}
@@ -0,0 +1,169 @@
+// Command vaultzero works with VaultZero vaults from the command line, for
+// scripts and machines without the desktop app
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"vaultzero/vault"
+)
+
+const usage = `Usage: vaultzero [options] <command> [arguments]
+
+Commands:
+  init                      create a new vault
+  unlock                    check the master password and show the vault
+  ls                        list credentials
+  get <id|name>             show a credential
+  add                       add a credential
+  edit <id|name>            change a credential
+  rm <id|name>              delete a credential
+  generate                  generate a password
+  import <file.csv>         import credentials from a browser or manager export
+  export <file>             export credentials as CSV or an encrypted backup
+
+Run 'vaultzero <command> -h' for the options of a command.
+
+Options:
+`
+
+// cli holds the global options and the streams commands talk to
+type cli struct {
+	vaultPath  string
+	passwordFD int
+	json       bool
+
+	stdin  *os.File
+	stdout io.Writer
+	stderr io.Writer
+
+	passwords *passwordReader
+}
+
+// command runs one subcommand with its arguments
+type command func(c *cli, args []string) error
+
+var commands = map[string]command{
+	"init":     (*cli).initVault,
+	"unlock":   (*cli).unlock,
+	"ls":       (*cli).list,
+	"get":      (*cli).get,
+	"add":      (*cli).add,
+	"edit":     (*cli).edit,
+	"rm":       (*cli).remove,
+	"generate": (*cli).generate,
+	"import":   (*cli).importCSV,
+	"export":   (*cli).export,
+}
+
+// errUsage reports a usage error whose message has already been printed
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run parses the global options, runs the command and returns the process
+// exit code
+func run(args []string, stdin *os.File, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	flags := flag.NewFlagSet("vaultzero", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&c.vaultPath, "vault", "", "vault file or directory; default: the vault selected in the app")
+	flags.IntVar(&c.passwordFD, "password-fd", -1, "read the master password from this file descriptor instead of the terminal")
+	flags.BoolVar(&c.json, "json", false, "print results as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "vaultzero: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	c.passwords = newPasswordReader(stdin, c.passwordFD, stderr)
+
+	if err := cmd(c, flags.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintln(stderr, "vaultzero:", err)
+		return 1
+	}
+	return 0
+}
+
+// commandFlags creates the flag set of a command
+func (c *cli) commandFlags(name, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: vaultzero %s [options] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse parses a command's arguments, which must leave exactly nargs
+// positional arguments
+func parse(flags *flag.FlagSet, args []string, nargs int) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != nargs {
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// print writes a result: as indented JSON with --json, otherwise with text
+func (c *cli) print(v interface{}, text func(w io.Writer)) error {
+	if !c.json {
+		text(c.stdout)
+		return nil
+	}
+
+	// Passwords are printed as they are, without HTML escaping
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// storage opens the store of the selected vault
+func (c *cli) storage() (*vault.StorageManager, error) {
+	location := c.vaultPath
+	if location == "" {
+		registry, err := vault.LoadVaultRegistry()
+		if err != nil {
+			return nil, err
+		}
+		location = registry.Current
+	}
+
+	storage, err := vault.NewStorageManager(location)
+	if err != nil {
+		return nil, err
+	}
+	storage.OnBackupError = func(err error) {
+		fmt.Fprintln(c.stderr, "Warning:", err)
+	}
+	return storage, nil
+}
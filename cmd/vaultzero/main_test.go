@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"vaultzero/vault"
+)
+
+const testPassword = "correct horse"
+
+// hotpURI is an RFC 4226 test account; its codes for counters 0 and 1 are
+// 755224 and 287082
+const hotpURI = "otpauth://hotp/Test:alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=0"
+
+// runCLI runs vaultzero on the vault in dir. input is what the command
+// reads on stdin, which is also the master password descriptor: its first
+// line is the master password.
+func runCLI(t *testing.T, dir, input string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+
+	stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	if _, err := stdin.WriteString(input); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stdin.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	args = append([]string{"--vault", dir, "--password-fd", strconv.Itoa(int(stdin.Fd()))}, args...)
+	var out, errOut bytes.Buffer
+	code = run(args, stdin, &out, &errOut)
+	return out.String(), errOut.String(), code
+}
+
+// newTestVault creates a vault in a temporary directory with the CLI,
+// keeping the vault registry out of the user's home directory
+func newTestVault(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	if _, stderr, code := runCLI(t, dir, testPassword+"\n", "init"); code != 0 {
+		t.Fatalf("init exited with %d: %s", code, stderr)
+	}
+	return dir
+}
+
+func TestUsageErrors(t *testing.T) {
+	dir := newTestVault(t)
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no command", nil},
+		{"unknown command", []string{"frobnicate"}},
+		{"unknown global option", []string{"--frobnicate", "ls"}},
+		{"unknown command option", []string{"ls", "-frobnicate"}},
+		{"missing argument", []string{"get"}},
+		{"extra argument", []string{"ls", "extra"}},
+		{"add without name", []string{"add", "-username", "octocat"}},
+		{"field without label", []string{"add", "-name", "GitHub", "-field", "=1234"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, code := runCLI(t, dir, testPassword+"\n", tt.args...)
+			if code != 2 {
+				t.Errorf("exit code %d, want 2", code)
+			}
+			if !strings.Contains(stderr, "Usage") {
+				t.Errorf("no usage printed: %q", stderr)
+			}
+		})
+	}
+}
+
+func TestAddAndGet(t *testing.T) {
+	dir := newTestVault(t)
+
+	// The credential password is the line after the master password
+	stdout, stderr, code := runCLI(t, dir, testPassword+"\nhunter22\n", "add", "-name", "GitHub", "-username", "octocat")
+	if code != 0 {
+		t.Fatalf("add exited with %d: %s", code, stderr)
+	}
+	id := strings.TrimSpace(stdout)
+
+	stdout, stderr, code = runCLI(t, dir, testPassword+"\n", "get", "-field", "password", "github")
+	if code != 0 {
+		t.Fatalf("get exited with %d: %s", code, stderr)
+	}
+	if stdout != "hunter22\n" {
+		t.Errorf("get printed %q, want the password", stdout)
+	}
+
+	stdout, _, code = runCLI(t, dir, testPassword+"\n", "--json", "get", id)
+	var cred vault.Credential
+	if code != 0 || json.Unmarshal([]byte(stdout), &cred) != nil || cred.Username != "octocat" {
+		t.Fatalf("get --json exited with %d and printed %q", code, stdout)
+	}
+
+	if _, stderr, code = runCLI(t, dir, testPassword+"\n", "get", "-field", "pin", id); code != 1 || !strings.Contains(stderr, "unknown field") {
+		t.Errorf("unknown field: exit code %d, %q", code, stderr)
+	}
+	if _, stderr, code = runCLI(t, dir, testPassword+"\n", "get", "GitLab"); code != 1 || !strings.Contains(stderr, "not found") {
+		t.Errorf("unknown credential: exit code %d, %q", code, stderr)
+	}
+}
+
+func TestCustomFieldOptions(t *testing.T) {
+	dir := newTestVault(t)
+
+	_, stderr, code := runCLI(t, dir, testPassword+"\nhunter22\n", "add", "-name", "Bank", "-username", "me",
+		"-field", "Account=12-345",
+		"-field", "PIN=hidden:0000",
+		"-field", "Support=url:https://bank.example/help",
+		"-field", "Code=totp:JBSWY3DPEHPK3PXP")
+	if code != 0 {
+		t.Fatalf("add exited with %d: %s", code, stderr)
+	}
+
+	fields := func() []vault.CustomField {
+		t.Helper()
+		stdout, stderr, code := runCLI(t, dir, testPassword+"\n", "--json", "get", "bank")
+		var cred vault.Credential
+		if code != 0 || json.Unmarshal([]byte(stdout), &cred) != nil {
+			t.Fatalf("get exited with %d: %s", code, stderr)
+		}
+		return cred.Fields
+	}
+	got := fields()
+	want := []struct{ label, kind string }{
+		{"Account", vault.FieldTypeText},
+		{"PIN", vault.FieldTypeHidden},
+		{"Support", vault.FieldTypeURL},
+		{"Code", vault.FieldTypeTOTP},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("fields %+v", got)
+	}
+	for i, w := range want {
+		if got[i].Label != w.label || got[i].Type != w.kind {
+			t.Errorf("field %d = %+v, want %s of type %s", i, got[i], w.label, w.kind)
+		}
+	}
+	if stdout, _, _ := runCLI(t, dir, testPassword+"\n", "get", "-field", "code", "bank"); len(strings.TrimSpace(stdout)) != 6 {
+		t.Errorf("TOTP field code %q", stdout)
+	}
+
+	// Fields are replaced in place by label, removed and added at the end
+	_, stderr, code = runCLI(t, dir, testPassword+"\n", "edit",
+		"-field", "pin=hidden:1111", "-remove-field", "account", "-field", "Renewal=date:2027-01-31", "bank")
+	if code != 0 {
+		t.Fatalf("edit exited with %d: %s", code, stderr)
+	}
+	got = fields()
+	if len(got) != 4 || got[0].Label != "pin" || got[0].Value != "1111" || got[3].Label != "Renewal" {
+		t.Fatalf("edited fields %+v", got)
+	}
+
+	// Values are checked against their type
+	if _, stderr, code = runCLI(t, dir, testPassword+"\n", "edit", "-field", "Born=date:31/01/1990", "bank"); code != 1 || !strings.Contains(stderr, "YYYY-MM-DD") {
+		t.Errorf("invalid date: exit code %d, %q", code, stderr)
+	}
+}
+
+func TestMasterPasswordSource(t *testing.T) {
+	dir := newTestVault(t)
+
+	if _, stderr, code := runCLI(t, dir, "wrong password\n", "ls"); code != 1 || !strings.Contains(stderr, "invalid master password") {
+		t.Errorf("wrong password: exit code %d, %q", code, stderr)
+	}
+	if _, stderr, code := runCLI(t, dir, "", "ls"); code != 1 || !strings.Contains(stderr, "no password") {
+		t.Errorf("no password: exit code %d, %q", code, stderr)
+	}
+	if _, stderr, code := runCLI(t, dir, "\n", "ls"); code != 1 || !strings.Contains(stderr, "empty password") {
+		t.Errorf("empty password: exit code %d, %q", code, stderr)
+	}
+
+	// A line without a newline at the end of the input is still read
+	if _, stderr, code := runCLI(t, dir, testPassword, "ls"); code != 0 {
+		t.Errorf("password without newline: exit code %d, %q", code, stderr)
+	}
+
+	// Without --password-fd the password must come from a terminal
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	var stderr bytes.Buffer
+	if code := run([]string{"--vault", dir, "ls"}, stdin, &bytes.Buffer{}, &stderr); code != 1 || !strings.Contains(stderr.String(), "--password-fd") {
+		t.Errorf("no terminal: exit code %d, %q", code, stderr.String())
+	}
+}
+
+func TestGetHOTPCode(t *testing.T) {
+	dir := newTestVault(t)
+
+	store, err := vault.NewStorageManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vault.New(store)
+	if err := v.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.AddCredential(vault.Credential{ServiceName: "Bank", OTPAuth: hotpURI}); err != nil {
+		t.Fatal(err)
+	}
+
+	// While another process holds the vault the counter cannot be saved
+	stdout, stderr, code := runCLI(t, dir, testPassword+"\n", "get", "-field", "otp", "Bank")
+	if code != 1 || !strings.Contains(stderr, "in use") || stdout != "" {
+		t.Fatalf("HOTP code from a vault in use: exit code %d, %q, %q", code, stdout, stderr)
+	}
+	v.Lock()
+
+	for _, want := range []string{"755224\n", "287082\n"} {
+		stdout, stderr, code = runCLI(t, dir, testPassword+"\n", "get", "-field", "otp", "Bank")
+		if code != 0 || stdout != want {
+			t.Fatalf("HOTP code: exit code %d, %q, %q; want %q", code, stdout, stderr, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// passwordReader reads the master password and credential passwords. With
+// a password file descriptor the master password is its first line;
+// otherwise it is typed on the terminal without echo. Credential passwords
+// come from the terminal or, when stdin is not one, from lines on stdin.
+type passwordReader struct {
+	stdin  *os.File
+	fd     int
+	prompt io.Writer
+
+	lines map[int]*bufio.Reader // buffered readers of non-terminal inputs, by fd
+}
+
+func newPasswordReader(stdin *os.File, fd int, prompt io.Writer) *passwordReader {
+	return &passwordReader{
+		stdin:  stdin,
+		fd:     fd,
+		prompt: prompt,
+		lines:  make(map[int]*bufio.Reader),
+	}
+}
+
+// master reads the master password
+func (pr *passwordReader) master(prompt string) (string, error) {
+	if pr.fd >= 0 {
+		if pr.fd == int(pr.stdin.Fd()) {
+			return pr.readLine(pr.stdin)
+		}
+		return pr.readLine(os.NewFile(uintptr(pr.fd), "password-fd"))
+	}
+
+	if !pr.isTerminal() {
+		return "", errors.New("no terminal to read the master password from; use --password-fd")
+	}
+	return pr.readTerminal(prompt)
+}
+
+// newMaster reads a new master password, asking twice on a terminal
+func (pr *passwordReader) newMaster() (string, error) {
+	password, err := pr.master("New master password: ")
+	if err != nil || pr.fd >= 0 {
+		return password, err
+	}
+
+	confirm, err := pr.readTerminal("Repeat master password: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm != password {
+		return "", errors.New("passwords do not match")
+	}
+	return password, nil
+}
+
+// secret reads a credential's password from the terminal or stdin
+func (pr *passwordReader) secret(prompt string) (string, error) {
+	if pr.isTerminal() {
+		return pr.readTerminal(prompt)
+	}
+	return pr.readLine(pr.stdin)
+}
+
+// isTerminal reports whether stdin is an interactive terminal
+func (pr *passwordReader) isTerminal() bool {
+	return term.IsTerminal(int(pr.stdin.Fd()))
+}
+
+// readTerminal prompts on stderr and reads a line without echoing it
+func (pr *passwordReader) readTerminal(prompt string) (string, error) {
+	fmt.Fprint(pr.prompt, prompt)
+	password, err := term.ReadPassword(int(pr.stdin.Fd()))
+	fmt.Fprintln(pr.prompt)
+	if err != nil {
+		return "", err
+	}
+	if len(password) == 0 {
+		return "", errors.New("no password entered")
+	}
+	return string(password), nil
+}
+
+// readLine reads the next line from a non-terminal input. Readers are kept
+// so that several passwords can be read from the same input.
+func (pr *passwordReader) readLine(file *os.File) (string, error) {
+	lines, ok := pr.lines[int(file.Fd())]
+	if !ok {
+		lines = bufio.NewReader(file)
+		pr.lines[int(file.Fd())] = lines
+	}
+
+	line, err := lines.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", fmt.Errorf("no password on %s", file.Name())
+		}
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("empty password on %s", file.Name())
+	}
+	return password, nil
+}
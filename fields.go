@@ -0,0 +1,51 @@
+package main
+
+import (
+	"time"
+
+	"vaultzero/vault"
+)
+
+// SetCustomFields replaces the ordered custom fields of a credential, credit
+// card, secure note or identity
+func (a *App) SetCustomFields(itemID string, fields []vault.CustomField) error {
+	if err := a.vault.SetCustomFields(itemID, fields); err != nil {
+		return err
+	}
+
+	a.emitItemsUpdated()
+	return nil
+}
+
+// CopyCustomField copies a custom field's value to clipboard with auto-clear.
+// A TOTP field copies its current one-time password.
+func (a *App) CopyCustomField(itemID, fieldID string) error {
+	field, err := a.vault.CustomField(itemID, fieldID)
+	if err != nil {
+		return err
+	}
+
+	if field.Type == vault.FieldTypeTOTP {
+		code, err := field.Code(time.Now())
+		if err != nil {
+			return err
+		}
+		return ClipboardCopy(code.Code)
+	}
+	return ClipboardCopy(field.Value)
+}
+
+// GetCustomFieldCode returns the current one-time password of a TOTP field
+// and how many seconds it stays valid
+func (a *App) GetCustomFieldCode(itemID, fieldID string) (*vault.OneTimeCode, error) {
+	field, err := a.vault.CustomField(itemID, fieldID)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := field.Code(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
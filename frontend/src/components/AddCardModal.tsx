@@ -10,6 +10,8 @@ import {
   getCardColor,
   getCardBrandName,
 } from '../utils/creditCard';
+import CustomFieldEditor from './CustomFieldEditor';
+import { EditableField, toEditableFields, fromEditableFields } from '../utils/customFields';
 
 interface AddCardModalProps {
   isOpen: boolean;
@@ -26,6 +28,7 @@ const AddCardModal: React.FC<AddCardModalProps> = ({ isOpen, onClose, onSuccess,
   const [expiryYear, setExpiryYear] = useState('');
   const [cvv, setCvv] = useState('');
   const [billingZip, setBillingZip] = useState('');
+  const [fields, setFields] = useState<EditableField[]>([]);
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState('');
 
@@ -41,6 +44,7 @@ const AddCardModal: React.FC<AddCardModalProps> = ({ isOpen, onClose, onSuccess,
       setExpiryYear(editCard.expiryYear);
       setCvv(editCard.cvv);
       setBillingZip(editCard.billingZip || '');
+      setFields(toEditableFields(editCard.fields));
     } else {
       resetForm();
     }
@@ -54,6 +58,7 @@ const AddCardModal: React.FC<AddCardModalProps> = ({ isOpen, onClose, onSuccess,
     setExpiryYear('');
     setCvv('');
     setBillingZip('');
+    setFields([]);
     setError('');
   };
 
@@ -128,8 +133,9 @@ const AddCardModal: React.FC<AddCardModalProps> = ({ isOpen, onClose, onSuccess,
           cardType,
           billingZip
         );
+        await App.SetCustomFields(editCard.id, fromEditableFields(fields));
       } else {
-        await App.AddCreditCard(
+        const id = await App.AddCreditCard(
           cardName,
           cardholderName,
           cardNumber,
@@ -139,6 +145,9 @@ const AddCardModal: React.FC<AddCardModalProps> = ({ isOpen, onClose, onSuccess,
           cardType,
           billingZip
         );
+        if (fields.length > 0) {
+          await App.SetCustomFields(id, fromEditableFields(fields));
+        }
       }
 
       onSuccess();
@@ -314,6 +323,9 @@ const AddCardModal: React.FC<AddCardModalProps> = ({ isOpen, onClose, onSuccess,
             />
           </div>
 
+          {/* Custom Fields */}
+          <CustomFieldEditor fields={fields} onChange={setFields} />
+
           {/* Actions */}
           <div className="flex gap-3 pt-4">
             <button
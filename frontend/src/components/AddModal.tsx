@@ -1,10 +1,12 @@
 import { useState, useEffect } from 'react';
 import { X, Save, Eye, EyeOff, Key, AlertTriangle } from 'lucide-react';
-import { Credential, Category } from '../types';
+import { Credential, Category, MatchMode } from '../types';
 import * as App from '../wailsjs/go/main/App';
 import PasswordGenerator from './PasswordGenerator';
 import PasswordStrengthIndicator from './PasswordStrengthIndicator';
 import { checkDuplicatePassword } from '../utils/duplicatePassword';
+import CustomFieldEditor from './CustomFieldEditor';
+import { EditableField, toEditableFields, fromEditableFields } from '../utils/customFields';
 
 interface AddModalProps {
   isOpen: boolean;
@@ -15,12 +17,21 @@ interface AddModalProps {
 
 const categories: Category[] = ['Social', 'Work', 'Finance', 'Other'];
 
+const matchModes: { value: MatchMode; label: string }[] = [
+  { value: 'base', label: 'Same site (any subdomain)' },
+  { value: 'exact', label: 'Exact host only' },
+  { value: 'equivalent', label: 'Same site or equivalent domains' },
+];
+
 const AddModal: React.FC<AddModalProps> = ({ isOpen, onClose, onSuccess, editCredential }) => {
   const [serviceName, setServiceName] = useState('');
   const [url, setUrl] = useState('');
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
   const [category, setCategory] = useState<string>('Other');
+  const [matchMode, setMatchMode] = useState<MatchMode>('base');
+  const [equivalentDomains, setEquivalentDomains] = useState('');
+  const [fields, setFields] = useState<EditableField[]>([]);
   const [showPassword, setShowPassword] = useState(false);
   const [showGenerator, setShowGenerator] = useState(false);
   const [loading, setLoading] = useState(false);
@@ -37,6 +48,9 @@ const AddModal: React.FC<AddModalProps> = ({ isOpen, onClose, onSuccess, editCre
       setUsername(editCredential.username);
       setPassword(editCredential.password);
       setCategory(editCredential.category);
+      setMatchMode(editCredential.matchMode || 'base');
+      setEquivalentDomains((editCredential.equivalentDomains || []).join(', '));
+      setFields(toEditableFields(editCredential.fields));
     } else {
       resetForm();
     }
@@ -64,6 +78,9 @@ const AddModal: React.FC<AddModalProps> = ({ isOpen, onClose, onSuccess, editCre
     setUsername('');
     setPassword('');
     setCategory('Other');
+    setMatchMode('base');
+    setEquivalentDomains('');
+    setFields([]);
     setShowPassword(false);
     setShowGenerator(false);
     setError('');
@@ -100,14 +117,23 @@ const AddModal: React.FC<AddModalProps> = ({ isOpen, onClose, onSuccess, editCre
           password.trim(),
           category
         );
+        await App.SetCredentialMatching(
+          editCredential.id,
+          matchMode,
+          equivalentDomains.split(',').map((d) => d.trim()).filter((d) => d)
+        );
+        await App.SetCustomFields(editCredential.id, fromEditableFields(fields));
       } else {
-        await App.AddCredential(
+        const id = await App.AddCredential(
           serviceName.trim(),
           url.trim(),
           username.trim(),
           password.trim(),
           category
         );
+        if (fields.length > 0) {
+          await App.SetCustomFields(id, fromEditableFields(fields));
+        }
       }
 
       resetForm();
@@ -198,6 +224,35 @@ const AddModal: React.FC<AddModalProps> = ({ isOpen, onClose, onSuccess, editCre
             </select>
           </div>
 
+          {/* Browser matching (for saved credentials) */}
+          {editCredential && (
+            <div>
+              <label className="block text-sm font-medium text-slate-300 mb-2">
+                Browser Matching
+              </label>
+              <select
+                value={matchMode}
+                onChange={(e) => setMatchMode(e.target.value as MatchMode)}
+                className="w-full px-4 py-3 bg-slate-900/50 border border-slate-700 rounded-lg text-slate-100 focus:outline-none focus:ring-2 focus:ring-primary-500 focus:border-transparent transition-all cursor-pointer"
+              >
+                {matchModes.map((mode) => (
+                  <option key={mode.value} value={mode.value}>
+                    {mode.label}
+                  </option>
+                ))}
+              </select>
+              {matchMode === 'equivalent' && (
+                <input
+                  type="text"
+                  value={equivalentDomains}
+                  onChange={(e) => setEquivalentDomains(e.target.value)}
+                  placeholder="youtube.com, gmail.com"
+                  className="mt-2 w-full px-4 py-3 bg-slate-900/50 border border-slate-700 rounded-lg text-slate-100 placeholder-slate-500 focus:outline-none focus:ring-2 focus:ring-primary-500 focus:border-transparent transition-all"
+                />
+              )}
+            </div>
+          )}
+
           {/* Username */}
           <div>
             <label className="block text-sm font-medium text-slate-300 mb-2">
@@ -292,6 +347,9 @@ const AddModal: React.FC<AddModalProps> = ({ isOpen, onClose, onSuccess, editCre
             )}
           </div>
 
+          {/* Custom Fields */}
+          <CustomFieldEditor fields={fields} onChange={setFields} />
+
           {/* Actions */}
           <div className="flex gap-3 pt-4">
             <button
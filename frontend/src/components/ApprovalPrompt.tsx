@@ -0,0 +1,124 @@
+import { useState, useEffect } from 'react';
+import { ShieldAlert } from 'lucide-react';
+import * as App from '../wailsjs/go/main/App';
+import { EventsOn } from '../wailsjs/runtime/runtime';
+
+interface ApprovalRequest {
+  id: string;
+  origin: string;
+  access: 'credentials' | 'creditCards' | 'identities';
+  client: string;
+  scopes: string[];
+  expiresAt: string;
+}
+
+const accessTitles: Record<string, string> = {
+  credentials: 'Share Logins?',
+  creditCards: 'Share Credit Cards?',
+  identities: 'Share Identities?',
+};
+
+const accessDescriptions: Record<string, string> = {
+  credentials: 'your saved logins',
+  creditCards: 'your credit cards',
+  identities: 'your identities and addresses',
+};
+
+const scopeLabels: Record<string, string> = {
+  once: 'Allow Once',
+  window: 'Allow for 15 Minutes',
+  always: 'Always Allow',
+};
+
+// Asks the user whether a paired browser may read secrets for a site. Requests
+// arrive through the "approval-requested" event and are answered in order.
+const ApprovalPrompt: React.FC = () => {
+  const [requests, setRequests] = useState<ApprovalRequest[]>([]);
+  const [error, setError] = useState('');
+
+  useEffect(() => {
+    EventsOn('approval-requested', (request: ApprovalRequest) => {
+      setRequests((pending) => [...pending, request]);
+    });
+  }, []);
+
+  // Drop prompts the app has stopped waiting for
+  useEffect(() => {
+    if (requests.length === 0) return;
+    const remaining = new Date(requests[0].expiresAt).getTime() - Date.now();
+    const timer = setTimeout(() => {
+      setRequests((pending) => pending.slice(1));
+    }, Math.max(remaining, 0));
+    return () => clearTimeout(timer);
+  }, [requests]);
+
+  if (requests.length === 0) return null;
+
+  const request = requests[0];
+
+  const handleRespond = async (allow: boolean, scope: string) => {
+    setError('');
+    try {
+      await App.RespondToApproval(request.id, allow, scope);
+    } catch (err: any) {
+      setError(err.message || 'Failed to answer the request');
+    }
+    setRequests((pending) => pending.slice(1));
+  };
+
+  return (
+    <div className="fixed inset-0 bg-black/50 backdrop-blur-sm flex items-center justify-center z-[60]">
+      <div className="bg-slate-800 rounded-xl shadow-2xl w-full max-w-md mx-4 border border-slate-700">
+        {/* Header */}
+        <div className="flex items-center gap-2 p-6 border-b border-slate-700">
+          <ShieldAlert className="w-5 h-5 text-amber-400" />
+          <h2 className="text-xl font-semibold text-slate-100">
+            {accessTitles[request.access] || accessTitles.credentials}
+          </h2>
+        </div>
+
+        {/* Content */}
+        <div className="p-6 space-y-4">
+          <p className="text-sm text-slate-400">
+            <span className="text-slate-200">{request.client || 'A paired browser'}</span> wants{' '}
+            {accessDescriptions[request.access] || accessDescriptions.credentials} for
+          </p>
+          <div className="text-center font-mono text-slate-100 bg-slate-900/50 border border-slate-700 rounded-lg py-3 break-all">
+            {request.origin}
+          </div>
+          {request.access !== 'credentials' && (
+            <p className="text-xs text-slate-500">
+              {request.access === 'creditCards' ? 'Credit cards' : 'Identities'} are confirmed every time.
+            </p>
+          )}
+
+          {error && (
+            <div className="p-3 bg-red-500/10 border border-red-500/50 rounded-lg text-sm text-red-400">
+              {error}
+            </div>
+          )}
+
+          <div className="space-y-2">
+            {request.scopes.map((scope) => (
+              <button
+                key={scope}
+                onClick={() => handleRespond(true, scope)}
+                className="w-full px-4 py-2 rounded-lg bg-primary-600 hover:bg-primary-700 text-white font-medium transition-colors"
+              >
+                {scopeLabels[scope] || scope}
+              </button>
+            ))}
+            <button
+              onClick={() => handleRespond(false, '')}
+              className="w-full px-4 py-2 rounded-lg bg-slate-700 hover:bg-slate-600 text-slate-200 font-medium transition-colors"
+            >
+              Deny
+            </button>
+          </div>
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default ApprovalPrompt;
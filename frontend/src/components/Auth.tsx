@@ -47,7 +47,7 @@ const Auth: React.FC<AuthProps> = ({ onAuthenticated }) => {
         if (masterPassword !== confirmPassword) {
           throw new Error('Passwords do not match');
         }
-        await App.CreateVault(masterPassword);
+        await App.CreateVault("", "", masterPassword);
       } else {
         await App.UnlockVault(masterPassword);
       }
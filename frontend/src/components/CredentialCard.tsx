@@ -3,6 +3,7 @@ import { Copy, Eye, EyeOff, Trash2, Edit, Check, AlertCircle, Star } from 'lucid
 import { Credential } from '../types';
 import * as App from '../wailsjs/go/main/App';
 import PasswordStrengthIndicator from './PasswordStrengthIndicator';
+import CustomFieldList from './CustomFieldList';
 import { countPasswordUsage } from '../utils/duplicatePassword';
 
 interface CredentialCardProps {
@@ -210,6 +211,8 @@ const CredentialCard: React.FC<CredentialCardProps> = ({ credential, allCredenti
           </div>
         </div>
       </div>
+
+      <CustomFieldList itemId={credential.id} fields={credential.fields} />
     </div>
   );
 };
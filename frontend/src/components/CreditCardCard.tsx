@@ -3,6 +3,7 @@ import { Copy, Trash2, Edit, Check, Star, Eye, EyeOff } from 'lucide-react';
 import { CreditCard } from '../types';
 import * as App from '../wailsjs/go/main/App';
 import { maskCardNumber, getCardColor, getCardBrandName } from '../utils/creditCard';
+import CustomFieldList from './CustomFieldList';
 
 interface CreditCardCardProps {
   card: CreditCard;
@@ -186,6 +187,8 @@ const CreditCardCard: React.FC<CreditCardCardProps> = ({ card, onDelete, onEdit
             Billing ZIP: <span className="text-slate-400">{card.billingZip}</span>
           </div>
         )}
+
+        <CustomFieldList itemId={card.id} fields={card.fields} />
       </div>
     </div>
   );
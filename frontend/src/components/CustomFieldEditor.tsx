@@ -0,0 +1,146 @@
+import { Plus, Trash2, ChevronUp, ChevronDown } from 'lucide-react';
+import { CustomFieldType } from '../types';
+import { EditableField } from '../utils/customFields';
+
+interface CustomFieldEditorProps {
+  fields: EditableField[];
+  onChange: (fields: EditableField[]) => void;
+}
+
+const fieldTypes: { value: CustomFieldType; label: string }[] = [
+  { value: 'text', label: 'Text' },
+  { value: 'hidden', label: 'Hidden' },
+  { value: 'url', label: 'URL' },
+  { value: 'email', label: 'Email' },
+  { value: 'date', label: 'Date' },
+  { value: 'totp', label: 'One-time password' },
+];
+
+const inputType = (type: CustomFieldType) => {
+  switch (type) {
+    case 'hidden':
+    case 'totp':
+      return 'password';
+    case 'url':
+      return 'url';
+    case 'email':
+      return 'email';
+    case 'date':
+      return 'date';
+    default:
+      return 'text';
+  }
+};
+
+const inputClass =
+  'w-full px-3 py-2 bg-slate-900/50 border border-slate-700 rounded-lg text-slate-100 placeholder-slate-500 focus:outline-none focus:ring-2 focus:ring-primary-500 focus:border-transparent transition-all text-sm';
+
+// Edits an item's custom fields in order. Fields can be added, removed and
+// moved; the form saves them with SetCustomFields.
+const CustomFieldEditor: React.FC<CustomFieldEditorProps> = ({ fields, onChange }) => {
+  const update = (index: number, change: Partial<EditableField>) => {
+    onChange(fields.map((field, i) => (i === index ? { ...field, ...change } : field)));
+  };
+
+  const move = (index: number, offset: number) => {
+    const target = index + offset;
+    if (target < 0 || target >= fields.length) return;
+    const next = [...fields];
+    [next[index], next[target]] = [next[target], next[index]];
+    onChange(next);
+  };
+
+  const remove = (index: number) => {
+    onChange(fields.filter((_, i) => i !== index));
+  };
+
+  const add = () => {
+    onChange([...fields, { id: '', label: '', type: 'text', value: '' }]);
+  };
+
+  return (
+    <div>
+      <label className="block text-sm font-medium text-slate-300 mb-2">
+        Custom Fields
+      </label>
+      <div className="space-y-3">
+        {fields.map((field, index) => (
+          <div key={index} className="bg-slate-900/30 border border-slate-700 rounded-lg p-3 space-y-2">
+            <div className="flex gap-2">
+              <input
+                type="text"
+                value={field.label}
+                onChange={(e) => update(index, { label: e.target.value })}
+                placeholder="Label"
+                className={inputClass}
+              />
+              <select
+                value={field.type}
+                onChange={(e) => update(index, { type: e.target.value as CustomFieldType })}
+                className={`${inputClass} w-auto cursor-pointer`}
+              >
+                {fieldTypes.map((type) => (
+                  <option key={type.value} value={type.value}>
+                    {type.label}
+                  </option>
+                ))}
+              </select>
+            </div>
+            <div className="flex gap-2">
+              <input
+                type={inputType(field.type)}
+                value={field.value}
+                onChange={(e) => update(index, { value: e.target.value })}
+                placeholder={
+                  field.type === 'totp'
+                    ? field.saved
+                      ? 'Saved - enter a new secret to replace it'
+                      : 'otpauth:// URI or secret key'
+                    : 'Value'
+                }
+                autoComplete="off"
+                className={inputClass}
+              />
+              <button
+                type="button"
+                onClick={() => move(index, -1)}
+                disabled={index === 0}
+                className="p-2 rounded-lg hover:bg-slate-700 transition-colors disabled:opacity-30"
+                title="Move up"
+              >
+                <ChevronUp className="w-4 h-4 text-slate-400" />
+              </button>
+              <button
+                type="button"
+                onClick={() => move(index, 1)}
+                disabled={index === fields.length - 1}
+                className="p-2 rounded-lg hover:bg-slate-700 transition-colors disabled:opacity-30"
+                title="Move down"
+              >
+                <ChevronDown className="w-4 h-4 text-slate-400" />
+              </button>
+              <button
+                type="button"
+                onClick={() => remove(index)}
+                className="p-2 rounded-lg hover:bg-slate-700 transition-colors"
+                title="Remove field"
+              >
+                <Trash2 className="w-4 h-4 text-red-400" />
+              </button>
+            </div>
+          </div>
+        ))}
+      </div>
+      <button
+        type="button"
+        onClick={add}
+        className="mt-3 flex items-center gap-1 text-xs text-primary-400 hover:text-primary-300 transition-colors"
+      >
+        <Plus className="w-3 h-3" />
+        Add Field
+      </button>
+    </div>
+  );
+};
+
+export default CustomFieldEditor;
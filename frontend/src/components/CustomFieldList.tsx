@@ -0,0 +1,144 @@
+import { useEffect, useState } from 'react';
+import { Copy, Check, Eye, EyeOff } from 'lucide-react';
+import { CustomField } from '../types';
+import * as App from '../wailsjs/go/main/App';
+
+interface CustomFieldListProps {
+  itemId: string;
+  fields?: CustomField[];
+}
+
+// A TOTP field's current code and when it runs out, in milliseconds
+interface ShownCode {
+  code: string;
+  expiresAt: number;
+}
+
+// Shows an item's custom fields in order. Hidden fields are masked until
+// revealed, and every value is copied with the clipboard auto-clear. TOTP
+// fields never show their secret: revealing one shows its current code,
+// which is replaced when it runs out.
+const CustomFieldList: React.FC<CustomFieldListProps> = ({ itemId, fields }) => {
+  const [revealed, setRevealed] = useState<Record<string, boolean>>({});
+  const [codes, setCodes] = useState<Record<string, ShownCode>>({});
+  const [now, setNow] = useState(Date.now());
+  const [copied, setCopied] = useState('');
+
+  const loadCode = async (id: string) => {
+    try {
+      const code = await App.GetCustomFieldCode(itemId, id);
+      setCodes((current) => ({
+        ...current,
+        [id]: { code: code.code, expiresAt: Date.now() + code.secondsRemaining * 1000 },
+      }));
+    } catch (error) {
+      console.error('Failed to get one-time code:', error);
+    }
+  };
+
+  // Count down while any code is shown
+  const showingCodes = Object.keys(codes).length > 0;
+  useEffect(() => {
+    if (!showingCodes) return;
+    const timer = setInterval(() => setNow(Date.now()), 1000);
+    return () => clearInterval(timer);
+  }, [showingCodes]);
+
+  useEffect(() => {
+    Object.entries(codes).forEach(([id, shown]) => {
+      if (shown.expiresAt <= now) {
+        loadCode(id);
+      }
+    });
+  }, [now]);
+
+  if (!fields || fields.length === 0) return null;
+
+  const handleCopy = async (field: CustomField) => {
+    try {
+      await App.CopyCustomField(itemId, field.id);
+      setCopied(field.id);
+      setTimeout(() => setCopied(''), 2000);
+    } catch (error) {
+      console.error('Failed to copy field:', error);
+    }
+  };
+
+  const toggleReveal = (field: CustomField) => {
+    const show = !revealed[field.id];
+    setRevealed((current) => ({ ...current, [field.id]: show }));
+
+    if (field.type !== 'totp') return;
+    if (show) {
+      setNow(Date.now());
+      loadCode(field.id);
+    } else {
+      setCodes((current) => {
+        const { [field.id]: _, ...rest } = current;
+        return rest;
+      });
+    }
+  };
+
+  const displayValue = (field: CustomField, concealed: boolean) => {
+    if (concealed && !revealed[field.id]) return '••••••••••••';
+    if (field.type !== 'totp') return field.value;
+
+    const shown = codes[field.id];
+    if (!shown) return '······';
+    const seconds = Math.max(Math.ceil((shown.expiresAt - now) / 1000), 0);
+    return `${shown.code} · ${seconds}s`;
+  };
+
+  return (
+    <div className="mt-3 space-y-2">
+      {fields.map((field) => {
+        const concealed = field.type === 'hidden' || field.type === 'totp';
+        return (
+          <div key={field.id} className="flex items-center justify-between bg-slate-900/50 rounded-lg px-4 py-3">
+            <div className="flex-1 min-w-0">
+              <div className="text-xs text-slate-500 mb-1">{field.label}</div>
+              <div className={`text-slate-200 truncate ${concealed ? 'font-mono' : ''}`}>
+                {displayValue(field, concealed)}
+              </div>
+            </div>
+            <div className="flex gap-2 ml-3 flex-shrink-0">
+              {concealed && (
+                <button
+                  onClick={() => toggleReveal(field)}
+                  className="p-2 rounded-lg bg-slate-700 hover:bg-slate-600 transition-colors"
+                  title={
+                    revealed[field.id]
+                      ? `Hide ${field.label}`
+                      : field.type === 'totp'
+                        ? 'Show current code'
+                        : `Show ${field.label}`
+                  }
+                >
+                  {revealed[field.id] ? (
+                    <EyeOff className="w-4 h-4 text-slate-300" />
+                  ) : (
+                    <Eye className="w-4 h-4 text-slate-300" />
+                  )}
+                </button>
+              )}
+              <button
+                onClick={() => handleCopy(field)}
+                className="p-2 rounded-lg bg-slate-700 hover:bg-slate-600 transition-colors"
+                title={field.type === 'totp' ? 'Copy current code' : `Copy ${field.label}`}
+              >
+                {copied === field.id ? (
+                  <Check className="w-4 h-4 text-green-400" />
+                ) : (
+                  <Copy className="w-4 h-4 text-slate-300" />
+                )}
+              </button>
+            </div>
+          </div>
+        );
+      })}
+    </div>
+  );
+};
+
+export default CustomFieldList;
@@ -1,8 +1,9 @@
 import { useState, useEffect } from 'react';
-import { Search, Plus, Lock, LogOut, Grid, List, Users, Briefcase, DollarSign, Folder, Download, Upload, Key, CreditCard as CreditCardIcon } from 'lucide-react';
+import { Search, Plus, Lock, LogOut, Grid, List, Users, Briefcase, DollarSign, Folder, Download, Upload, Key, Link, CreditCard as CreditCardIcon } from 'lucide-react';
 import { Credential, Category, CreditCard } from '../types';
 import * as App from '../wailsjs/go/main/App';
 import { EventsOn } from '../wailsjs/runtime/runtime';
+import { customFieldsMatch } from '../utils/customFields';
 import CredentialCard from './CredentialCard';
 import CreditCardCard from './CreditCardCard';
 import AddModal from './AddModal';
@@ -10,6 +11,8 @@ import AddCardModal from './AddCardModal';
 import ImportModal from './ImportModal';
 import ExportModal from './ExportModal';
 import ChangePasswordModal from './ChangePasswordModal';
+import PairingModal from './PairingModal';
+import ApprovalPrompt from './ApprovalPrompt';
 import { useAutoLock } from '../hooks/useAutoLock';
 
 const Dashboard: React.FC = () => {
@@ -25,6 +28,7 @@ const Dashboard: React.FC = () => {
   const [isImportModalOpen, setIsImportModalOpen] = useState(false);
   const [isExportModalOpen, setIsExportModalOpen] = useState(false);
   const [isChangePasswordModalOpen, setIsChangePasswordModalOpen] = useState(false);
+  const [isPairingModalOpen, setIsPairingModalOpen] = useState(false);
   const [editCredential, setEditCredential] = useState<Credential | null>(null);
   const [editCard, setEditCard] = useState<CreditCard | null>(null);
   const [viewMode, setViewMode] = useState<'grid' | 'list'>('grid');
@@ -89,7 +93,8 @@ const Dashboard: React.FC = () => {
         (cred) =>
           cred.serviceName.toLowerCase().includes(query) ||
           cred.username.toLowerCase().includes(query) ||
-          cred.url.toLowerCase().includes(query)
+          cred.url.toLowerCase().includes(query) ||
+          customFieldsMatch(cred.fields, query)
       );
     }
 
@@ -125,7 +130,8 @@ const Dashboard: React.FC = () => {
         (card) =>
           card.cardName.toLowerCase().includes(query) ||
           card.cardholderName.toLowerCase().includes(query) ||
-          card.cardNumber.includes(query)
+          card.cardNumber.includes(query) ||
+          customFieldsMatch(card.fields, query)
       );
     }
 
@@ -327,6 +333,13 @@ const Dashboard: React.FC = () => {
             <Key className="w-5 h-5" />
             <span className="font-medium">Change Password</span>
           </button>
+          <button
+            onClick={() => setIsPairingModalOpen(true)}
+            className="w-full flex items-center gap-3 px-4 py-3 rounded-lg bg-slate-700 hover:bg-slate-600 text-slate-300 transition-colors"
+          >
+            <Link className="w-5 h-5" />
+            <span className="font-medium">Pair Browser</span>
+          </button>
           <button
             onClick={handleLockVault}
             className="w-full flex items-center gap-3 px-4 py-3 rounded-lg bg-slate-700 hover:bg-slate-600 text-slate-300 transition-colors"
@@ -556,6 +569,15 @@ const Dashboard: React.FC = () => {
           console.log('Master password changed successfully');
         }}
       />
+
+      {/* Browser Pairing Modal */}
+      <PairingModal
+        isOpen={isPairingModalOpen}
+        onClose={() => setIsPairingModalOpen(false)}
+      />
+
+      {/* Browser access requests */}
+      <ApprovalPrompt />
     </div>
   );
 };
@@ -16,6 +16,7 @@ const ImportModal: React.FC<ImportModalProps> = ({ isOpen, onClose, onSuccess })
   const [importing, setImporting] = useState(false);
   const [result, setResult] = useState<any>(null);
   const [error, setError] = useState('');
+  const [backupPassword, setBackupPassword] = useState('');
   const fileInputRef = useRef<HTMLInputElement>(null);
 
   const handleFileSelect = (e: React.ChangeEvent<HTMLInputElement>) => {
@@ -67,7 +68,7 @@ const ImportModal: React.FC<ImportModalProps> = ({ isOpen, onClose, onSuccess })
 
     try {
       // Backend opens its own file dialog
-      const importResult = await App.ImportEncryptedBackup();
+      const importResult = await App.ImportEncryptedBackup(backupPassword);
 
       setResult(importResult);
 
@@ -88,6 +89,7 @@ const ImportModal: React.FC<ImportModalProps> = ({ isOpen, onClose, onSuccess })
     setFile(null);
     setResult(null);
     setError('');
+    setBackupPassword('');
     setImportType('csv');
     onClose();
   };
@@ -283,8 +285,16 @@ const ImportModal: React.FC<ImportModalProps> = ({ isOpen, onClose, onSuccess })
                 Import Encrypted Backup
               </h3>
               <p className="text-sm text-slate-400 mb-6">
-                Click the button below to select your .vault backup file. Your credentials will be decrypted using your current master password.
+                Click the button below to select your .vault backup file. Backups of this vault open with your current master password; for a backup of another vault, or one made before you changed your master password, enter the master password it was exported with.
               </p>
+              <input
+                type="password"
+                value={backupPassword}
+                onChange={(e) => setBackupPassword(e.target.value)}
+                placeholder="Backup master password (optional)"
+                className="w-full mb-6 px-4 py-3 bg-slate-900/50 border border-slate-700 rounded-lg text-slate-100 placeholder-slate-500 focus:outline-none focus:ring-2 focus:ring-primary-500 focus:border-transparent transition-all"
+                disabled={importing}
+              />
               <button
                 onClick={handleImportBackup}
                 disabled={importing}
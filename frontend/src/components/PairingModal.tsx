@@ -0,0 +1,202 @@
+import { useState, useEffect } from 'react';
+import { X, Link, Trash2 } from 'lucide-react';
+import * as App from '../wailsjs/go/main/App';
+
+interface PairingModalProps {
+  isOpen: boolean;
+  onClose: () => void;
+}
+
+interface PairedClient {
+  id: string;
+  name: string;
+  pairedAt: string;
+}
+
+interface ApprovalGrant {
+  id: string;
+  origin: string;
+  access: string;
+  scope: 'window' | 'always';
+  client: string;
+  expiresAt?: string;
+}
+
+const PairingModal: React.FC<PairingModalProps> = ({ isOpen, onClose }) => {
+  const [code, setCode] = useState('');
+  const [clients, setClients] = useState<PairedClient[]>([]);
+  const [grants, setGrants] = useState<ApprovalGrant[]>([]);
+  const [error, setError] = useState('');
+
+  const loadClients = async () => {
+    try {
+      const result = await App.ListPairedClients();
+      setClients(result || []);
+    } catch (err: any) {
+      setError(err.message || 'Failed to load paired browsers');
+    }
+  };
+
+  const loadGrants = async () => {
+    try {
+      const result = await App.ListApprovals();
+      setGrants(result || []);
+    } catch (err: any) {
+      setError(err.message || 'Failed to load site access');
+    }
+  };
+
+  useEffect(() => {
+    if (isOpen) {
+      setCode('');
+      setError('');
+      loadClients();
+      loadGrants();
+    }
+  }, [isOpen]);
+
+  if (!isOpen) return null;
+
+  const handleStartPairing = async () => {
+    setError('');
+    try {
+      setCode(await App.StartPairing());
+    } catch (err: any) {
+      setError(err.message || 'Failed to start pairing');
+    }
+  };
+
+  const handleUnpair = async (id: string) => {
+    setError('');
+    try {
+      await App.UnpairClient(id);
+      await loadClients();
+    } catch (err: any) {
+      setError(err.message || 'Failed to remove browser');
+    }
+  };
+
+  const handleRevoke = async (id: string) => {
+    setError('');
+    try {
+      await App.RevokeApproval(id);
+      await loadGrants();
+    } catch (err: any) {
+      setError(err.message || 'Failed to revoke access');
+    }
+  };
+
+  return (
+    <div className="fixed inset-0 bg-black/50 backdrop-blur-sm flex items-center justify-center z-50">
+      <div className="bg-slate-800 rounded-xl shadow-2xl w-full max-w-lg mx-4 border border-slate-700">
+        {/* Header */}
+        <div className="flex items-center justify-between p-6 border-b border-slate-700">
+          <h2 className="text-xl font-semibold text-slate-100 flex items-center gap-2">
+            <Link className="w-5 h-5 text-primary-500" />
+            Browser Pairing
+          </h2>
+          <button
+            onClick={onClose}
+            className="text-slate-400 hover:text-slate-300 transition-colors"
+          >
+            <X className="w-5 h-5" />
+          </button>
+        </div>
+
+        {/* Content */}
+        <div className="p-6 space-y-6">
+          <div className="space-y-3">
+            <p className="text-sm text-slate-400">
+              The browser extension only gets access after it is paired. Start pairing and enter the code in the extension popup within two minutes.
+            </p>
+            {code ? (
+              <div className="text-center text-3xl font-mono tracking-widest text-slate-100 bg-slate-900/50 border border-slate-700 rounded-lg py-4">
+                {code}
+              </div>
+            ) : (
+              <button
+                onClick={handleStartPairing}
+                className="w-full px-4 py-3 rounded-lg bg-primary-600 hover:bg-primary-700 text-white font-medium transition-colors"
+              >
+                Start Pairing
+              </button>
+            )}
+          </div>
+
+          {/* Paired browsers */}
+          <div className="space-y-2">
+            <label className="block text-sm font-medium text-slate-300">
+              Paired Browsers
+            </label>
+            {clients.length === 0 ? (
+              <p className="text-sm text-slate-500">No browsers are paired.</p>
+            ) : (
+              clients.map((client) => (
+                <div
+                  key={client.id}
+                  className="flex items-center justify-between p-3 rounded-lg bg-slate-900/50 border border-slate-700"
+                >
+                  <div>
+                    <div className="text-sm text-slate-200">{client.name}</div>
+                    <div className="text-xs text-slate-500">
+                      Paired {new Date(client.pairedAt).toLocaleString()}
+                    </div>
+                  </div>
+                  <button
+                    onClick={() => handleUnpair(client.id)}
+                    className="text-slate-400 hover:text-red-400 transition-colors"
+                    title="Remove"
+                  >
+                    <Trash2 className="w-4 h-4" />
+                  </button>
+                </div>
+              ))
+            )}
+          </div>
+
+          {/* Sites allowed without asking */}
+          <div className="space-y-2">
+            <label className="block text-sm font-medium text-slate-300">
+              Site Access
+            </label>
+            {grants.length === 0 ? (
+              <p className="text-sm text-slate-500">Every site asks for approval.</p>
+            ) : (
+              grants.map((grant) => (
+                <div
+                  key={grant.id}
+                  className="flex items-center justify-between p-3 rounded-lg bg-slate-900/50 border border-slate-700"
+                >
+                  <div className="min-w-0">
+                    <div className="text-sm text-slate-200 truncate">{grant.origin}</div>
+                    <div className="text-xs text-slate-500">
+                      {grant.scope === 'always'
+                        ? 'Always allowed'
+                        : `Allowed until ${new Date(grant.expiresAt || '').toLocaleTimeString()}`}
+                      {grant.client && ` · ${grant.client}`}
+                    </div>
+                  </div>
+                  <button
+                    onClick={() => handleRevoke(grant.id)}
+                    className="text-slate-400 hover:text-red-400 transition-colors"
+                    title="Revoke"
+                  >
+                    <Trash2 className="w-4 h-4" />
+                  </button>
+                </div>
+              ))
+            )}
+          </div>
+
+          {error && (
+            <div className="p-3 bg-red-500/10 border border-red-500/50 rounded-lg text-sm text-red-400">
+              {error}
+            </div>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default PairingModal;
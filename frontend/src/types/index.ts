@@ -8,8 +8,14 @@ export interface Credential {
   iconURL: string;
   isFavorite: boolean;
   createdAt: string;
+  matchMode?: MatchMode;
+  equivalentDomains?: string[];
+  fields?: CustomField[];
 }
 
+// How the browser extension matches a credential's URL against pages
+export type MatchMode = '' | 'base' | 'exact' | 'equivalent';
+
 export interface CreditCard {
   id: string;
   cardName: string;
@@ -22,6 +28,17 @@ export interface CreditCard {
   billingZip: string;
   isFavorite: boolean;
   createdAt: string;
+  fields?: CustomField[];
+}
+
+// Custom field types; hidden and TOTP values are masked like passwords
+export type CustomFieldType = 'text' | 'hidden' | 'url' | 'email' | 'date' | 'totp';
+
+export interface CustomField {
+  id: string;
+  label: string;
+  type: CustomFieldType;
+  value: string;
 }
 
 export type Category = 'All' | 'Social' | 'Work' | 'Finance' | 'Other';
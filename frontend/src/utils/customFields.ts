@@ -0,0 +1,43 @@
+import { CustomField } from '../types';
+
+/**
+ * Check if a search query matches an item's custom fields. Labels always
+ * count; hidden and TOTP values are secrets and are not searched.
+ */
+export const customFieldsMatch = (fields: CustomField[] | undefined, query: string): boolean => {
+  if (!fields) {
+    return false;
+  }
+
+  return fields.some(
+    (field) =>
+      field.label.toLowerCase().includes(query) ||
+      (field.type !== 'hidden' && field.type !== 'totp' && field.value.toLowerCase().includes(query))
+  );
+};
+
+/**
+ * A custom field in an item form. Saved TOTP secrets are never put back in
+ * the form: their value starts empty and `saved` keeps the stored one until
+ * a new secret is entered.
+ */
+export interface EditableField extends CustomField {
+  saved?: string;
+}
+
+export const toEditableFields = (fields: CustomField[] | undefined): EditableField[] =>
+  (fields || []).map((field) =>
+    field.type === 'totp' ? { ...field, value: '', saved: field.value } : { ...field }
+  );
+
+/**
+ * The fields to save from a form. Rows left completely empty are dropped,
+ * and TOTP fields without a new secret keep the saved one.
+ */
+export const fromEditableFields = (fields: EditableField[]): CustomField[] =>
+  fields
+    .filter((field) => field.label.trim() || field.value.trim() || field.saved)
+    .map(({ saved, ...field }) => ({
+      ...field,
+      value: field.type === 'totp' && !field.value.trim() && saved ? saved : field.value,
+    }));
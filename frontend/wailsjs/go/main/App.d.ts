@@ -38,7 +38,7 @@ export function GetAllCredentials():Promise<Array<main.Credential>>;
 
 export function GetAllCreditCards():Promise<Array<main.CreditCard>>;
 
-export function ImportEncryptedBackup():Promise<main.ImportResult>;
+export function ImportEncryptedBackup(arg1:string):Promise<main.ImportResult>;
 
 export function ImportFromCSV(arg1:string):Promise<main.ImportResult>;
 
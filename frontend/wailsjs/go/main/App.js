@@ -74,8 +74,8 @@ export function GetAllCreditCards() {
   return window['go']['main']['App']['GetAllCreditCards']();
 }
 
-export function ImportEncryptedBackup() {
-  return window['go']['main']['App']['ImportEncryptedBackup']();
+export function ImportEncryptedBackup(arg1) {
+  return window['go']['main']['App']['ImportEncryptedBackup'](arg1);
 }
 
 export function ImportFromCSV(arg1) {
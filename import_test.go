@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"vaultzero/vault"
+)
+
+func TestImportFromCSVErrors(t *testing.T) {
+	v := vault.New(vault.NewMemoryStore(t.Name()))
+	a := &App{vault: v}
+
+	if _, err := a.ImportFromCSV("name,url,username,password\n"); !errors.Is(err, vault.ErrLocked) {
+		t.Errorf("import into a locked vault: %v", err)
+	}
+
+	if err := v.Create("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(v.Lock)
+
+	// A bad file is reported as such, not as a failure to save
+	if _, err := a.ImportFromCSV(""); err == nil || err.Error() != "CSV file is empty" {
+		t.Errorf("empty file: %v", err)
+	}
+
+	result, err := a.ImportFromCSV("name,url,username,password\nGitHub,https://github.com,octocat,hunter22\n")
+	if err != nil || result.Imported != 1 {
+		t.Fatalf("import: %+v, %v", result, err)
+	}
+}
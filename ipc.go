@@ -0,0 +1,429 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"vaultzero/ipcproto"
+	"vaultzero/vault"
+)
+
+const (
+	// ipcIdleTimeout closes connections from clients that stopped talking
+	ipcIdleTimeout = 5 * time.Minute
+
+	ipcAppName = "VaultZero"
+)
+
+// ipcCapabilities lists the actions the app accepts from paired clients
+var ipcCapabilities = []string{
+	ipcproto.ActionPair,
+	ipcproto.ActionSearch,
+	ipcproto.ActionFill,
+	ipcproto.ActionSave,
+	ipcproto.ActionGetCreditCards,
+	ipcproto.ActionOneTimeCode,
+	ipcproto.ActionGetIdentities,
+}
+
+// serveConnection answers framed requests on a connection until the client
+// hangs up or the stream breaks
+func (s *IPCServer) serveConnection(stream io.ReadWriter) {
+	conn := ipcproto.NewConn(stream)
+
+	for {
+		var request ipcproto.Request
+		var response *ipcproto.Response
+
+		err := conn.Receive(&request)
+		switch {
+		case err == nil:
+			response = s.handleEnvelope(&request)
+		case errors.Is(err, ipcproto.ErrInvalidMessage):
+			response = ipcFailure(ipcproto.NewError(ipcproto.CodeBadRequest, "Invalid request format"))
+		default:
+			// Client went away or sent a broken frame; the stream is unusable
+			return
+		}
+
+		response.Version = ipcproto.Version
+		if err := conn.Send(response); err != nil {
+			return
+		}
+	}
+}
+
+// handleEnvelope answers the messages a client may send in the clear:
+// hello, pairing, and sealed requests. Sealed requests are authenticated and
+// decrypted before they reach handleRequest, and their responses are sealed
+// the same way; unsealed actions are rejected before dispatch.
+func (s *IPCServer) handleEnvelope(request *ipcproto.Request) *ipcproto.Response {
+	if request.Action == ipcproto.ActionHello {
+		return s.handleHello(request.Hello)
+	}
+	if request.Version != ipcproto.Version {
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeUnsupportedVersion,
+			fmt.Sprintf("Unsupported protocol version %d", request.Version)))
+	}
+
+	pairing := s.app.pairing
+	if pairing == nil {
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeFailed, "Browser pairing is unavailable"))
+	}
+
+	switch request.Action {
+	case ipcproto.ActionPair:
+		paired, err := pairing.pair(request.Pair)
+		if err != nil {
+			return ipcFailure(err)
+		}
+		return &ipcproto.Response{
+			Success: true,
+			Pairing: paired,
+		}
+
+	case ipcproto.ActionSealed:
+		client, inner, err := pairing.openRequest(request.Sealed)
+		if err != nil {
+			return ipcFailure(err)
+		}
+
+		response, err := pairing.sealResponse(client, inner.Nonce, s.handleRequest(client, inner))
+		if err != nil {
+			return ipcFailure(err)
+		}
+		return response
+
+	default:
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeNotPaired, "Requests must be sealed by a paired client"))
+	}
+}
+
+// handleHello negotiates the protocol version and reports the app's capabilities
+func (s *IPCServer) handleHello(hello *ipcproto.HelloRequest) *ipcproto.Response {
+	if hello == nil {
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeBadRequest, "Missing hello"))
+	}
+
+	supported := false
+	for _, version := range hello.Versions {
+		if version == ipcproto.Version {
+			supported = true
+		}
+	}
+	if !supported {
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeUnsupportedVersion,
+			fmt.Sprintf("VaultZero speaks protocol version %d", ipcproto.Version)))
+	}
+
+	return &ipcproto.Response{
+		Success: true,
+		Hello: &ipcproto.HelloResponse{
+			Version:      ipcproto.Version,
+			App:          ipcAppName,
+			Capabilities: ipcCapabilities,
+			Paired:       s.app.pairing != nil && s.app.pairing.isPaired(hello.ClientID),
+		},
+	}
+}
+
+// handleRequest processes an authenticated IPC request from a paired client
+func (s *IPCServer) handleRequest(client *PairedClient, request *ipcproto.Request) *ipcproto.Response {
+	if request.Version != ipcproto.Version {
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeUnsupportedVersion,
+			fmt.Sprintf("Unsupported protocol version %d", request.Version)))
+	}
+
+	switch request.Action {
+	case ipcproto.ActionSearch:
+		if request.Search == nil {
+			return ipcFailure(ipcproto.NewError(ipcproto.CodeBadRequest, "Missing search parameters"))
+		}
+		return s.handleSearch(request.Search.URL)
+
+	case ipcproto.ActionFill:
+		if request.Fill == nil {
+			return ipcFailure(ipcproto.NewError(ipcproto.CodeBadRequest, "Missing fill parameters"))
+		}
+		return s.handleFill(client, request.Fill)
+
+	case ipcproto.ActionSave:
+		if request.Save == nil {
+			return ipcFailure(ipcproto.NewError(ipcproto.CodeBadRequest, "Missing credential"))
+		}
+		return s.handleSave(request.Save)
+
+	case ipcproto.ActionGetCreditCards:
+		if request.CreditCards == nil {
+			return ipcFailure(ipcproto.NewError(ipcproto.CodeBadRequest, "Missing page URL"))
+		}
+		return s.handleGetCreditCards(client, request.CreditCards.URL)
+
+	case ipcproto.ActionOneTimeCode:
+		if request.OneTimeCode == nil {
+			return ipcFailure(ipcproto.NewError(ipcproto.CodeBadRequest, "Missing one-time code parameters"))
+		}
+		return s.handleOneTimeCode(client, request.OneTimeCode)
+
+	case ipcproto.ActionGetIdentities:
+		if request.Identities == nil {
+			return ipcFailure(ipcproto.NewError(ipcproto.CodeBadRequest, "Missing page URL"))
+		}
+		return s.handleGetIdentities(client, request.Identities.URL)
+
+	default:
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeUnknownAction, "Unknown action: "+request.Action))
+	}
+}
+
+// handleSearch lists the credentials matching a URL, without their passwords
+func (s *IPCServer) handleSearch(url string) *ipcproto.Response {
+	credentials, err := s.app.vault.Credentials()
+	if err != nil {
+		return ipcVaultLocked()
+	}
+
+	var matching []ipcproto.Credential
+	for _, cred := range credentials {
+		if matchesPage(cred, url) {
+			matching = append(matching, ipcCredential(cred))
+		}
+	}
+
+	return &ipcproto.Response{
+		Success:     true,
+		Credentials: matching,
+	}
+}
+
+// handleFill returns the password of one credential for the page it is
+// filled into, once the user allowed the page to have it
+func (s *IPCServer) handleFill(client *PairedClient, fill *ipcproto.FillRequest) *ipcproto.Response {
+	if !s.app.vault.IsUnlocked() {
+		return ipcVaultLocked()
+	}
+	if _, ok := s.fillableCredential(fill); !ok {
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeNotFound, "No such credential for this site"))
+	}
+	if err := s.authorize(client, fill.URL, AccessCredentials); err != nil {
+		return ipcFailure(err)
+	}
+
+	// The vault may have changed while the user was deciding
+	cred, ok := s.fillableCredential(fill)
+	if !ok {
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeNotFound, "No such credential for this site"))
+	}
+
+	return &ipcproto.Response{
+		Success: true,
+		Fill: &ipcproto.FillResponse{
+			ID:       cred.ID,
+			Username: cred.Username,
+			Password: cred.Password,
+		},
+	}
+}
+
+// fillableCredential finds the credential a fill request names, provided it
+// matches the page; a page cannot ask for another site's password
+func (s *IPCServer) fillableCredential(fill *ipcproto.FillRequest) (vault.Credential, bool) {
+	cred, err := s.app.vault.Credential(fill.ID)
+	if err != nil {
+		return vault.Credential{}, false
+	}
+	return cred, matchesPage(cred, fill.URL)
+}
+
+// handleOneTimeCode returns the current one-time password of a credential
+// for the page it is filled into. It is authorized like the password.
+func (s *IPCServer) handleOneTimeCode(client *PairedClient, request *ipcproto.OneTimeCodeRequest) *ipcproto.Response {
+	if !s.app.vault.IsUnlocked() {
+		return ipcVaultLocked()
+	}
+	fill := &ipcproto.FillRequest{ID: request.ID, URL: request.URL}
+	if cred, ok := s.fillableCredential(fill); !ok || cred.OTPAuth == "" {
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeNotFound, "No one-time password for this site"))
+	}
+	if err := s.authorize(client, request.URL, AccessCredentials); err != nil {
+		return ipcFailure(err)
+	}
+
+	// The vault may have changed while the user was deciding
+	if _, ok := s.fillableCredential(fill); !ok {
+		return ipcFailure(ipcproto.NewError(ipcproto.CodeNotFound, "No one-time password for this site"))
+	}
+	code, err := s.app.GetOneTimeCode(request.ID)
+	if err != nil {
+		if errors.Is(err, vault.ErrNoOTP) {
+			return ipcFailure(ipcproto.NewError(ipcproto.CodeNotFound, "No one-time password for this site"))
+		}
+		return ipcFailure(err)
+	}
+
+	return &ipcproto.Response{
+		Success: true,
+		OneTimeCode: &ipcproto.OneTimeCodeResponse{
+			ID:               request.ID,
+			Code:             code.Code,
+			SecondsRemaining: code.SecondsRemaining,
+		},
+	}
+}
+
+// handleSave saves a new credential
+func (s *IPCServer) handleSave(save *ipcproto.SaveRequest) *ipcproto.Response {
+	if !s.app.vault.IsUnlocked() {
+		return ipcVaultLocked()
+	}
+
+	category := save.Category
+	if category == "" {
+		category = "Other"
+	}
+
+	_, err := s.app.AddCredential(save.ServiceName, save.URL, save.Username, save.Password, category)
+	if err != nil {
+		return ipcFailure(err)
+	}
+
+	return &ipcproto.Response{
+		Success: true,
+	}
+}
+
+// handleGetCreditCards returns all credit cards after the user confirmed
+// the request
+func (s *IPCServer) handleGetCreditCards(client *PairedClient, url string) *ipcproto.Response {
+	if !s.app.vault.IsUnlocked() {
+		return ipcVaultLocked()
+	}
+	if err := s.authorize(client, url, AccessCreditCards); err != nil {
+		return ipcFailure(err)
+	}
+
+	creditCards, err := s.app.vault.CreditCards()
+	if err != nil {
+		return ipcVaultLocked()
+	}
+
+	cards := make([]ipcproto.CreditCard, 0, len(creditCards))
+	for _, card := range creditCards {
+		cards = append(cards, ipcCreditCard(card))
+	}
+
+	return &ipcproto.Response{
+		Success:     true,
+		CreditCards: cards,
+	}
+}
+
+// handleGetIdentities returns all identities for filling address and
+// personal details forms after the user confirmed the request
+func (s *IPCServer) handleGetIdentities(client *PairedClient, url string) *ipcproto.Response {
+	if !s.app.vault.IsUnlocked() {
+		return ipcVaultLocked()
+	}
+	if err := s.authorize(client, url, AccessIdentities); err != nil {
+		return ipcFailure(err)
+	}
+
+	stored, err := s.app.vault.Identities()
+	if err != nil {
+		return ipcVaultLocked()
+	}
+
+	identities := make([]ipcproto.Identity, 0, len(stored))
+	for _, identity := range stored {
+		identities = append(identities, ipcIdentity(identity))
+	}
+
+	return &ipcproto.Response{
+		Success:    true,
+		Identities: identities,
+	}
+}
+
+// authorize waits for the user to approve access to secrets for a page.
+// The vault may have been locked while the prompt was open.
+func (s *IPCServer) authorize(client *PairedClient, url, access string) error {
+	if s.app.approvals == nil {
+		return ipcproto.NewError(ipcproto.CodeDenied, "Approval is unavailable")
+	}
+	if err := s.app.approvals.authorize(url, access, client.Name); err != nil {
+		return err
+	}
+	if !s.app.vault.IsUnlocked() {
+		return ipcproto.NewError(ipcproto.CodeVaultLocked, "Vault is locked")
+	}
+	return nil
+}
+
+// ipcFailure turns an error into a failed response, keeping protocol error codes
+func ipcFailure(err error) *ipcproto.Response {
+	var protoErr *ipcproto.Error
+	if !errors.As(err, &protoErr) {
+		protoErr = ipcproto.NewError(ipcproto.CodeFailed, err.Error())
+	}
+	return &ipcproto.Response{
+		Success: false,
+		Error:   protoErr,
+	}
+}
+
+// ipcVaultLocked is the response to requests that need the unlocked vault
+func ipcVaultLocked() *ipcproto.Response {
+	return ipcFailure(ipcproto.NewError(ipcproto.CodeVaultLocked, "Vault is locked"))
+}
+
+// ipcCredential converts a credential to its wire form, leaving out the
+// password and one-time password
+func ipcCredential(cred vault.Credential) ipcproto.Credential {
+	return ipcproto.Credential{
+		ID:             cred.ID,
+		ServiceName:    cred.ServiceName,
+		Username:       cred.Username,
+		HasOneTimeCode: cred.OTPAuth != "",
+	}
+}
+
+// ipcCreditCard converts a credit card to its wire form
+func ipcCreditCard(card vault.CreditCard) ipcproto.CreditCard {
+	return ipcproto.CreditCard{
+		ID:             card.ID,
+		CardName:       card.CardName,
+		CardholderName: card.CardholderName,
+		CardNumber:     card.CardNumber,
+		ExpiryMonth:    card.ExpiryMonth,
+		ExpiryYear:     card.ExpiryYear,
+		CVV:            card.CVV,
+		CardType:       card.CardType,
+		BillingZip:     card.BillingZip,
+	}
+}
+
+// ipcIdentity converts an identity to its wire form
+func ipcIdentity(identity vault.Identity) ipcproto.Identity {
+	return ipcproto.Identity{
+		ID:          identity.ID,
+		Name:        identity.Name,
+		FirstName:   identity.FirstName,
+		MiddleName:  identity.MiddleName,
+		LastName:    identity.LastName,
+		Email:       identity.Email,
+		Phone:       identity.Phone,
+		DateOfBirth: identity.DateOfBirth,
+		Address: ipcproto.Address{
+			Street1:    identity.Address.Street1,
+			Street2:    identity.Address.Street2,
+			City:       identity.Address.City,
+			Region:     identity.Address.Region,
+			PostalCode: identity.Address.PostalCode,
+			Country:    identity.Address.Country,
+		},
+		PassportNumber: identity.PassportNumber,
+		IDNumber:       identity.IDNumber,
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"vaultzero/ipcproto"
+)
+
+// serveTestConnection serves one in-memory connection and returns the
+// client end and a channel closed when serving stops
+func serveTestConnection(t *testing.T, s *IPCServer) (*ipcproto.Conn, net.Conn, chan struct{}) {
+	t.Helper()
+
+	server, client := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer server.Close()
+		s.serveConnection(server)
+	}()
+	t.Cleanup(func() {
+		client.Close()
+		<-done
+	})
+
+	client.SetDeadline(time.Now().Add(10 * time.Second))
+	return ipcproto.NewConn(client), client, done
+}
+
+func TestServeConnection(t *testing.T) {
+	conn, client, done := serveTestConnection(t, NewIPCServer(&App{}))
+	hello := ipcproto.Request{
+		Action: ipcproto.ActionHello,
+		Hello:  &ipcproto.HelloRequest{Client: "test", Versions: []int{ipcproto.Version}},
+	}
+
+	// Several requests are answered in order on one connection
+	for i := 0; i < 2; i++ {
+		if err := conn.Send(hello); err != nil {
+			t.Fatal(err)
+		}
+		var response ipcproto.Response
+		if err := conn.Receive(&response); err != nil {
+			t.Fatal(err)
+		}
+		if !response.Success || response.Hello.Version != ipcproto.Version || response.Hello.Paired {
+			t.Fatalf("hello %d: %+v", i, response)
+		}
+	}
+
+	// A frame that is not JSON is answered and the connection stays usable
+	if err := ipcproto.WriteFrame(client, []byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+	var response ipcproto.Response
+	if err := conn.Receive(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Success || response.Error.Code != ipcproto.CodeBadRequest {
+		t.Fatalf("invalid message: %+v", response)
+	}
+	if err := conn.Send(hello); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Receive(&response); err != nil || !response.Success {
+		t.Fatalf("hello after an invalid message: %+v, %v", response, err)
+	}
+
+	// An oversized frame ends the connection without reading its payload
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], ipcproto.MaxFrameSize+1)
+	if _, err := client.Write(header[:]); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection still served after an oversized frame")
+	}
+}
+
+func TestServeConnectionRejectsUnsealedRequests(t *testing.T) {
+	conn, _, _ := serveTestConnection(t, NewIPCServer(&App{pairing: &pairingManager{}}))
+
+	tests := []struct {
+		request ipcproto.Request
+		code    ipcproto.ErrorCode
+	}{
+		{ipcproto.Request{Version: ipcproto.Version, Action: ipcproto.ActionSearch, Search: &ipcproto.SearchRequest{URL: "https://github.com"}}, ipcproto.CodeNotPaired},
+		{ipcproto.Request{Version: ipcproto.Version, Action: ipcproto.ActionFill, Fill: &ipcproto.FillRequest{ID: "1", URL: "https://github.com"}}, ipcproto.CodeNotPaired},
+		{ipcproto.Request{Version: ipcproto.Version - 1, Action: ipcproto.ActionSearch}, ipcproto.CodeUnsupportedVersion},
+		{ipcproto.Request{Action: ipcproto.ActionHello, Hello: &ipcproto.HelloRequest{Versions: []int{ipcproto.Version + 1}}}, ipcproto.CodeUnsupportedVersion},
+		{ipcproto.Request{Action: ipcproto.ActionHello}, ipcproto.CodeBadRequest},
+	}
+	for _, tt := range tests {
+		if err := conn.Send(tt.request); err != nil {
+			t.Fatal(err)
+		}
+		var response ipcproto.Response
+		if err := conn.Receive(&response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Success || response.Error == nil || response.Error.Code != tt.code {
+			t.Errorf("%s request: got %+v, want %s", tt.request.Action, response, tt.code)
+		}
+		if response.Version != ipcproto.Version {
+			t.Errorf("%s response has version %d", tt.request.Action, response.Version)
+		}
+	}
+}
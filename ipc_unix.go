@@ -0,0 +1,160 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const ipcSocketName = "vaultzero.sock"
+
+// IPCServer handles inter-process communication via a Unix domain socket
+type IPCServer struct {
+	app      *App
+	listener *net.UnixListener
+	mu       sync.Mutex
+	running  bool
+}
+
+// NewIPCServer creates a new IPC server
+func NewIPCServer(app *App) *IPCServer {
+	return &IPCServer{
+		app: app,
+	}
+}
+
+// IPCSocketPath returns the socket the native host connects to. It lives in
+// $XDG_RUNTIME_DIR, or in a private per-user directory under the temp dir
+// where that is not set (as on macOS).
+func IPCSocketPath() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("vaultzero-%d", os.Getuid()))
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+
+		// Someone else could have created the directory first in a shared temp dir
+		info, err := os.Lstat(dir)
+		if err != nil {
+			return "", err
+		}
+		if !info.IsDir() || info.Mode().Perm() != 0700 || !ownedByCurrentUser(info) {
+			return "", errors.New("insecure IPC directory " + dir)
+		}
+	}
+
+	return filepath.Join(dir, ipcSocketName), nil
+}
+
+// ownedByCurrentUser reports whether a file belongs to the user running the app
+func ownedByCurrentUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
+
+// Start starts the IPC server (Unix domain socket)
+func (s *IPCServer) Start() error {
+	path, err := IPCSocketPath()
+	if err != nil {
+		return err
+	}
+
+	// Replace a stale socket left behind by a crash, but not a live one
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return errors.New("another VaultZero instance is already serving the browser extension")
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return err
+	}
+	listener.SetUnlinkOnClose(true)
+
+	s.mu.Lock()
+	s.listener = listener
+	s.running = true
+	s.mu.Unlock()
+
+	// Accept connections in background
+	go s.acceptConnections(listener)
+
+	return nil
+}
+
+// Stop stops the IPC server and removes the socket
+func (s *IPCServer) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running = false
+	if s.listener == nil {
+		return nil
+	}
+
+	err := s.listener.Close()
+	s.listener = nil
+	return err
+}
+
+// acceptConnections accepts and handles incoming connections
+func (s *IPCServer) acceptConnections(listener *net.UnixListener) {
+	for {
+		conn, err := listener.AcceptUnix()
+		if err != nil {
+			s.mu.Lock()
+			running := s.running
+			s.mu.Unlock()
+			if running {
+				println("IPC accept failed:", err.Error())
+			}
+			return
+		}
+
+		// Handle the connection in a goroutine so we can accept more clients
+		go s.handleConnection(conn)
+	}
+}
+
+// handleConnection serves a single client after checking that it runs as
+// the same user as the app
+func (s *IPCServer) handleConnection(conn *net.UnixConn) {
+	defer conn.Close()
+
+	uid, err := peerUID(conn)
+	if err != nil {
+		println("Warning: Rejecting IPC client with unknown credentials:", err.Error())
+		return
+	}
+	if uid != os.Getuid() {
+		println("Warning: Rejecting IPC client running as UID", uid)
+		return
+	}
+
+	s.serveConnection(idleConn{conn})
+}
+
+// idleConn drops clients that send nothing for ipcIdleTimeout
+type idleConn struct {
+	*net.UnixConn
+}
+
+func (c idleConn) Read(p []byte) (int, error) {
+	c.SetReadDeadline(time.Now().Add(ipcIdleTimeout))
+	return c.UnixConn.Read(p)
+}
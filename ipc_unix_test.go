@@ -0,0 +1,111 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"vaultzero/ipcproto"
+)
+
+func TestIPCSocketPath(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	if path, err := IPCSocketPath(); err != nil || path != filepath.Join(runtimeDir, ipcSocketName) {
+		t.Errorf("with XDG_RUNTIME_DIR: %q, %v", path, err)
+	}
+
+	// Without it the socket goes in a private directory under the temp dir
+	tempDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("TMPDIR", tempDir)
+	private := filepath.Join(tempDir, fmt.Sprintf("vaultzero-%d", os.Getuid()))
+	path, err := IPCSocketPath()
+	if err != nil || path != filepath.Join(private, ipcSocketName) {
+		t.Fatalf("without XDG_RUNTIME_DIR: %q, %v", path, err)
+	}
+	if info, err := os.Stat(private); err != nil || info.Mode().Perm() != 0700 {
+		t.Fatalf("private directory: %v, %v", info.Mode(), err)
+	}
+
+	// A directory others can write to is refused
+	if err := os.Chmod(private, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := IPCSocketPath(); err == nil {
+		t.Error("accepted a world-writable IPC directory")
+	}
+}
+
+func TestIPCServerStartStop(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	path, err := IPCSocketPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A socket left behind by a crashed instance is replaced
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	s := NewIPCServer(&App{})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode %v, want 0600", info.Mode().Perm())
+	}
+
+	// A live socket is not taken over by a second instance
+	if err := NewIPCServer(&App{}).Start(); err == nil {
+		t.Fatal("second server started on a live socket")
+	}
+
+	// Clients running as the same user are served
+	client, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(10 * time.Second))
+	conn := ipcproto.NewConn(client)
+	err = conn.Send(ipcproto.Request{
+		Action: ipcproto.ActionHello,
+		Hello:  &ipcproto.HelloRequest{Client: "test", Versions: []int{ipcproto.Version}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response ipcproto.Response
+	if err := conn.Receive(&response); err != nil {
+		t.Fatal(err)
+	}
+	if !response.Success || response.Hello.App != ipcAppName {
+		t.Fatalf("hello over the socket: %+v", response)
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket still exists after Stop: %v", err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+// Package ipcproto is the wire protocol between the VaultZero app and its
+// browser native messaging host.
+//
+// Messages are JSON documents, each sent as one frame: a 4-byte big-endian
+// length followed by that many bytes. A connection carries any number of
+// request/response exchanges in order.
+package ipcproto
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MaxFrameSize bounds a single message so a bad length cannot exhaust memory
+const MaxFrameSize = 16 << 20
+
+var (
+	// ErrFrameTooLarge is returned for frames over MaxFrameSize
+	ErrFrameTooLarge = errors.New("ipc frame exceeds maximum size")
+
+	// ErrInvalidMessage wraps frames that are not valid JSON messages. The
+	// frame has been consumed, so the connection can still be used.
+	ErrInvalidMessage = errors.New("invalid ipc message")
+)
+
+// WriteFrame writes one length-prefixed message
+func WriteFrame(w io.Writer, payload []byte) error {
+	if len(payload) > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadFrame reads one length-prefixed message. It returns io.EOF only if
+// the stream ended cleanly between frames.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	if length > MaxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Conn exchanges JSON messages as frames over a stream
+type Conn struct {
+	reader  *bufio.Reader
+	writer  io.Writer
+	writeMu sync.Mutex
+}
+
+// NewConn wraps a stream such as a socket or pipe
+func NewConn(rw io.ReadWriter) *Conn {
+	return &Conn{
+		reader: bufio.NewReader(rw),
+		writer: rw,
+	}
+}
+
+// Send encodes v and writes it as one frame
+func (c *Conn) Send(v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return WriteFrame(c.writer, payload)
+}
+
+// Receive reads one frame and decodes it into v
+func (c *Conn) Receive(v interface{}) error {
+	payload, err := ReadFrame(c.reader)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return nil
+}
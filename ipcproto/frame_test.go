@@ -0,0 +1,118 @@
+package ipcproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// frame returns a length prefix announcing length bytes followed by payload
+func frame(length uint32, payload string) []byte {
+	data := make([]byte, 4, 4+len(payload))
+	binary.BigEndian.PutUint32(data, length)
+	return append(data, payload...)
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	var stream bytes.Buffer
+	for _, payload := range []string{`{"action":"hello"}`, "", `{"action":"sealed"}`} {
+		if err := WriteFrame(&stream, []byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []string{`{"action":"hello"}`, "", `{"action":"sealed"}`} {
+		got, err := ReadFrame(&stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("read %q, want %q", got, want)
+		}
+	}
+	if _, err := ReadFrame(&stream); err != io.EOF {
+		t.Errorf("end of stream: got %v, want io.EOF", err)
+	}
+}
+
+func TestFrameTooLarge(t *testing.T) {
+	var stream bytes.Buffer
+	if err := WriteFrame(&stream, make([]byte, MaxFrameSize+1)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("write: got %v, want ErrFrameTooLarge", err)
+	}
+	if stream.Len() != 0 {
+		t.Errorf("wrote %d bytes of an oversized frame", stream.Len())
+	}
+
+	// The length is checked before anything is allocated or read
+	for _, length := range []uint32{MaxFrameSize + 1, 0xFFFFFFFF} {
+		if _, err := ReadFrame(bytes.NewReader(frame(length, "{}"))); !errors.Is(err, ErrFrameTooLarge) {
+			t.Errorf("read of length %d: got %v, want ErrFrameTooLarge", length, err)
+		}
+	}
+
+	if err := WriteFrame(&stream, make([]byte, MaxFrameSize)); err != nil {
+		t.Fatalf("write of the largest frame: %v", err)
+	}
+	if payload, err := ReadFrame(&stream); err != nil || len(payload) != MaxFrameSize {
+		t.Errorf("read of the largest frame: %d bytes, %v", len(payload), err)
+	}
+}
+
+func TestTruncatedFrame(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"partial length", []byte{0, 0}},
+		{"missing payload", frame(10, "")},
+		{"partial payload", frame(10, `{"a":`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadFrame(bytes.NewReader(tt.data)); err != io.ErrUnexpectedEOF {
+				t.Errorf("got %v, want io.ErrUnexpectedEOF", err)
+			}
+		})
+	}
+}
+
+func TestConnSkipsInvalidMessage(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(frame(9, "not json!"))
+	stream.Write(frame(17, `{"action":"pair"}`))
+	conn := NewConn(&stream)
+
+	var request Request
+	if err := conn.Receive(&request); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("got %v, want ErrInvalidMessage", err)
+	}
+
+	// The bad frame was consumed, so the next message is read as usual
+	if err := conn.Receive(&request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Action != ActionPair {
+		t.Errorf("read action %q after the invalid message", request.Action)
+	}
+}
+
+func TestConnSendReceive(t *testing.T) {
+	var stream bytes.Buffer
+	conn := NewConn(&stream)
+
+	sent := Response{Version: Version, Success: false, Error: NewError(CodeDenied, "no")}
+	if err := conn.Send(sent); err != nil {
+		t.Fatal(err)
+	}
+
+	var received Response
+	if err := conn.Receive(&received); err != nil {
+		t.Fatal(err)
+	}
+	if received.Version != Version || received.Error == nil || received.Error.Code != CodeDenied {
+		t.Errorf("received %+v", received)
+	}
+}
@@ -0,0 +1,5 @@
+module vaultzero/ipcproto
+
+go 1.21
+
+require golang.org/x/crypto v0.33.0
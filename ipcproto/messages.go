@@ -0,0 +1,228 @@
+package ipcproto
+
+// Version is the protocol version spoken by this package. Clients announce
+// the versions they speak in their hello; every other request carries the
+// version it was written for and is rejected if the peer does not speak it.
+//
+// Version 2 stopped sending passwords in search results; they are fetched
+// one at a time with a fill request.
+const Version = 2
+
+// Actions a client can request. Hello, pair and sealed travel in the clear;
+// the others are only accepted sealed inside a SealedRequest payload.
+const (
+	ActionHello          = "hello"
+	ActionPair           = "pair"
+	ActionSealed         = "sealed"
+	ActionSearch         = "search"
+	ActionFill           = "fill"
+	ActionSave           = "save"
+	ActionGetCreditCards = "getCreditCards"
+	ActionOneTimeCode    = "oneTimeCode"
+	ActionGetIdentities  = "getIdentities"
+)
+
+// ErrorCode tells a client why a request failed, independent of the message text
+type ErrorCode string
+
+const (
+	CodeBadRequest         ErrorCode = "bad_request"
+	CodeUnsupportedVersion ErrorCode = "unsupported_version"
+	CodeUnknownAction      ErrorCode = "unknown_action"
+	CodeNotPaired          ErrorCode = "not_paired"
+	CodePairingFailed      ErrorCode = "pairing_failed"
+	CodeAuthFailed         ErrorCode = "auth_failed"
+	CodeVaultLocked        ErrorCode = "vault_locked"
+	CodeNotFound           ErrorCode = "not_found"
+	CodeDenied             ErrorCode = "denied"           // the user refused the request
+	CodeApprovalTimeout    ErrorCode = "approval_timeout" // the user did not answer in time
+	CodeFailed             ErrorCode = "failed"
+)
+
+// Error describes a failed request
+type Error struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// NewError creates an error with a code and a human-readable message
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Request is a message from a client. Exactly one of the action-specific
+// fields is set, matching Action.
+type Request struct {
+	Version int    `json:"version"`
+	Action  string `json:"action"`
+
+	// Nonce and Timestamp make every sealed request unique and short-lived
+	Nonce     string `json:"nonce,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+
+	Hello       *HelloRequest       `json:"hello,omitempty"`
+	Pair        *PairRequest        `json:"pair,omitempty"`
+	Sealed      *SealedMessage      `json:"sealed,omitempty"`
+	Search      *SearchRequest      `json:"search,omitempty"`
+	Fill        *FillRequest        `json:"fill,omitempty"`
+	Save        *SaveRequest        `json:"save,omitempty"`
+	CreditCards *CreditCardsRequest `json:"creditCards,omitempty"`
+	OneTimeCode *OneTimeCodeRequest `json:"oneTimeCode,omitempty"`
+	Identities  *IdentitiesRequest  `json:"identities,omitempty"`
+}
+
+// Response answers a Request. Error is set if and only if Success is false.
+type Response struct {
+	Version int    `json:"version"`
+	Success bool   `json:"success"`
+	Error   *Error `json:"error,omitempty"`
+
+	Hello       *HelloResponse       `json:"hello,omitempty"`
+	Pairing     *PairResponse        `json:"pairing,omitempty"`
+	Sealed      *SealedMessage       `json:"sealed,omitempty"`
+	Credentials []Credential         `json:"credentials,omitempty"`
+	Fill        *FillResponse        `json:"fill,omitempty"`
+	CreditCards []CreditCard         `json:"creditCards,omitempty"`
+	OneTimeCode *OneTimeCodeResponse `json:"oneTimeCode,omitempty"`
+	Identities  []Identity           `json:"identities,omitempty"`
+}
+
+// HelloRequest opens a connection and negotiates the protocol version
+type HelloRequest struct {
+	Client   string `json:"client"`             // name and version of the client
+	Versions []int  `json:"versions"`           // protocol versions the client speaks
+	ClientID string `json:"clientId,omitempty"` // set by clients that believe they are paired
+}
+
+// HelloResponse tells the client what the app supports
+type HelloResponse struct {
+	Version      int      `json:"version"`      // protocol version used on this connection
+	App          string   `json:"app"`          // name and version of the app
+	Capabilities []string `json:"capabilities"` // actions the app accepts
+	Paired       bool     `json:"paired"`       // whether the hello's client ID is paired
+}
+
+// PairRequest proves knowledge of the pairing code shown in the app
+type PairRequest struct {
+	ClientID   string `json:"clientId"`
+	ClientName string `json:"clientName"`
+	Nonce      []byte `json:"nonce"`
+	Proof      []byte `json:"proof"` // see PairingProof
+}
+
+// PairResponse carries the new client key, sealed under the pairing key
+type PairResponse struct {
+	Key string `json:"key"`
+}
+
+// SealedMessage is a Request or Response encrypted with a paired client's key
+type SealedMessage struct {
+	ClientID string `json:"clientId"`
+	Payload  string `json:"payload"`
+}
+
+// SearchRequest looks up credentials for a page
+type SearchRequest struct {
+	URL string `json:"url"`
+}
+
+// FillRequest asks for the secret of one credential found by a search, to
+// fill it into the page at URL
+type FillRequest struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// FillResponse carries what is filled into a login form
+type FillResponse struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// OneTimeCodeRequest asks for the current one-time password of a credential
+// found by a search, to fill it into the page at URL
+type OneTimeCodeRequest struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// OneTimeCodeResponse carries a one-time password. SecondsRemaining is zero
+// for counter-based codes, which stay valid until used.
+type OneTimeCodeResponse struct {
+	ID               string `json:"id"`
+	Code             string `json:"code"`
+	SecondsRemaining int    `json:"secondsRemaining"`
+}
+
+// CreditCardsRequest asks for the payment cards to fill on a page
+type CreditCardsRequest struct {
+	URL string `json:"url"`
+}
+
+// IdentitiesRequest asks for the identities to fill into address and
+// personal details forms on a page
+type IdentitiesRequest struct {
+	URL string `json:"url"`
+}
+
+// SaveRequest stores a new credential
+type SaveRequest struct {
+	ServiceName string `json:"serviceName"`
+	URL         string `json:"url"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	Category    string `json:"category,omitempty"`
+}
+
+// Credential describes a login found by a search. It carries no secret;
+// the password is only sent in answer to a fill request, and the one-time
+// password in answer to a one-time code request.
+type Credential struct {
+	ID             string `json:"id"`
+	ServiceName    string `json:"serviceName"`
+	Username       string `json:"username"`
+	HasOneTimeCode bool   `json:"hasOneTimeCode,omitempty"`
+}
+
+// CreditCard is a payment card as sent to the browser
+type CreditCard struct {
+	ID             string `json:"id"`
+	CardName       string `json:"cardName"`
+	CardholderName string `json:"cardholderName"`
+	CardNumber     string `json:"cardNumber"`
+	ExpiryMonth    string `json:"expiryMonth"`
+	ExpiryYear     string `json:"expiryYear"`
+	CVV            string `json:"cvv"`
+	CardType       string `json:"cardType"`
+	BillingZip     string `json:"billingZip"`
+}
+
+// Identity is a person's details and address as sent to the browser
+type Identity struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	FirstName      string  `json:"firstName"`
+	MiddleName     string  `json:"middleName"`
+	LastName       string  `json:"lastName"`
+	Email          string  `json:"email"`
+	Phone          string  `json:"phone"`
+	DateOfBirth    string  `json:"dateOfBirth"`
+	Address        Address `json:"address"`
+	PassportNumber string  `json:"passportNumber"`
+	IDNumber       string  `json:"idNumber"`
+}
+
+// Address is a postal address as sent to the browser
+type Address struct {
+	Street1    string `json:"street1"`
+	Street2    string `json:"street2"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postalCode"`
+	Country    string `json:"country"`
+}
@@ -0,0 +1,111 @@
+package ipcproto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// A client pairs once: the app shows a short-lived code, the client proves it
+// knows the code and receives a random per-client key sealed under a key
+// derived from it. Every later request is sealed with that client key and
+// each response is bound to the nonce of its request.
+
+const (
+	pairingKeyInfo    = "vaultzero pairing v1"
+	pairRequestLabel  = "vaultzero pair request "
+	pairResponseLabel = "vaultzero pair response "
+	requestLabel      = "vaultzero request "
+	responseLabel     = "vaultzero response "
+)
+
+// NormalizePairingCode strips separators and case from a typed code
+func NormalizePairingCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// PairingKey stretches a pairing code into a key, salted with the client's nonce
+func PairingKey(code string, nonce []byte) ([]byte, error) {
+	key := make([]byte, 32)
+	reader := hkdf.New(sha256.New, []byte(NormalizePairingCode(code)), nonce, []byte(pairingKeyInfo))
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// PairingProof shows knowledge of the pairing key for a client ID
+func PairingProof(pairingKey []byte, clientID string) []byte {
+	mac := hmac.New(sha256.New, pairingKey)
+	mac.Write([]byte(pairRequestLabel + clientID))
+	return mac.Sum(nil)
+}
+
+// PairResponseAAD binds the sealed client key to the client it was issued to
+func PairResponseAAD(clientID string) []byte {
+	return []byte(pairResponseLabel + clientID)
+}
+
+// RequestAAD binds a sealed request to its sender
+func RequestAAD(clientID string) []byte {
+	return []byte(requestLabel + clientID)
+}
+
+// ResponseAAD binds a sealed response to the request it answers
+func ResponseAAD(clientID, nonce string) []byte {
+	return []byte(responseLabel + clientID + " " + nonce)
+}
+
+// Seal encrypts with AES-256-GCM, returning base64 of nonce || ciphertext
+func Seal(plaintext, key, aad []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, plaintext, aad)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Open decrypts and authenticates data produced by Seal
+func Open(sealed string, key, aad []byte) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+// newGCM creates an AES-GCM cipher for a 32-byte key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
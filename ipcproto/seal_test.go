@@ -0,0 +1,145 @@
+package ipcproto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	key := testKey(t)
+	plaintext := []byte(`{"action":"search","nonce":"n1"}`)
+
+	sealed, err := Seal(plaintext, key, RequestAAD("client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := Open(sealed, key, RequestAAD("client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("opened %q, want %q", opened, plaintext)
+	}
+
+	// Every seal uses a fresh nonce
+	again, err := Seal(plaintext, key, RequestAAD("client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == sealed {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	key := testKey(t)
+	sealed, err := Seal([]byte("secret"), key, RequestAAD("client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(sealed)
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name   string
+		sealed string
+		key    []byte
+		aad    []byte
+	}{
+		{"wrong key", sealed, testKey(t), RequestAAD("client")},
+		{"other client", sealed, key, RequestAAD("other")},
+		{"response label", sealed, key, ResponseAAD("client", "")},
+		{"no associated data", sealed, key, nil},
+		{"tampered ciphertext", base64.StdEncoding.EncodeToString(tampered), key, RequestAAD("client")},
+		{"truncated", base64.StdEncoding.EncodeToString(raw[:8]), key, RequestAAD("client")},
+		{"not base64", "not base64!", key, RequestAAD("client")},
+		{"short key", sealed, key[:7], RequestAAD("client")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(tt.sealed, tt.key, tt.aad); err == nil {
+				t.Error("opened")
+			}
+		})
+	}
+}
+
+func TestResponseBoundToRequest(t *testing.T) {
+	key := testKey(t)
+	sealed, err := Seal([]byte(`{"success":true}`), key, ResponseAAD("client", "nonce-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(sealed, key, ResponseAAD("client", "nonce-1")); err != nil {
+		t.Fatalf("response did not open for its request: %v", err)
+	}
+	if _, err := Open(sealed, key, ResponseAAD("client", "nonce-2")); err == nil {
+		t.Error("response opened as the answer to another request")
+	}
+	if _, err := Open(sealed, key, ResponseAAD("other", "nonce-1")); err == nil {
+		t.Error("response opened for another client")
+	}
+	if _, err := Open(sealed, key, RequestAAD("client")); err == nil {
+		t.Error("response opened as a request")
+	}
+}
+
+func TestPairingHandshake(t *testing.T) {
+	nonce := []byte("0123456789abcdef")
+
+	// Typed codes may differ in case and separators
+	appKey, err := PairingKey("ABCD-EFGH", nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey, err := PairingKey("abcd efgh", nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(appKey, clientKey) {
+		t.Fatal("normalized codes gave different keys")
+	}
+	if len(appKey) != 32 {
+		t.Errorf("pairing key has %d bytes, want 32", len(appKey))
+	}
+
+	if other, _ := PairingKey("ABCD-EFGJ", nonce); bytes.Equal(other, appKey) {
+		t.Error("another code gave the same key")
+	}
+	if other, _ := PairingKey("ABCD-EFGH", []byte("fedcba9876543210")); bytes.Equal(other, appKey) {
+		t.Error("another nonce gave the same key")
+	}
+
+	proof := PairingProof(appKey, "client")
+	if !bytes.Equal(proof, PairingProof(clientKey, "client")) {
+		t.Error("proofs of the same key differ")
+	}
+	if bytes.Equal(proof, PairingProof(appKey, "other")) {
+		t.Error("proof does not depend on the client ID")
+	}
+
+	// The client key sealed for one client cannot be taken by another
+	sealed, err := Seal([]byte("client key"), appKey, PairResponseAAD("client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(sealed, clientKey, PairResponseAAD("client")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(sealed, clientKey, PairResponseAAD("other")); err == nil {
+		t.Error("pairing response opened for another client")
+	}
+}
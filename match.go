@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/net/publicsuffix"
+
+	"vaultzero/vault"
+)
+
+// How a credential's URL is compared with the page asking for it. Sites are
+// compared by registrable domain (eTLD+1 from the public suffix list), so
+// "accounts.google.com" and "mail.google.com" share "google.com" while
+// "google.com.attacker.io" does not.
+const (
+	MatchBaseDomain = "base"       // any host under the same registrable domain (the default)
+	MatchExact      = "exact"      // the same host, and port if the credential names one
+	MatchEquivalent = "equivalent" // the base domain or one of the credential's equivalent domains
+)
+
+// siteURL is the part of a URL that matching looks at
+type siteURL struct {
+	scheme string
+	host   string // lower case, without port
+	port   string // empty unless given explicitly
+}
+
+// parseSiteURL reads a credential or page URL; bare hosts are taken as https
+func parseSiteURL(rawURL string) (siteURL, bool) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return siteURL{}, false
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return siteURL{}, false
+	}
+
+	return siteURL{
+		scheme: strings.ToLower(parsed.Scheme),
+		host:   strings.TrimSuffix(strings.ToLower(parsed.Hostname()), "."),
+		port:   parsed.Port(),
+	}, true
+}
+
+// registrableDomain returns the host's eTLD+1. Hosts without one, such as IP
+// addresses, "localhost" or a bare public suffix, only match themselves.
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// matchesPage reports whether a credential may be offered on the page at
+// pageURL. An https credential is never offered on a plain http page.
+func matchesPage(cred vault.Credential, pageURL string) bool {
+	page, ok := parseSiteURL(pageURL)
+	if !ok {
+		return false
+	}
+	site, ok := parseSiteURL(cred.URL)
+	if !ok {
+		return false
+	}
+
+	if site.scheme == "https" && page.scheme != "https" {
+		return false
+	}
+
+	switch cred.MatchMode {
+	case MatchExact:
+		if site.port != "" && site.port != page.port {
+			return false
+		}
+		return site.host == page.host
+
+	case MatchEquivalent:
+		pageDomain := registrableDomain(page.host)
+		if registrableDomain(site.host) == pageDomain {
+			return true
+		}
+		for _, equivalent := range cred.EquivalentDomains {
+			if other, ok := parseSiteURL(equivalent); ok && registrableDomain(other.host) == pageDomain {
+				return true
+			}
+		}
+		return false
+
+	default:
+		return registrableDomain(site.host) == registrableDomain(page.host)
+	}
+}
+
+// SetCredentialMatching chooses how a credential is matched against pages in
+// the browser. Equivalent domains are only used with MatchEquivalent.
+func (a *App) SetCredentialMatching(id, mode string, equivalentDomains []string) error {
+	switch mode {
+	case "", MatchBaseDomain, MatchExact:
+		equivalentDomains = nil
+	case MatchEquivalent:
+		for _, domain := range equivalentDomains {
+			if _, ok := parseSiteURL(domain); !ok {
+				return errors.New("invalid equivalent domain: " + domain)
+			}
+		}
+	default:
+		return errors.New("unknown match mode: " + mode)
+	}
+
+	err := a.vault.UpdateCredential(id, func(cred *vault.Credential) error {
+		cred.MatchMode = mode
+		cred.EquivalentDomains = equivalentDomains
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "credentials-updated")
+	return nil
+}
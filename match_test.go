@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"vaultzero/vault"
+)
+
+func TestMatchesPage(t *testing.T) {
+	tests := []struct {
+		name    string
+		cred    vault.Credential
+		pageURL string
+		want    bool
+	}{
+		{"same host", vault.Credential{URL: "https://google.com"}, "https://google.com/login", true},
+		{"subdomain", vault.Credential{URL: "https://accounts.google.com"}, "https://mail.google.com", true},
+		{"bare credential host", vault.Credential{URL: "github.com"}, "https://github.com/session", true},
+		{"host case and trailing dot", vault.Credential{URL: "https://GitHub.com."}, "https://github.com", true},
+
+		// Suffix attacks
+		{"domain as subdomain of attacker", vault.Credential{URL: "https://google.com"}, "https://google.com.attacker.io", false},
+		{"domain as suffix of attacker", vault.Credential{URL: "https://google.com"}, "https://evilgoogle.com", false},
+		{"domain in attacker path", vault.Credential{URL: "https://google.com"}, "https://attacker.io/google.com", false},
+		{"domain in attacker userinfo", vault.Credential{URL: "https://google.com"}, "https://google.com@attacker.io", false},
+
+		// Scheme downgrade
+		{"https credential on http page", vault.Credential{URL: "https://bank.com"}, "http://bank.com", false},
+		{"http credential on https page", vault.Credential{URL: "http://bank.com"}, "https://bank.com", true},
+		{"http credential on http page", vault.Credential{URL: "http://bank.com"}, "http://bank.com", true},
+		{"https exact credential on http page", vault.Credential{URL: "https://bank.com", MatchMode: MatchExact}, "http://bank.com", false},
+
+		// Exact mode
+		{"exact same host", vault.Credential{URL: "https://login.example.com", MatchMode: MatchExact}, "https://login.example.com/x", true},
+		{"exact other subdomain", vault.Credential{URL: "https://login.example.com", MatchMode: MatchExact}, "https://www.example.com", false},
+		{"exact parent domain", vault.Credential{URL: "https://login.example.com", MatchMode: MatchExact}, "https://example.com", false},
+		{"exact same port", vault.Credential{URL: "https://example.com:8443", MatchMode: MatchExact}, "https://example.com:8443/", true},
+		{"exact other port", vault.Credential{URL: "https://example.com:8443", MatchMode: MatchExact}, "https://example.com:9443/", false},
+		{"exact port missing on page", vault.Credential{URL: "https://example.com:8443", MatchMode: MatchExact}, "https://example.com/", false},
+		{"exact any port without one", vault.Credential{URL: "https://example.com", MatchMode: MatchExact}, "https://example.com:8443/", true},
+
+		// Equivalent domains
+		{"equivalent listed domain", vault.Credential{URL: "https://amazon.com", MatchMode: MatchEquivalent, EquivalentDomains: []string{"amazon.co.uk", "https://amazon.de"}}, "https://www.amazon.co.uk", true},
+		{"equivalent listed URL", vault.Credential{URL: "https://amazon.com", MatchMode: MatchEquivalent, EquivalentDomains: []string{"amazon.co.uk", "https://amazon.de"}}, "https://amazon.de/signin", true},
+		{"equivalent own domain", vault.Credential{URL: "https://amazon.com", MatchMode: MatchEquivalent, EquivalentDomains: []string{"amazon.co.uk"}}, "https://smile.amazon.com", true},
+		{"equivalent unlisted domain", vault.Credential{URL: "https://amazon.com", MatchMode: MatchEquivalent, EquivalentDomains: []string{"amazon.co.uk"}}, "https://amazon.fr", false},
+		{"equivalent suffix attack", vault.Credential{URL: "https://amazon.com", MatchMode: MatchEquivalent, EquivalentDomains: []string{"amazon.co.uk"}}, "https://amazon.co.uk.attacker.io", false},
+		{"equivalent ignored in base mode", vault.Credential{URL: "https://amazon.com", EquivalentDomains: []string{"amazon.co.uk"}}, "https://amazon.co.uk", false},
+
+		// IP addresses and localhost only match themselves
+		{"same IP", vault.Credential{URL: "http://192.168.1.1"}, "http://192.168.1.1/admin", true},
+		{"other IP in subnet", vault.Credential{URL: "http://192.168.1.1"}, "http://192.168.1.2", false},
+		{"IPv6", vault.Credential{URL: "http://[::1]:8080"}, "http://[::1]:8080/", true},
+		{"localhost", vault.Credential{URL: "http://localhost:3000"}, "http://localhost:5173", true},
+		{"localhost exact port", vault.Credential{URL: "http://localhost:3000", MatchMode: MatchExact}, "http://localhost:5173", false},
+		{"localhost and loopback IP", vault.Credential{URL: "http://localhost"}, "http://127.0.0.1", false},
+
+		// Public suffixes are not registrable domains
+		{"same co.uk domain", vault.Credential{URL: "https://www.bbc.co.uk"}, "https://account.bbc.co.uk", true},
+		{"other co.uk domain", vault.Credential{URL: "https://bbc.co.uk"}, "https://itv.co.uk", false},
+		{"bare public suffix", vault.Credential{URL: "https://co.uk"}, "https://bbc.co.uk", false},
+		{"private suffix", vault.Credential{URL: "https://alice.github.io"}, "https://mallory.github.io", false},
+
+		// Unusable URLs
+		{"empty credential URL", vault.Credential{}, "https://example.com", false},
+		{"empty page URL", vault.Credential{URL: "https://example.com"}, "", false},
+		{"page without host", vault.Credential{URL: "https://example.com"}, "about:blank", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesPage(tt.cred, tt.pageURL); got != tt.want {
+				t.Errorf("matchesPage(%q, %q) = %v, want %v", tt.cred.URL, tt.pageURL, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"vaultzero/vault"
+)
+
+// SetCredentialOTP sets up a credential's one-time password from an
+// otpauth:// URI, or from a bare base32 secret as sites show it for manual
+// entry. An empty value removes the one-time password.
+func (a *App) SetCredentialOTP(id, otpAuth string) error {
+	err := a.vault.UpdateCredential(id, func(cred *vault.Credential) error {
+		cred.OTPAuth = otpAuth
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "credentials-updated")
+	return nil
+}
+
+// GetOneTimeCode returns a credential's current one-time password and how
+// many seconds it stays valid
+func (a *App) GetOneTimeCode(id string) (*vault.OneTimeCode, error) {
+	code, err := a.vault.OneTimeCode(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Using an HOTP code moved its counter on
+	if code.Type == vault.OTPTypeHOTP {
+		runtime.EventsEmit(a.ctx, "credentials-updated")
+	}
+
+	return &code, nil
+}
+
+// CopyOneTimeCode copies a credential's current one-time password to the
+// clipboard with auto-clear and returns it with its remaining validity
+func (a *App) CopyOneTimeCode(id string) (*vault.OneTimeCode, error) {
+	code, err := a.GetOneTimeCode(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ClipboardCopy(code.Code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"vaultzero/ipcproto"
+	"vaultzero/vault"
+)
+
+// Browser clients pair once with a short-lived code shown in the app (see
+// ipcproto/seal.go for the handshake). Every later request must be sealed
+// with the client's key, carry a fresh nonce and a current timestamp, and is
+// rejected before dispatch unless it opens with the key of a paired client.
+
+const (
+	pairedClientsFileName = "clients.json"
+	pairingCodeAlphabet   = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	pairingCodeLength     = 8
+	pairingCodeTTL        = 2 * time.Minute
+	pairingMaxAttempts    = 5
+	ipcRequestMaxSkew     = 2 * time.Minute
+)
+
+// PairedClient is a browser client allowed to use the IPC server
+type PairedClient struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	PairedAt time.Time `json:"pairedAt"`
+	LastUsed time.Time `json:"lastUsed,omitempty"`
+	Key      []byte    `json:"key,omitempty"` // never sent to the frontend
+}
+
+// pairingManager holds the paired clients and the pairing code on offer
+type pairingManager struct {
+	mu          sync.Mutex
+	path        string
+	clients     map[string]*PairedClient
+	code        string
+	codeExpires time.Time
+	attempts    int
+	seenNonces  map[string]time.Time
+}
+
+// loadPairingManager reads the paired clients from the app settings directory
+func loadPairingManager() (*pairingManager, error) {
+	vaultDir, err := vault.DefaultVaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	pm := &pairingManager{
+		path:       filepath.Join(vaultDir, pairedClientsFileName),
+		clients:    make(map[string]*PairedClient),
+		seenNonces: make(map[string]time.Time),
+	}
+
+	data, err := os.ReadFile(pm.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pm, nil
+		}
+		return nil, err
+	}
+
+	var clients []*PairedClient
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return nil, errors.New("paired clients file is corrupted")
+	}
+	for _, client := range clients {
+		pm.clients[client.ID] = client
+	}
+
+	return pm, nil
+}
+
+// save writes the paired clients to disk; the caller holds pm.mu
+func (pm *pairingManager) save() error {
+	clients := make([]*PairedClient, 0, len(pm.clients))
+	for _, client := range pm.clients {
+		clients = append(clients, client)
+	}
+
+	data, err := json.MarshalIndent(clients, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(pm.path), 0700); err != nil {
+		return err
+	}
+	return vault.WriteFileAtomic(pm.path, data, 0600)
+}
+
+// startPairing generates a new pairing code, replacing any previous one
+func (pm *pairingManager) startPairing() (string, error) {
+	code := make([]byte, pairingCodeLength)
+	random := make([]byte, pairingCodeLength)
+	if _, err := io.ReadFull(rand.Reader, random); err != nil {
+		return "", err
+	}
+	for i, b := range random {
+		// The alphabet has 32 symbols, so this is unbiased
+		code[i] = pairingCodeAlphabet[int(b)%len(pairingCodeAlphabet)]
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.code = string(code)
+	pm.codeExpires = time.Now().Add(pairingCodeTTL)
+	pm.attempts = 0
+
+	return pm.code[:4] + "-" + pm.code[4:], nil
+}
+
+// pair checks a pairing request against the code on offer and, if it
+// matches, registers the client with a fresh key
+func (pm *pairingManager) pair(request *ipcproto.PairRequest) (*ipcproto.PairResponse, error) {
+	if request == nil || request.ClientID == "" || len(request.Nonce) < 16 {
+		return nil, ipcproto.NewError(ipcproto.CodeBadRequest, "invalid pairing request")
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.code == "" || time.Now().After(pm.codeExpires) {
+		return nil, ipcproto.NewError(ipcproto.CodePairingFailed, "no pairing in progress - start pairing in VaultZero first")
+	}
+
+	pairingKey, err := ipcproto.PairingKey(pm.code, request.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(request.Proof, ipcproto.PairingProof(pairingKey, request.ClientID)) {
+		// Only a few guesses per code, then the user has to start over
+		pm.attempts++
+		if pm.attempts >= pairingMaxAttempts {
+			pm.code = ""
+		}
+		return nil, ipcproto.NewError(ipcproto.CodePairingFailed, "wrong pairing code")
+	}
+
+	// A code pairs exactly one client
+	pm.code = ""
+
+	clientKey, err := vault.GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+	sealedKey, err := ipcproto.Seal(clientKey, pairingKey, ipcproto.PairResponseAAD(request.ClientID))
+	if err != nil {
+		return nil, err
+	}
+
+	name := request.ClientName
+	if name == "" {
+		name = "Browser extension"
+	}
+	pm.clients[request.ClientID] = &PairedClient{
+		ID:       request.ClientID,
+		Name:     name,
+		PairedAt: time.Now(),
+		Key:      clientKey,
+	}
+	if err := pm.save(); err != nil {
+		delete(pm.clients, request.ClientID)
+		return nil, err
+	}
+
+	return &ipcproto.PairResponse{Key: sealedKey}, nil
+}
+
+// openRequest authenticates and decrypts a sealed request. It fails for
+// unknown clients, tampered payloads, stale timestamps and replayed nonces.
+func (pm *pairingManager) openRequest(sealed *ipcproto.SealedMessage) (*PairedClient, *ipcproto.Request, error) {
+	if sealed == nil {
+		return nil, nil, ipcproto.NewError(ipcproto.CodeBadRequest, "missing sealed request")
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	client, ok := pm.clients[sealed.ClientID]
+	if sealed.ClientID == "" || !ok {
+		return nil, nil, ipcproto.NewError(ipcproto.CodeNotPaired, "client is not paired")
+	}
+
+	plaintext, err := ipcproto.Open(sealed.Payload, client.Key, ipcproto.RequestAAD(client.ID))
+	if err != nil {
+		return nil, nil, ipcproto.NewError(ipcproto.CodeAuthFailed, "request authentication failed")
+	}
+
+	var request ipcproto.Request
+	if err := json.Unmarshal(plaintext, &request); err != nil {
+		return nil, nil, ipcproto.NewError(ipcproto.CodeBadRequest, "invalid request format")
+	}
+
+	now := time.Now()
+	sent := time.Unix(request.Timestamp, 0)
+	if sent.Before(now.Add(-ipcRequestMaxSkew)) || sent.After(now.Add(ipcRequestMaxSkew)) {
+		return nil, nil, ipcproto.NewError(ipcproto.CodeAuthFailed, "request expired")
+	}
+
+	// Nonces only need remembering for as long as their timestamp is accepted
+	for nonce, seen := range pm.seenNonces {
+		if now.Sub(seen) > 2*ipcRequestMaxSkew {
+			delete(pm.seenNonces, nonce)
+		}
+	}
+	replayKey := client.ID + "/" + request.Nonce
+	if request.Nonce == "" || !pm.seenNonces[replayKey].IsZero() {
+		return nil, nil, ipcproto.NewError(ipcproto.CodeAuthFailed, "request replayed")
+	}
+	pm.seenNonces[replayKey] = now
+
+	client.LastUsed = now
+	return client, &request, nil
+}
+
+// sealResponse encrypts a response for the client, bound to the request nonce
+func (pm *pairingManager) sealResponse(client *PairedClient, nonce string, response *ipcproto.Response) (*ipcproto.Response, error) {
+	response.Version = ipcproto.Version
+	plaintext, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := ipcproto.Seal(plaintext, client.Key, ipcproto.ResponseAAD(client.ID, nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ipcproto.Response{
+		Success: response.Success,
+		Sealed: &ipcproto.SealedMessage{
+			ClientID: client.ID,
+			Payload:  payload,
+		},
+	}, nil
+}
+
+// isPaired reports whether a client ID belongs to a paired client
+func (pm *pairingManager) isPaired(id string) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	_, ok := pm.clients[id]
+	return ok
+}
+
+// list returns the paired clients without their keys
+func (pm *pairingManager) list() []PairedClient {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	clients := make([]PairedClient, 0, len(pm.clients))
+	for _, client := range pm.clients {
+		info := *client
+		info.Key = nil
+		clients = append(clients, info)
+	}
+	return clients
+}
+
+// unpair forgets a client; its key stops working immediately
+func (pm *pairingManager) unpair(id string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	client, ok := pm.clients[id]
+	if !ok {
+		return errors.New("client not found")
+	}
+
+	delete(pm.clients, id)
+	if err := pm.save(); err != nil {
+		pm.clients[id] = client
+		return err
+	}
+	return nil
+}
+
+// StartPairing creates a pairing code to enter in the browser extension. It
+// is valid for two minutes and pairs a single client.
+func (a *App) StartPairing() (string, error) {
+	if a.pairing == nil {
+		return "", errors.New("browser pairing is unavailable")
+	}
+	return a.pairing.startPairing()
+}
+
+// ListPairedClients returns the browser clients allowed to use the vault
+func (a *App) ListPairedClients() []PairedClient {
+	if a.pairing == nil {
+		return []PairedClient{}
+	}
+	return a.pairing.list()
+}
+
+// UnpairClient revokes a browser client's access
+func (a *App) UnpairClient(id string) error {
+	if a.pairing == nil {
+		return errors.New("browser pairing is unavailable")
+	}
+	return a.pairing.unpair(id)
+}
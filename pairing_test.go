@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"vaultzero/ipcproto"
+	"vaultzero/vault"
+)
+
+// newTestPairing returns a pairing manager keeping its clients in a
+// temporary settings directory
+func newTestPairing(t *testing.T) *pairingManager {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	pm, err := loadPairingManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pm
+}
+
+// pairRequest builds the pairing request a client sends for a typed code
+func pairRequest(t *testing.T, clientID, code string) (*ipcproto.PairRequest, []byte) {
+	t.Helper()
+
+	nonce := []byte("0123456789abcdef")
+	pairingKey, err := ipcproto.PairingKey(code, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ipcproto.PairRequest{
+		ClientID:   clientID,
+		ClientName: "Test browser",
+		Nonce:      nonce,
+		Proof:      ipcproto.PairingProof(pairingKey, clientID),
+	}, pairingKey
+}
+
+// pairTestClient pairs a client and returns the key it received
+func pairTestClient(t *testing.T, pm *pairingManager, clientID string) []byte {
+	t.Helper()
+
+	code, err := pm.startPairing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, pairingKey := pairRequest(t, clientID, code)
+	response, err := pm.pair(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ipcproto.Open(response.Key, pairingKey, ipcproto.PairResponseAAD(clientID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// sealRequest seals a request the way a paired client sends it
+func sealRequest(t *testing.T, clientID string, key []byte, request ipcproto.Request) *ipcproto.SealedMessage {
+	t.Helper()
+
+	plaintext, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := ipcproto.Seal(plaintext, key, ipcproto.RequestAAD(clientID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ipcproto.SealedMessage{ClientID: clientID, Payload: payload}
+}
+
+// errorCode returns the protocol error code of err, or "" if it has none
+func errorCode(err error) ipcproto.ErrorCode {
+	var protoErr *ipcproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code
+	}
+	return ""
+}
+
+func TestPairing(t *testing.T) {
+	pm := newTestPairing(t)
+
+	// Nothing pairs before the user starts pairing
+	request, _ := pairRequest(t, "client", "ABCD-EFGH")
+	if _, err := pm.pair(request); errorCode(err) != ipcproto.CodePairingFailed {
+		t.Fatalf("pairing without a code: %v", err)
+	}
+
+	code, err := pm.startPairing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != pairingCodeLength+1 || code[4] != '-' {
+		t.Errorf("pairing code %q", code)
+	}
+
+	wrong, _ := pairRequest(t, "client", "AAAA-AAAA")
+	if _, err := pm.pair(wrong); errorCode(err) != ipcproto.CodePairingFailed {
+		t.Fatalf("wrong code: %v", err)
+	}
+
+	// The code is typed in any case and without its separator
+	request, pairingKey := pairRequest(t, "client", " "+strings.ToLower(code[:4]+code[5:])+" ")
+	response, err := pm.pair(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ipcproto.Open(response.Key, pairingKey, ipcproto.PairResponseAAD("client"))
+	if err != nil || len(key) != 32 {
+		t.Fatalf("client key: %d bytes, %v", len(key), err)
+	}
+
+	// A code pairs one client only
+	other, _ := pairRequest(t, "other", code)
+	if _, err := pm.pair(other); errorCode(err) != ipcproto.CodePairingFailed {
+		t.Errorf("second pairing with one code: %v", err)
+	}
+
+	// Paired clients are kept, and listed without their keys
+	reloaded, err := loadPairingManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := reloaded.list()
+	if len(clients) != 1 || clients[0].ID != "client" || clients[0].Name != "Test browser" || clients[0].Key != nil {
+		t.Fatalf("paired clients %+v", clients)
+	}
+	if !reloaded.isPaired("client") || reloaded.isPaired("other") {
+		t.Error("isPaired does not match the paired clients")
+	}
+
+	if err := reloaded.unpair("client"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.unpair("client"); err == nil {
+		t.Error("unpaired a client twice")
+	}
+	if reloaded, _ = loadPairingManager(); len(reloaded.list()) != 0 {
+		t.Errorf("unpaired client is still stored: %+v", reloaded.list())
+	}
+}
+
+func TestPairingAttemptsAreLimited(t *testing.T) {
+	pm := newTestPairing(t)
+	code, err := pm.startPairing()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrong, _ := pairRequest(t, "client", "AAAA-AAAA")
+	for i := 0; i < pairingMaxAttempts; i++ {
+		if _, err := pm.pair(wrong); err == nil {
+			t.Fatal("paired with the wrong code")
+		}
+	}
+
+	// Once the guesses are used up the right code no longer works either
+	request, _ := pairRequest(t, "client", code)
+	if _, err := pm.pair(request); errorCode(err) != ipcproto.CodePairingFailed {
+		t.Errorf("pairing after too many attempts: %v", err)
+	}
+}
+
+func TestPairingRequestValidation(t *testing.T) {
+	pm := newTestPairing(t)
+	if _, err := pm.startPairing(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, request := range []*ipcproto.PairRequest{
+		nil,
+		{Nonce: make([]byte, 16)},
+		{ClientID: "client", Nonce: make([]byte, 8)},
+	} {
+		if _, err := pm.pair(request); errorCode(err) != ipcproto.CodeBadRequest {
+			t.Errorf("pair(%+v): got %v, want bad_request", request, err)
+		}
+	}
+}
+
+func TestOpenRequest(t *testing.T) {
+	pm := newTestPairing(t)
+	key := pairTestClient(t, pm, "client")
+	otherKey := pairTestClient(t, pm, "other")
+
+	request := func(nonce string, sent time.Time) ipcproto.Request {
+		return ipcproto.Request{
+			Version:   ipcproto.Version,
+			Action:    ipcproto.ActionSearch,
+			Nonce:     nonce,
+			Timestamp: sent.Unix(),
+			Search:    &ipcproto.SearchRequest{URL: "https://github.com"},
+		}
+	}
+	now := time.Now()
+
+	sealed := sealRequest(t, "client", key, request("n1", now))
+	client, opened, err := pm.openRequest(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.ID != "client" || opened.Action != ipcproto.ActionSearch || opened.Search.URL != "https://github.com" {
+		t.Fatalf("opened %+v from %+v", opened, client)
+	}
+	if client.LastUsed.IsZero() {
+		t.Error("last use was not recorded")
+	}
+
+	tampered := *sealRequest(t, "client", key, request("n2", now))
+	tampered.Payload = tampered.Payload[:len(tampered.Payload)-4] + "AAAA"
+
+	tests := []struct {
+		name   string
+		sealed *ipcproto.SealedMessage
+		code   ipcproto.ErrorCode
+	}{
+		{"missing", nil, ipcproto.CodeBadRequest},
+		{"unknown client", sealRequest(t, "stranger", key, request("n3", now)), ipcproto.CodeNotPaired},
+		{"replayed nonce", sealed, ipcproto.CodeAuthFailed},
+		{"tampered payload", &tampered, ipcproto.CodeAuthFailed},
+		{"sealed by another client", &ipcproto.SealedMessage{ClientID: "client", Payload: sealRequest(t, "other", otherKey, request("n4", now)).Payload}, ipcproto.CodeAuthFailed},
+		{"sealed for another client", &ipcproto.SealedMessage{ClientID: "other", Payload: sealRequest(t, "client", otherKey, request("n5", now)).Payload}, ipcproto.CodeAuthFailed},
+		{"stale timestamp", sealRequest(t, "client", key, request("n6", now.Add(-ipcRequestMaxSkew-time.Minute))), ipcproto.CodeAuthFailed},
+		{"future timestamp", sealRequest(t, "client", key, request("n7", now.Add(ipcRequestMaxSkew+time.Minute))), ipcproto.CodeAuthFailed},
+		{"no timestamp", sealRequest(t, "client", key, request("n8", time.Unix(0, 0))), ipcproto.CodeAuthFailed},
+		{"no nonce", sealRequest(t, "client", key, request("", now)), ipcproto.CodeAuthFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := pm.openRequest(tt.sealed); errorCode(err) != tt.code {
+				t.Errorf("got %v, want %s", err, tt.code)
+			}
+		})
+	}
+
+	// Nonces are per client, and a rejected request does not use one up
+	if _, _, err := pm.openRequest(sealRequest(t, "other", otherKey, request("n1", now))); err != nil {
+		t.Errorf("other client reusing a nonce: %v", err)
+	}
+	if _, _, err := pm.openRequest(sealRequest(t, "client", key, request("n6", now))); err != nil {
+		t.Errorf("nonce of a rejected request: %v", err)
+	}
+
+	// A client stops working as soon as it is unpaired
+	if err := pm.unpair("client"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := pm.openRequest(sealRequest(t, "client", key, request("n9", now))); errorCode(err) != ipcproto.CodeNotPaired {
+		t.Errorf("unpaired client: %v", err)
+	}
+}
+
+func TestSealedRequestOverConnection(t *testing.T) {
+	pm := newTestPairing(t)
+	key := pairTestClient(t, pm, "client")
+
+	v := vault.New(vault.NewMemoryStore(t.Name()))
+	if err := v.Create("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.AddCredential(vault.Credential{ServiceName: "GitHub", URL: "https://github.com/login", Username: "octocat", Password: "hunter22"}); err != nil {
+		t.Fatal(err)
+	}
+	conn, _, _ := serveTestConnection(t, NewIPCServer(&App{vault: v, pairing: pm}))
+
+	search := ipcproto.Request{
+		Version: ipcproto.Version,
+		Action:  ipcproto.ActionSealed,
+		Sealed: sealRequest(t, "client", key, ipcproto.Request{
+			Version:   ipcproto.Version,
+			Action:    ipcproto.ActionSearch,
+			Nonce:     "n1",
+			Timestamp: time.Now().Unix(),
+			Search:    &ipcproto.SearchRequest{URL: "https://github.com"},
+		}),
+	}
+	if err := conn.Send(search); err != nil {
+		t.Fatal(err)
+	}
+	var response ipcproto.Response
+	if err := conn.Receive(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Sealed == nil || response.Credentials != nil {
+		t.Fatalf("response is not sealed: %+v", response)
+	}
+
+	// The response opens only as the answer to its request
+	if _, err := ipcproto.Open(response.Sealed.Payload, key, ipcproto.ResponseAAD("client", "n2")); err == nil {
+		t.Error("response opened for another request nonce")
+	}
+	plaintext, err := ipcproto.Open(response.Sealed.Payload, key, ipcproto.ResponseAAD("client", "n1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inner ipcproto.Response
+	if err := json.Unmarshal(plaintext, &inner); err != nil {
+		t.Fatal(err)
+	}
+	if !inner.Success || len(inner.Credentials) != 1 || inner.Credentials[0].Username != "octocat" {
+		t.Fatalf("search response %+v", inner)
+	}
+
+	// Sending the same sealed request again is refused
+	if err := conn.Send(search); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Receive(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Success || response.Error.Code != ipcproto.CodeAuthFailed {
+		t.Errorf("replayed request: %+v", response)
+	}
+}
+
+func TestPairedClientsFileCorrupted(t *testing.T) {
+	pm := newTestPairing(t)
+	if err := os.MkdirAll(filepath.Dir(pm.path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pm.path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadPairingManager(); err == nil {
+		t.Error("loaded a corrupted paired clients file")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows && !linux && !darwin
+
+package main
+
+import (
+	"errors"
+	"net"
+)
+
+// peerUID is not implemented here, so every client is rejected
+func peerUID(conn *net.UnixConn) (int, error) {
+	return -1, errors.New("peer credentials are not supported on this platform")
+}
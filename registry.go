@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"vaultzero/vault"
+)
+
+// ListVaults returns all known vaults
+func (a *App) ListVaults() ([]vault.VaultInfo, error) {
+	vaults := make([]vault.VaultInfo, 0, len(a.registry.Vaults))
+	for _, entry := range a.registry.Vaults {
+		_, err := os.Stat(entry.Path)
+		vaults = append(vaults, vault.VaultInfo{
+			Name:       entry.Name,
+			Path:       entry.Path,
+			LastOpened: entry.LastOpened,
+			Exists:     err == nil,
+			Active:     filepath.Clean(entry.Path) == filepath.Clean(a.vault.Location()),
+		})
+	}
+	return vaults, nil
+}
+
+// OpenVault switches to an existing vault file or directory. The current
+// vault is locked; the new one still has to be unlocked with its password.
+func (a *App) OpenVault(path string) error {
+	storage, err := a.storeAt(path)
+	if err != nil {
+		return err
+	}
+	if !storage.VaultExists() {
+		return errors.New("no vault found at " + storage.Location())
+	}
+
+	a.switchStorage(storage)
+	if err := a.rememberVault(""); err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "vault-switched", storage.Location())
+	return nil
+}
+
+// storeAt opens the vault store for a location; without a configured
+// backend vaults are files on disk
+func (a *App) storeAt(location string) (vault.VaultStore, error) {
+	if a.openStore == nil {
+		return openFileStore(location)
+	}
+	return a.openStore(location)
+}
+
+// openFileStore opens the file-backed store for a vault path or directory
+func openFileStore(location string) (vault.VaultStore, error) {
+	storage, err := vault.NewStorageManager(location)
+	if err != nil {
+		return nil, err
+	}
+	storage.OnBackupError = func(err error) {
+		println("Warning:", err.Error())
+	}
+	return storage, nil
+}
+
+// switchStorage locks the current vault and makes storage the active one
+func (a *App) switchStorage(storage vault.VaultStore) {
+	a.LockVault()
+	a.vault = a.newVault(storage)
+}
+
+// newVault opens the vault in storage, telling the frontend when it is
+// reloaded after changes made outside the app
+func (a *App) newVault(storage vault.VaultStore) *vault.Vault {
+	v := vault.New(storage)
+	v.OnReload = a.emitItemsUpdated
+	v.OnConflict = func(err error) {
+		runtime.EventsEmit(a.ctx, "vault-conflict", err.Error())
+	}
+	return v
+}
+
+// emitItemsUpdated tells the frontend to reload every kind of item
+func (a *App) emitItemsUpdated() {
+	runtime.EventsEmit(a.ctx, "credentials-updated")
+	runtime.EventsEmit(a.ctx, "creditcards-updated")
+	runtime.EventsEmit(a.ctx, "securenotes-updated")
+	runtime.EventsEmit(a.ctx, "identities-updated")
+}
+
+// rememberVault records the active vault in the registry as the current one
+func (a *App) rememberVault(name string) error {
+	path := a.vault.Location()
+	a.registry.Add(name, path)
+	a.registry.SetCurrent(path)
+	return a.registry.Save()
+}
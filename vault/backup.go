@@ -0,0 +1,230 @@
+package vault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupDirName      = "backups"
+	backupFileSuffix   = ".dat"
+	backupTimestampFmt = "20060102T150405.000000000Z"
+)
+
+// BackupPolicy controls how many earlier vault generations are kept
+type BackupPolicy struct {
+	MaxCount int           `json:"maxCount"` // 0 disables backups
+	MaxAge   time.Duration `json:"maxAge"`   // 0 keeps backups regardless of age
+}
+
+// DefaultBackupPolicy keeps the last 10 generations for up to 30 days
+func DefaultBackupPolicy() BackupPolicy {
+	return BackupPolicy{
+		MaxCount: 10,
+		MaxAge:   30 * 24 * time.Hour,
+	}
+}
+
+// BackupInfo describes one backup generation of the vault
+type BackupInfo struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Credentials int       `json:"credentials"`
+	CreditCards int       `json:"creditCards"`
+	SecureNotes int       `json:"secureNotes"`
+	Identities  int       `json:"identities"`
+	Readable    bool      `json:"readable"` // false if it cannot be decrypted with the current key
+}
+
+// SetBackupPolicy changes how many backups are kept; it applies from the next save
+func (sm *StorageManager) SetBackupPolicy(policy BackupPolicy) {
+	sm.backupPolicy = policy
+}
+
+// GetBackupPolicy returns the current backup policy
+func (sm *StorageManager) GetBackupPolicy() BackupPolicy {
+	return sm.backupPolicy
+}
+
+// backupCurrentVault copies the vault file into the backup directory and
+// prunes old generations. Headerless legacy files are not backed up since
+// they are useless without the salt file that migration removes.
+func (sm *StorageManager) backupCurrentVault() error {
+	if sm.backupPolicy.MaxCount <= 0 {
+		return nil
+	}
+
+	raw, err := os.ReadFile(sm.vaultPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !isVaultContainer(raw) {
+		return nil
+	}
+
+	if err := os.MkdirAll(sm.backupDir, 0700); err != nil {
+		return err
+	}
+
+	name := sm.backupPrefix + time.Now().UTC().Format(backupTimestampFmt) + backupFileSuffix
+	if err := WriteFileAtomic(filepath.Join(sm.backupDir, name), raw, 0600); err != nil {
+		return err
+	}
+
+	return sm.pruneBackups()
+}
+
+// pruneBackups removes generations beyond the policy's count and age limits.
+// The newest backup is never removed for being too old.
+func (sm *StorageManager) pruneBackups() error {
+	backups, err := sm.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-sm.backupPolicy.MaxAge)
+	for i, backup := range backups {
+		tooMany := i >= sm.backupPolicy.MaxCount
+		tooOld := i > 0 && sm.backupPolicy.MaxAge > 0 && backup.CreatedAt.Before(cutoff)
+		if tooMany || tooOld {
+			if err := os.Remove(filepath.Join(sm.backupDir, backup.ID)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ListBackups returns the available backups, newest first, without decrypting them
+func (sm *StorageManager) ListBackups() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(sm.backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []BackupInfo{}, nil
+		}
+		return nil, err
+	}
+
+	backups := []BackupInfo{}
+	for _, entry := range entries {
+		createdAt, ok := sm.parseBackupName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		backups = append(backups, BackupInfo{
+			ID:        entry.Name(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// LoadBackup decrypts a backup generation with the vault data key
+func (sm *StorageManager) LoadBackup(id string, dataKey []byte) (*Contents, error) {
+	if _, ok := sm.parseBackupName(id); !ok || filepath.Base(id) != id {
+		return nil, errors.New("invalid backup id")
+	}
+
+	raw, err := os.ReadFile(filepath.Join(sm.backupDir, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("backup not found")
+		}
+		return nil, err
+	}
+
+	file, err := decodeVaultFile(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	vault, _, err := decryptVault(file, dataKey)
+	if err != nil {
+		return nil, errors.New("backup cannot be decrypted with the current vault key")
+	}
+
+	return vault, nil
+}
+
+// parseBackupName extracts the creation time from the name of one of this vault's backups
+func (sm *StorageManager) parseBackupName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, sm.backupPrefix) || !strings.HasSuffix(name, backupFileSuffix) {
+		return time.Time{}, false
+	}
+
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, sm.backupPrefix), backupFileSuffix)
+	createdAt, err := time.Parse(backupTimestampFmt, stamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return createdAt, true
+}
+
+// ListBackups returns the vault's backup generations with their item counts
+func (v *Vault) ListBackups() ([]BackupInfo, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return nil, ErrLocked
+	}
+
+	backups, err := v.store.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range backups {
+		backup, err := v.store.LoadBackup(backups[i].ID, v.dataKey)
+		if err != nil {
+			continue
+		}
+		backups[i].Credentials = len(backup.Credentials)
+		backups[i].CreditCards = len(backup.CreditCards)
+		backups[i].SecureNotes = len(backup.SecureNotes)
+		backups[i].Identities = len(backup.Identities)
+		backups[i].Readable = true
+	}
+
+	return backups, nil
+}
+
+// RestoreBackup replaces the vault contents with a backup generation. The
+// backup must decrypt with the current data key; the current master password
+// stays in effect and the replaced contents become a backup themselves.
+func (v *Vault) RestoreBackup(id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return ErrLocked
+	}
+
+	restored, err := v.store.LoadBackup(id, v.dataKey)
+	if err != nil {
+		return err
+	}
+
+	// Keep the current header so the current master password stays in effect
+	return v.update(func(c *Contents) error {
+		c.Credentials = restored.Credentials
+		c.CreditCards = restored.CreditCards
+		c.SecureNotes = restored.SecureNotes
+		c.Identities = restored.Identities
+		return nil
+	})
+}
@@ -0,0 +1,170 @@
+ package vault
+
+  import (
+        "crypto/aes"
+        "crypto/cipher"
+        "crypto/hmac"
+        "crypto/rand"
+        "crypto/sha256"
+        "encoding/base64"
+        "errors"
+        "fmt"
+        "io"
+
+        "golang.org/x/crypto/argon2"
+  )
+
+  const (
+        saltSize = 32
+        keySize  = 32
+
+        kdfArgon2id = "argon2id"
+
+        keyCheckLabel = "vaultzero key check v1"
+  )
+
+  // KDFParams describes how the master key is derived from the master password
+  type KDFParams struct {
+        Algorithm string `json:"algorithm"`
+        Time      uint32 `json:"time"`
+        Memory    uint32 `json:"memory"` // KiB
+        Threads   uint8  `json:"threads"`
+  }
+
+  // DefaultKDFParams returns the parameters used for new vaults and password changes
+  func DefaultKDFParams() KDFParams {
+        return KDFParams{Algorithm: kdfArgon2id, Time: 3, Memory: 64 * 1024, Threads: 4}
+  }
+
+  // LegacyKDFParams returns the fixed parameters used by headerless vaults
+  func LegacyKDFParams() KDFParams {
+        return KDFParams{Algorithm: kdfArgon2id, Time: 1, Memory: 64 * 1024, Threads: 4}
+  }
+
+  // Validate rejects unknown algorithms and parameters that are unusable or absurdly expensive
+  func (p KDFParams) Validate() error {
+        if p.Algorithm != kdfArgon2id {
+                return fmt.Errorf("unsupported key derivation algorithm %q", p.Algorithm)
+        }
+        if p.Time == 0 || p.Time > 64 || p.Memory < 8*1024 || p.Memory > 4*1024*1024 || p.Threads == 0 {
+                return errors.New("invalid key derivation parameters")
+        }
+        return nil
+  }
+
+  // DeriveKey uses Argon2 to derive a strong encryption key from the master password
+  func DeriveKey(password string, salt []byte, params KDFParams) ([]byte, error) {
+        if err := params.Validate(); err != nil {
+                return nil, err
+        }
+        return argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, keySize), nil
+  }
+
+  // GenerateSalt creates a random salt for key derivation
+  func GenerateSalt() ([]byte, error) {
+        salt := make([]byte, saltSize)
+        if _, err := rand.Read(salt); err != nil {
+                return nil, err
+        }
+        return salt, nil
+  }
+
+  // GenerateDataKey creates a random key for encrypting vault contents
+  func GenerateDataKey() ([]byte, error) {
+        key := make([]byte, keySize)
+        if _, err := rand.Read(key); err != nil {
+                return nil, err
+        }
+        return key, nil
+  }
+
+  // WrapKey encrypts a data key with a key-encryption key
+  func WrapKey(dataKey, kek []byte) (string, error) {
+        return Encrypt(dataKey, kek)
+  }
+
+  // UnwrapKey decrypts a data key wrapped by WrapKey
+  func UnwrapKey(wrapped string, kek []byte) ([]byte, error) {
+        dataKey, err := Decrypt(wrapped, kek)
+        if err != nil {
+                return nil, err
+        }
+        if len(dataKey) != keySize {
+                return nil, errors.New("wrapped key has invalid length")
+        }
+        return dataKey, nil
+  }
+
+  // Encrypt encrypts plaintext using AES-256-GCM
+  func Encrypt(plaintext []byte, key []byte) (string, error) {
+        return EncryptWithAAD(plaintext, key, nil)
+  }
+
+  // EncryptWithAAD encrypts plaintext using AES-256-GCM, authenticating additional data that is not stored
+  func EncryptWithAAD(plaintext []byte, key []byte, aad []byte) (string, error) {
+        block, err := aes.NewCipher(key)
+        if err != nil {
+                return "", err
+        }
+
+        gcm, err := cipher.NewGCM(block)
+        if err != nil {
+                return "", err
+        }
+
+        nonce := make([]byte, gcm.NonceSize())
+        if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+                return "", err
+        }
+
+        ciphertext := gcm.Seal(nonce, nonce, plaintext, aad)
+        return base64.StdEncoding.EncodeToString(ciphertext), nil
+  }
+
+  // Decrypt decrypts ciphertext using AES-256-GCM
+  func Decrypt(ciphertext string, key []byte) ([]byte, error) {
+        return DecryptWithAAD(ciphertext, key, nil)
+  }
+
+  // DecryptWithAAD decrypts ciphertext using AES-256-GCM, checking the same additional data used to encrypt it
+  func DecryptWithAAD(ciphertext string, key []byte, aad []byte) ([]byte, error) {
+        data, err := base64.StdEncoding.DecodeString(ciphertext)
+        if err != nil {
+                return nil, err
+        }
+
+        block, err := aes.NewCipher(key)
+        if err != nil {
+                return nil, err
+        }
+
+        gcm, err := cipher.NewGCM(block)
+        if err != nil {
+                return nil, err
+        }
+
+        nonceSize := gcm.NonceSize()
+        if len(data) < nonceSize {
+                return nil, errors.New("ciphertext too short")
+        }
+
+        nonce, cipherData := data[:nonceSize], data[nonceSize:]
+        plaintext, err := gcm.Open(nil, nonce, cipherData, aad)
+        if err != nil {
+                return nil, err
+        }
+
+        return plaintext, nil
+  }
+
+  // KeyCheckValue computes a verifier for a derived key that reveals nothing about the key itself
+  func KeyCheckValue(key []byte) []byte {
+        mac := hmac.New(sha256.New, key)
+        mac.Write([]byte(keyCheckLabel))
+        return mac.Sum(nil)
+  }
+
+  // VerifyKeyCheck compares a key against a stored key check value in constant time
+  func VerifyKeyCheck(key, check []byte) bool {
+        return hmac.Equal(KeyCheckValue(key), check)
+  }
\ No newline at end of file
@@ -0,0 +1,194 @@
+package vault
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ExportCredentialsToCSV exports credentials to a CSV file (Chrome format).
+// Custom fields follow in one column per label, which ParseCSV reads back
+// as custom fields.
+func ExportCredentialsToCSV(credentials []Credential, filePath string) error {
+	// Create file
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer file.Close()
+
+	// Create CSV writer
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	// Write header (Chrome format: name,url,username,password)
+	header := []string{"name", "url", "username", "password"}
+	added := make(map[string]bool)
+	for _, cred := range credentials {
+		for _, column := range fieldColumns(cred.Fields) {
+			if !added[column] {
+				added[column] = true
+				header = append(header, column)
+			}
+		}
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write header: %v", err)
+	}
+
+	// Write credentials
+	for _, cred := range credentials {
+		record := []string{
+			cred.ServiceName,
+			cred.URL,
+			cred.Username,
+			cred.Password,
+		}
+		values := make(map[string]string, len(cred.Fields))
+		for i, column := range fieldColumns(cred.Fields) {
+			values[column] = cred.Fields[i].Value
+		}
+		for _, column := range header[len(record):] {
+			record = append(record, values[column])
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write credential: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// fieldColumns names the CSV column of each custom field. A label used more
+// than once gets numbered columns, "PIN", "PIN (2)" and so on.
+func fieldColumns(fields []CustomField) []string {
+	columns := make([]string, len(fields))
+	seen := make(map[string]int)
+	for i, field := range fields {
+		seen[field.Label]++
+		columns[i] = field.Label
+		if n := seen[field.Label]; n > 1 {
+			columns[i] = fmt.Sprintf("%s (%d)", field.Label, n)
+		}
+	}
+	return columns
+}
+
+// backupFile is what an encrypted backup holds: every item of the vault.
+// Backups made before secure notes existed hold only the JSON array of
+// credentials, and those made before cards and identities were backed up
+// have neither.
+type backupFile struct {
+	Credentials []Credential `json:"credentials"`
+	CreditCards []CreditCard `json:"creditCards,omitempty"`
+	SecureNotes []SecureNote `json:"secureNotes"`
+	Identities  []Identity   `json:"identities,omitempty"`
+}
+
+const backupMagic = "VAULTZERO-BACKUP"
+
+// backupContainer is the on-disk form of an encrypted backup: a copy of the
+// vault header, whose wrapped data key the master password unlocks, and the
+// backup encrypted with that data key. Older backups are a bare base64
+// string encrypted with the vault's key of the time.
+type backupContainer struct {
+	VaultHeader
+	Data string `json:"data"`
+}
+
+// ExportEncryptedBackup creates a timestamped encrypted backup of the vault.
+// It carries the vault header, so any vault can import it given the master
+// password in use when it was exported.
+func ExportEncryptedBackup(contents *Contents, dataKey []byte, filePath string) error {
+	if contents.Header == nil || contents.Header.WrappedKey == "" {
+		return errors.New("vault has no wrapped data key to back up")
+	}
+
+	// Serialize vault items to JSON
+	data, err := json.Marshal(backupFile{
+		Credentials: contents.Credentials,
+		CreditCards: contents.CreditCards,
+		SecureNotes: contents.SecureNotes,
+		Identities:  contents.Identities,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault: %v", err)
+	}
+
+	// Encrypt vault
+	encryptedVault, err := Encrypt(data, dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt vault: %v", err)
+	}
+
+	backup := backupContainer{VaultHeader: *contents.Header, Data: encryptedVault}
+	backup.Magic = backupMagic
+	encoded, err := json.Marshal(backup)
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup: %v", err)
+	}
+
+	// Write to file
+	if err := os.WriteFile(filePath, encoded, 0600); err != nil {
+		return fmt.Errorf("failed to write backup: %v", err)
+	}
+
+	return nil
+}
+
+// ExportResult contains information about the export operation
+type ExportResult struct {
+	FilePath        string    `json:"filePath"`
+	CredentialCount int       `json:"credentialCount"`
+	CreditCardCount int       `json:"creditCardCount"` // only encrypted backups carry
+	SecureNoteCount int       `json:"secureNoteCount"` // items other than credentials
+	IdentityCount   int       `json:"identityCount"`
+	ExportedAt      time.Time `json:"exportedAt"`
+	Format          string    `json:"format"` // "csv" or "encrypted"
+}
+
+// ExportCSV writes the vault's credentials to a CSV file in Chrome's format
+func (v *Vault) ExportCSV(filePath string) (*ExportResult, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return nil, ErrLocked
+	}
+	if err := ExportCredentialsToCSV(v.contents.Credentials, filePath); err != nil {
+		return nil, err
+	}
+	return v.exportResult(filePath, "csv"), nil
+}
+
+// ExportEncryptedBackup writes all of the vault's items to a backup file
+// that the current master password unlocks
+func (v *Vault) ExportEncryptedBackup(filePath string) (*ExportResult, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return nil, ErrLocked
+	}
+	if err := ExportEncryptedBackup(v.contents, v.dataKey, filePath); err != nil {
+		return nil, err
+	}
+	result := v.exportResult(filePath, "encrypted")
+	result.CreditCardCount = len(v.contents.CreditCards)
+	result.SecureNoteCount = len(v.contents.SecureNotes)
+	result.IdentityCount = len(v.contents.Identities)
+	return result, nil
+}
+
+// exportResult describes an export of the vault's credentials
+func (v *Vault) exportResult(filePath, format string) *ExportResult {
+	return &ExportResult{
+		FilePath:        filePath,
+		CredentialCount: len(v.contents.Credentials),
+		ExportedAt:      time.Now(),
+		Format:          format,
+	}
+}
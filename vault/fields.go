@@ -0,0 +1,270 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Custom field types. Hidden and TOTP values are secrets: they are shown
+// masked until revealed, like passwords.
+const (
+	FieldTypeText   = "text"
+	FieldTypeHidden = "hidden"
+	FieldTypeURL    = "url"
+	FieldTypeEmail  = "email"
+	FieldTypeDate   = "date" // YYYY-MM-DD format
+	FieldTypeTOTP   = "totp" // otpauth://totp URI
+)
+
+var (
+	// ErrItemNotFound is returned for an ID that matches no item of any type
+	ErrItemNotFound = errors.New("item not found")
+
+	// ErrCustomFieldNotFound is returned for an unknown custom field ID
+	ErrCustomFieldNotFound = errors.New("custom field not found")
+)
+
+// CustomField is a labelled value added to an item, such as a security
+// question, an account number or a PIN
+type CustomField struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Concealed reports whether the field holds a secret that is masked and
+// copied like a password
+func (f CustomField) Concealed() bool {
+	return f.Type == FieldTypeHidden || f.Type == FieldTypeTOTP
+}
+
+// Code returns the current one-time password of a TOTP field
+func (f CustomField) Code(at time.Time) (OneTimeCode, error) {
+	if f.Type != FieldTypeTOTP {
+		return OneTimeCode{}, ErrNoOTP
+	}
+	otp, err := ParseOTPAuthURI(f.Value)
+	if err != nil {
+		return OneTimeCode{}, err
+	}
+	return otp.Code(at), nil
+}
+
+// NormalizeCustomFields checks a list of custom fields before it is stored,
+// keeping its order. Fields without an ID get a fresh one, fields without a
+// type are text, and TOTP fields may be given as a bare base32 secret.
+func NormalizeCustomFields(fields []CustomField) ([]CustomField, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	normalized := make([]CustomField, 0, len(fields))
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		field, err := normalizeCustomField(field)
+		if err != nil {
+			return nil, err
+		}
+		if seen[field.ID] {
+			field.ID = uuid.New().String()
+		}
+		seen[field.ID] = true
+		normalized = append(normalized, field)
+	}
+	return normalized, nil
+}
+
+// normalizeCustomField validates one field's value against its type
+func normalizeCustomField(field CustomField) (CustomField, error) {
+	field.Label = strings.TrimSpace(field.Label)
+	if field.Label == "" {
+		return CustomField{}, errors.New("custom fields need a label")
+	}
+	if field.ID == "" {
+		field.ID = uuid.New().String()
+	}
+	if field.Type == "" {
+		field.Type = FieldTypeText
+	}
+
+	switch field.Type {
+	case FieldTypeText, FieldTypeHidden:
+
+	case FieldTypeURL:
+		field.Value = strings.TrimSpace(field.Value)
+		if field.Value != "" && !isAbsoluteURL(field.Value) {
+			return CustomField{}, fmt.Errorf("%s is not a valid URL", field.Label)
+		}
+
+	case FieldTypeEmail:
+		field.Value = strings.TrimSpace(field.Value)
+		if field.Value != "" {
+			if _, err := mail.ParseAddress(field.Value); err != nil {
+				return CustomField{}, fmt.Errorf("%s is not a valid email address", field.Label)
+			}
+		}
+
+	case FieldTypeDate:
+		field.Value = strings.TrimSpace(field.Value)
+		if field.Value != "" {
+			if _, err := time.Parse("2006-01-02", field.Value); err != nil {
+				return CustomField{}, fmt.Errorf("%s is not a date in YYYY-MM-DD format", field.Label)
+			}
+		}
+
+	case FieldTypeTOTP:
+		otp, err := ParseOTP(field.Value, "", field.Label)
+		if err != nil {
+			return CustomField{}, fmt.Errorf("%s: %v", field.Label, err)
+		}
+		if otp.Type != OTPTypeTOTP {
+			return CustomField{}, fmt.Errorf("%s is not a time-based one-time password", field.Label)
+		}
+		field.Value = otp.URI()
+
+	default:
+		return CustomField{}, fmt.Errorf("unknown custom field type %q", field.Type)
+	}
+
+	return field, nil
+}
+
+// customFieldType guesses the type of a value imported without one
+func customFieldType(label, value string) string {
+	lower := strings.ToLower(value)
+	switch {
+	case strings.HasPrefix(lower, "otpauth://"):
+		// Fields only generate TOTP codes. HOTP and broken secrets are
+		// still kept, masked.
+		if otp, err := ParseOTPAuthURI(value); err != nil || otp.Type != OTPTypeTOTP {
+			return FieldTypeHidden
+		}
+		return FieldTypeTOTP
+	case (strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")) && isAbsoluteURL(value):
+		return FieldTypeURL
+	}
+	if _, err := time.Parse("2006-01-02", value); err == nil {
+		return FieldTypeDate
+	}
+	if address, err := mail.ParseAddress(value); err == nil && address.Address == value {
+		return FieldTypeEmail
+	}
+
+	// Values under labels with words like these are secrets
+	words := strings.FieldsFunc(strings.ToLower(label), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		switch word {
+		case "password", "passcode", "pin", "secret", "key", "token", "answer", "cvv":
+			return FieldTypeHidden
+		}
+	}
+	return FieldTypeText
+}
+
+// isAbsoluteURL reports whether value is a URL with a scheme and a host
+func isAbsoluteURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// normalizeChangedFields normalizes an item's custom fields after an update
+// changed them from before. Untouched fields are left as stored, so items
+// saved under older rules stay editable.
+func normalizeChangedFields(fields *[]CustomField, before []CustomField) error {
+	if slices.Equal(*fields, before) {
+		return nil
+	}
+	normalized, err := NormalizeCustomFields(*fields)
+	if err != nil {
+		return err
+	}
+	*fields = normalized
+	return nil
+}
+
+// itemFields returns the custom fields of the item with the given ID, of
+// whichever type it is
+func (c *Contents) itemFields(id string) (*[]CustomField, error) {
+	for i := range c.Credentials {
+		if c.Credentials[i].ID == id {
+			return &c.Credentials[i].Fields, nil
+		}
+	}
+	for i := range c.CreditCards {
+		if c.CreditCards[i].ID == id {
+			return &c.CreditCards[i].Fields, nil
+		}
+	}
+	for i := range c.SecureNotes {
+		if c.SecureNotes[i].ID == id {
+			return &c.SecureNotes[i].Fields, nil
+		}
+	}
+	for i := range c.Identities {
+		if c.Identities[i].ID == id {
+			return &c.Identities[i].Fields, nil
+		}
+	}
+	return nil, ErrItemNotFound
+}
+
+// CustomFields returns the custom fields of any item, in order
+func (v *Vault) CustomFields(itemID string) ([]CustomField, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return nil, ErrLocked
+	}
+	fields, err := v.contents.itemFields(itemID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]CustomField{}, *fields...), nil
+}
+
+// CustomField returns one custom field of an item
+func (v *Vault) CustomField(itemID, fieldID string) (CustomField, error) {
+	fields, err := v.CustomFields(itemID)
+	if err != nil {
+		return CustomField{}, err
+	}
+	for _, field := range fields {
+		if field.ID == fieldID {
+			return field, nil
+		}
+	}
+	return CustomField{}, ErrCustomFieldNotFound
+}
+
+// SetCustomFields replaces the custom fields of any item with fields, in
+// the given order
+func (v *Vault) SetCustomFields(itemID string, fields []CustomField) error {
+	normalized, err := NormalizeCustomFields(fields)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.update(func(c *Contents) error {
+		stored, err := c.itemFields(itemID)
+		if err != nil {
+			return err
+		}
+		*stored = normalized
+		return nil
+	})
+}
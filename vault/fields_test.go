@@ -0,0 +1,152 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeCustomFields(t *testing.T) {
+	fields, err := NormalizeCustomFields([]CustomField{
+		{Label: " Account number ", Value: "12-345"},
+		{Label: "Backup codes", Type: FieldTypeTOTP, Value: "jbsw y3dp ehpk 3pxp"},
+		{Label: "Renewal", Type: FieldTypeDate, Value: "2027-01-31"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields[0].Label != "Account number" || fields[0].Type != FieldTypeText || fields[0].ID == "" {
+		t.Errorf("text field = %+v", fields[0])
+	}
+	if !strings.HasPrefix(fields[1].Value, "otpauth://totp/") || !fields[1].Concealed() {
+		t.Errorf("TOTP field = %+v", fields[1])
+	}
+	if _, err := fields[1].Code(time.Unix(59, 0)); err != nil {
+		t.Errorf("TOTP field cannot generate codes: %v", err)
+	}
+
+	invalid := []CustomField{
+		{Value: "no label"},
+		{Label: "Kind", Type: "phone", Value: "555"},
+		{Label: "Born", Type: FieldTypeDate, Value: "31/01/1990"},
+		{Label: "Mail", Type: FieldTypeEmail, Value: "not an address"},
+		{Label: "Code", Type: FieldTypeTOTP, Value: "otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP&counter=1"},
+		{Label: "Site", Type: FieldTypeURL, Value: "bank.example/help"},
+		{Label: "Site", Type: FieldTypeURL, Value: "https://"},
+		{Label: "Site", Type: FieldTypeURL, Value: "mailto:help@bank.example"},
+	}
+	for _, field := range invalid {
+		if _, err := NormalizeCustomFields([]CustomField{field}); err == nil {
+			t.Errorf("accepted %+v", field)
+		}
+	}
+}
+
+func TestSetCustomFields(t *testing.T) {
+	v, _ := newTestVault(t)
+
+	card, err := v.AddCreditCard(CreditCard{CardName: "Personal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.SetCustomFields(card.ID, []CustomField{
+		{Label: "Phone PIN", Type: FieldTypeHidden, Value: "0000"},
+		{Label: "Support", Type: FieldTypeURL, Value: "https://bank.example/help"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The order is kept across a reload
+	v.Lock()
+	if err := v.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	fields, err := v.CustomFields(card.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 2 || fields[0].Label != "Phone PIN" || fields[1].Label != "Support" {
+		t.Fatalf("reloaded fields %+v", fields)
+	}
+
+	field, err := v.CustomField(card.ID, fields[0].ID)
+	if err != nil || field.Value != "0000" {
+		t.Errorf("CustomField = %+v, %v", field, err)
+	}
+	if _, err := v.CustomField(card.ID, "missing"); !errors.Is(err, ErrCustomFieldNotFound) {
+		t.Errorf("unknown field: got %v", err)
+	}
+	if err := v.SetCustomFields("missing", nil); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("unknown item: got %v", err)
+	}
+}
+
+func TestCustomFieldType(t *testing.T) {
+	tests := []struct {
+		label, value string
+		want         string
+	}{
+		{"Recovery", "otpauth://totp/Bank:me?secret=JBSWY3DPEHPK3PXP", FieldTypeTOTP},
+		{"Recovery", "otpauth://hotp/Bank:me?secret=JBSWY3DPEHPK3PXP&counter=1", FieldTypeHidden},
+		{"Recovery", "otpauth://totp/Bank:me?secret=not-base32", FieldTypeHidden},
+		{"Support", "https://bank.example/help", FieldTypeURL},
+		{"Support", "https://", FieldTypeText},
+		{"Renewal", "2027-01-31", FieldTypeDate},
+		{"Contact", "help@bank.example", FieldTypeEmail},
+		{"Phone PIN", "0000", FieldTypeHidden},
+		{"Account number", "12-345", FieldTypeText},
+	}
+	for _, tt := range tests {
+		got := customFieldType(tt.label, tt.value)
+		if got != tt.want {
+			t.Errorf("customFieldType(%q, %q) = %s, want %s", tt.label, tt.value, got, tt.want)
+		}
+
+		// Every guess must pass the checks made when it is stored
+		if _, err := NormalizeCustomFields([]CustomField{{Label: tt.label, Type: got, Value: tt.value}}); err != nil {
+			t.Errorf("guessed type of %q is rejected: %v", tt.value, err)
+		}
+	}
+}
+
+func TestUpdateNormalizesFields(t *testing.T) {
+	v, _ := newTestVault(t)
+
+	cred, err := v.AddCredential(Credential{ServiceName: "Bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.UpdateCredential(cred.ID, func(cred *Credential) error {
+		cred.Fields = append(cred.Fields, CustomField{Label: " Code ", Type: FieldTypeTOTP, Value: "jbsw y3dp ehpk 3pxp"})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := v.Credential(cred.ID)
+	if len(saved.Fields) != 1 || saved.Fields[0].ID == "" || saved.Fields[0].Label != "Code" || !strings.HasPrefix(saved.Fields[0].Value, "otpauth://totp/") {
+		t.Fatalf("updated fields %+v", saved.Fields)
+	}
+
+	err = v.UpdateCredential(cred.ID, func(cred *Credential) error {
+		cred.Fields = append(cred.Fields, CustomField{Label: "Support", Type: FieldTypeURL, Value: "not a url"})
+		return nil
+	})
+	if err == nil {
+		t.Error("saved an invalid URL field")
+	}
+
+	note, err := v.AddSecureNote(SecureNote{Title: "Alarm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.UpdateSecureNote(note.ID, func(note *SecureNote) error {
+		note.Fields = []CustomField{{Label: "Born", Type: FieldTypeDate, Value: "31/01/1990"}}
+		return nil
+	})
+	if err == nil {
+		t.Error("saved an invalid date field")
+	}
+}
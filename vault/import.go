@@ -0,0 +1,474 @@
+package vault
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportedCredential represents a credential from an import file
+type ImportedCredential struct {
+	ServiceName string
+	URL         string
+	Username    string
+	Password    string
+	Notes       string
+	OTPAuth     string        // otpauth:// URI, if the export carries one
+	Fields      []CustomField // columns beyond the format's, labelled by their header
+}
+
+// ParseCSV parses a CSV string and returns imported credentials
+func ParseCSV(csvContent string) ([]ImportedCredential, error) {
+	reader := csv.NewReader(strings.NewReader(csvContent))
+	reader.TrimLeadingSpace = true
+
+	// Read all records
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %v", err)
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("CSV file is empty")
+	}
+
+	// Detect CSV format from header
+	header := records[0]
+	format := detectCSVFormat(header)
+
+	// Parse credentials based on format
+	var credentials []ImportedCredential
+	for i := 1; i < len(records); i++ {
+		record := records[i]
+		cred, err := parseRecord(record, format)
+		if err != nil {
+			// Skip invalid records but continue
+			continue
+		}
+		if cred != nil {
+			cred.Fields = extraFields(header, record, csvColumns[format])
+			credentials = append(credentials, *cred)
+		}
+	}
+
+	if len(credentials) == 0 {
+		return nil, errors.New("no valid credentials found in CSV")
+	}
+
+	return credentials, nil
+}
+
+// CSVFormat represents different browser export formats
+type CSVFormat int
+
+const (
+	FormatChrome CSVFormat = iota
+	FormatFirefox
+	FormatSafari
+	FormatGeneric
+)
+
+// csvColumns is how many leading columns of each format are read into the
+// credential; any further columns become custom fields
+var csvColumns = map[CSVFormat]int{
+	FormatChrome:  4, // name,url,username,password
+	FormatFirefox: 9, // url,username,password and six columns of metadata
+	FormatSafari:  6, // Title,URL,Username,Password,Notes,OTPAuth
+	FormatGeneric: 3, // name or url,username,password
+}
+
+// detectCSVFormat detects the browser format from CSV header
+func detectCSVFormat(header []string) CSVFormat {
+	headerStr := strings.ToLower(strings.Join(header, ","))
+
+	// Chrome's exports and ours start with exactly these columns, whatever
+	// custom field columns follow
+	if len(header) >= 4 && strings.EqualFold(strings.Join(header[:4], ","), "name,url,username,password") {
+		return FormatChrome
+	}
+
+	// The more specific formats are checked first: every header below
+	// contains "username", which also satisfies Chrome's "name" column
+
+	// Firefox format: url,username,password,httpRealm,formActionOrigin,guid,timeCreated,timeLastUsed,timePasswordChanged
+	if strings.Contains(headerStr, "httprealm") || strings.Contains(headerStr, "formactionorigin") {
+		return FormatFirefox
+	}
+
+	// Safari format: Title,URL,Username,Password,Notes,OTPAuth
+	if strings.Contains(headerStr, "title") && strings.Contains(headerStr, "notes") {
+		return FormatSafari
+	}
+
+	// Chrome/Edge format: name,url,username,password
+	if strings.Contains(headerStr, "name") && strings.Contains(headerStr, "url") &&
+		strings.Contains(headerStr, "username") && strings.Contains(headerStr, "password") {
+		return FormatChrome
+	}
+
+	return FormatGeneric
+}
+
+// parseRecord parses a single CSV record based on format
+func parseRecord(record []string, format CSVFormat) (*ImportedCredential, error) {
+	if len(record) < 3 {
+		return nil, errors.New("invalid record")
+	}
+
+	var cred ImportedCredential
+
+	switch format {
+	case FormatChrome:
+		// Chrome/Edge: name,url,username,password
+		if len(record) >= 4 {
+			cred.ServiceName = record[0]
+			cred.URL = record[1]
+			cred.Username = record[2]
+			cred.Password = record[3]
+		}
+
+	case FormatFirefox:
+		// Firefox: url,username,password,...
+		if len(record) >= 3 {
+			cred.URL = record[0]
+			cred.Username = record[1]
+			cred.Password = record[2]
+			cred.ServiceName = extractServiceName(record[0])
+		}
+
+	case FormatSafari:
+		// Safari: Title,URL,Username,Password,Notes,OTPAuth
+		if len(record) >= 4 {
+			cred.ServiceName = record[0]
+			cred.URL = record[1]
+			cred.Username = record[2]
+			cred.Password = record[3]
+			if len(record) >= 5 {
+				cred.Notes = record[4]
+			}
+			if len(record) >= 6 {
+				cred.OTPAuth = record[5]
+			}
+		}
+
+	case FormatGeneric:
+		// Generic format - try to extract what we can
+		// Assume: first column is name/url, second is username, third is password
+		if len(record) >= 3 {
+			cred.ServiceName = extractServiceName(record[0])
+			cred.URL = record[0]
+			cred.Username = record[1]
+			cred.Password = record[2]
+		}
+	}
+
+	// Validate required fields
+	if cred.Username == "" || cred.Password == "" {
+		return nil, errors.New("missing required fields")
+	}
+
+	// If service name is empty, try to extract from URL
+	if cred.ServiceName == "" && cred.URL != "" {
+		cred.ServiceName = extractServiceName(cred.URL)
+	}
+
+	return &cred, nil
+}
+
+// extraFields turns the values in columns beyond the format's known ones
+// into custom fields, labelled by the column header. Empty values are left out.
+func extraFields(header, record []string, known int) []CustomField {
+	var fields []CustomField
+	for i := known; i < len(record) && i < len(header); i++ {
+		label := strings.TrimSpace(header[i])
+		if label == "" || record[i] == "" {
+			continue
+		}
+		fields = append(fields, CustomField{
+			Label: label,
+			Type:  customFieldType(label, record[i]),
+			Value: record[i],
+		})
+	}
+	return fields
+}
+
+// extractServiceName extracts a friendly service name from a URL
+func extractServiceName(url string) string {
+	if url == "" {
+		return "Imported"
+	}
+
+	// Remove protocol
+	url = strings.TrimPrefix(url, "https://")
+	url = strings.TrimPrefix(url, "http://")
+	url = strings.TrimPrefix(url, "www.")
+
+	// Extract domain
+	parts := strings.Split(url, "/")
+	if len(parts) > 0 {
+		domain := parts[0]
+		// Remove port if present
+		domain = strings.Split(domain, ":")[0]
+		// Capitalize first letter
+		if len(domain) > 0 {
+			domain = strings.ToUpper(domain[:1]) + domain[1:]
+		}
+		return domain
+	}
+
+	return "Imported"
+}
+
+// categorizeByURL attempts to categorize a credential based on its URL
+func categorizeByURL(url string) string {
+	urlLower := strings.ToLower(url)
+
+	// Social media
+	if strings.Contains(urlLower, "facebook") || strings.Contains(urlLower, "twitter") ||
+		strings.Contains(urlLower, "instagram") || strings.Contains(urlLower, "linkedin") ||
+		strings.Contains(urlLower, "reddit") || strings.Contains(urlLower, "tiktok") {
+		return "Social"
+	}
+
+	// Finance
+	if strings.Contains(urlLower, "bank") || strings.Contains(urlLower, "paypal") ||
+		strings.Contains(urlLower, "stripe") || strings.Contains(urlLower, "venmo") ||
+		strings.Contains(urlLower, "invest") || strings.Contains(urlLower, "crypto") {
+		return "Finance"
+	}
+
+	// Work-related
+	if strings.Contains(urlLower, "github") || strings.Contains(urlLower, "gitlab") ||
+		strings.Contains(urlLower, "slack") || strings.Contains(urlLower, "jira") ||
+		strings.Contains(urlLower, "confluence") || strings.Contains(urlLower, "office") ||
+		strings.Contains(urlLower, "google.com/drive") || strings.Contains(urlLower, "dropbox") {
+		return "Work"
+	}
+
+	return "Other"
+}
+
+// ImportResult contains the result of an import operation
+type ImportResult struct {
+	TotalProcessed int      `json:"totalProcessed"`
+	Imported       int      `json:"imported"`
+	Skipped        int      `json:"skipped"`
+	Errors         []string `json:"errors"`
+}
+
+// ImportCSV imports credentials from a CSV export of a browser or password
+// manager. Credentials with the URL and username of one already in the
+// vault are skipped.
+func (v *Vault) ImportCSV(csvContent string) (*ImportResult, error) {
+	// Parse CSV
+	importedCreds, err := ParseCSV(csvContent)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var result *ImportResult
+	err = v.update(func(c *Contents) error {
+		result = c.importCredentials(importedCreds)
+
+		// Save vault only if anything was imported
+		if result.Imported == 0 {
+			return errUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// importCredentials adds imported credentials that are not in the vault yet
+func (c *Contents) importCredentials(imported []ImportedCredential) *ImportResult {
+	result := &ImportResult{
+		TotalProcessed: len(imported),
+		Errors:         []string{},
+	}
+
+	// Import each credential
+	for _, importedCred := range imported {
+		// Check if credential already exists (by URL + username)
+		exists := false
+		for _, existingCred := range c.Credentials {
+			if existingCred.URL == importedCred.URL && existingCred.Username == importedCred.Username {
+				exists = true
+				break
+			}
+		}
+
+		if exists {
+			result.Skipped++
+			result.Errors = append(result.Errors, fmt.Sprintf("Skipped duplicate: %s (%s)", importedCred.ServiceName, importedCred.Username))
+			continue
+		}
+
+		// Keep the one-time password only if it can generate codes
+		otpAuth := ""
+		if importedCred.OTPAuth != "" {
+			if otp, err := ParseOTP(importedCred.OTPAuth, importedCred.ServiceName, importedCred.Username); err == nil {
+				otpAuth = otp.URI()
+			} else {
+				result.Errors = append(result.Errors, fmt.Sprintf("Dropped one-time password of %s: %v", importedCred.ServiceName, err))
+			}
+		}
+
+		fields, err := NormalizeCustomFields(importedCred.Fields)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Dropped custom fields of %s: %v", importedCred.ServiceName, err))
+			fields = nil
+		}
+
+		// Create new credential
+		credential := Credential{
+			ID:          uuid.New().String(),
+			ServiceName: importedCred.ServiceName,
+			URL:         importedCred.URL,
+			Username:    importedCred.Username,
+			Password:    importedCred.Password,
+			Category:    categorizeByURL(importedCred.URL),
+			IconURL:     FetchFavicon(importedCred.URL),
+			CreatedAt:   time.Now(),
+			OTPAuth:     otpAuth,
+			Fields:      fields,
+		}
+
+		c.Credentials = append(c.Credentials, credential)
+		result.Imported++
+	}
+
+	return result
+}
+
+// ImportEncryptedBackup adds the items of an encrypted backup made with
+// ExportEncryptedBackup. Backups of this vault open without masterPassword;
+// others need the master password in use when they were exported. Items
+// already in the vault are skipped: credentials with the same URL and
+// username, cards with the same number, notes with the same title and body
+// and identities with the same label and name.
+func (v *Vault) ImportEncryptedBackup(filePath, masterPassword string) (*ImportResult, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return nil, ErrLocked
+	}
+
+	// Load and decrypt backup
+	backup, err := ImportEncryptedBackup(filePath, masterPassword, v.contents.Header, v.dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Import credentials
+	var result *ImportResult
+	err = v.update(func(c *Contents) error {
+		result = &ImportResult{
+			TotalProcessed: len(backup.Credentials) + len(backup.CreditCards) + len(backup.SecureNotes) + len(backup.Identities),
+			Errors:         []string{},
+		}
+
+		for _, cred := range backup.Credentials {
+			// Check if credential already exists (by URL + username)
+			exists := false
+			for _, existingCred := range c.Credentials {
+				if existingCred.URL == cred.URL && existingCred.Username == cred.Username {
+					exists = true
+					break
+				}
+			}
+
+			if exists {
+				result.Skipped++
+				continue
+			}
+
+			// Add to vault under a fresh ID so it cannot clash with an existing item
+			cred.ID = uuid.New().String()
+			c.Credentials = append(c.Credentials, cred)
+			result.Imported++
+		}
+
+		for _, card := range backup.CreditCards {
+			exists := false
+			for _, existingCard := range c.CreditCards {
+				if existingCard.CardNumber == card.CardNumber {
+					exists = true
+					break
+				}
+			}
+
+			if exists {
+				result.Skipped++
+				continue
+			}
+
+			card.ID = uuid.New().String()
+			c.CreditCards = append(c.CreditCards, card)
+			result.Imported++
+		}
+
+		for _, note := range backup.SecureNotes {
+			exists := false
+			for _, existingNote := range c.SecureNotes {
+				if existingNote.Title == note.Title && existingNote.Body == note.Body {
+					exists = true
+					break
+				}
+			}
+
+			if exists {
+				result.Skipped++
+				continue
+			}
+
+			note.ID = uuid.New().String()
+			c.SecureNotes = append(c.SecureNotes, note)
+			result.Imported++
+		}
+
+		for _, identity := range backup.Identities {
+			exists := false
+			for _, existingIdentity := range c.Identities {
+				if existingIdentity.Name == identity.Name &&
+					existingIdentity.FirstName == identity.FirstName &&
+					existingIdentity.LastName == identity.LastName {
+					exists = true
+					break
+				}
+			}
+
+			if exists {
+				result.Skipped++
+				continue
+			}
+
+			identity.ID = uuid.New().String()
+			c.Identities = append(c.Identities, identity)
+			result.Imported++
+		}
+
+		// Save vault only if anything was imported
+		if result.Imported == 0 {
+			return errUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
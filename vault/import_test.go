@@ -0,0 +1,353 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCSVFormats(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want ImportedCredential
+	}{
+		{
+			name: "chrome",
+			csv:  "name,url,username,password\nGitHub,https://github.com/login,octocat,hunter22\n",
+			want: ImportedCredential{ServiceName: "GitHub", URL: "https://github.com/login", Username: "octocat", Password: "hunter22"},
+		},
+		{
+			name: "firefox",
+			csv:  "url,username,password,httpRealm,formActionOrigin,guid\nhttps://www.example.com/login,me,secret,,,{1}\n",
+			want: ImportedCredential{ServiceName: "Example.com", URL: "https://www.example.com/login", Username: "me", Password: "secret"},
+		},
+		{
+			name: "safari",
+			csv:  "Title,URL,Username,Password,Notes,OTPAuth\nBank,https://bank.example,me,secret,pin is 1234,otpauth://totp/Bank:me?secret=JBSWY3DPEHPK3PXP\n",
+			want: ImportedCredential{ServiceName: "Bank", URL: "https://bank.example", Username: "me", Password: "secret", Notes: "pin is 1234", OTPAuth: "otpauth://totp/Bank:me?secret=JBSWY3DPEHPK3PXP"},
+		},
+		{
+			name: "generic",
+			csv:  "site,login,pass\nhttps://example.org,me,secret\n",
+			want: ImportedCredential{ServiceName: "Example.org", URL: "https://example.org", Username: "me", Password: "secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV(tt.csv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVRejectsEmpty(t *testing.T) {
+	if _, err := ParseCSV(""); err == nil {
+		t.Error("parsed an empty file")
+	}
+	if _, err := ParseCSV("name,url,username,password\nGitHub,https://github.com,,\n"); err == nil {
+		t.Error("parsed a file without usable credentials")
+	}
+}
+
+func TestImportCSVSkipsDuplicates(t *testing.T) {
+	v, _ := newTestVault(t)
+
+	csv := "name,url,username,password\n" +
+		"GitHub,https://github.com,octocat,hunter22\n" +
+		"PayPal,https://paypal.com,me,secret\n"
+
+	result, err := v.ImportCSV(csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || result.Skipped != 0 {
+		t.Fatalf("first import: %+v", result)
+	}
+
+	result, err = v.ImportCSV(csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 0 || result.Skipped != 2 {
+		t.Fatalf("second import: %+v", result)
+	}
+
+	credentials, _ := v.Credentials()
+	categories := map[string]string{}
+	for _, cred := range credentials {
+		categories[cred.ServiceName] = cred.Category
+	}
+	if categories["GitHub"] != "Work" || categories["PayPal"] != "Finance" {
+		t.Errorf("imported categories = %v", categories)
+	}
+}
+
+func TestImportCSVKeepsOneTimePasswords(t *testing.T) {
+	v, _ := newTestVault(t)
+
+	csv := "Title,URL,Username,Password,Notes,OTPAuth\n" +
+		"Bank,https://bank.example,me,secret,,otpauth://totp/Bank:me?secret=JBSWY3DPEHPK3PXP\n" +
+		"Shop,https://shop.example,me,secret,,otpauth://totp/Shop:me?secret=broken!\n"
+
+	result, err := v.ImportCSV(csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || len(result.Errors) != 1 {
+		t.Fatalf("import result %+v", result)
+	}
+
+	credentials, _ := v.Credentials()
+	for _, cred := range credentials {
+		switch cred.ServiceName {
+		case "Bank":
+			if _, err := v.OneTimeCode(cred.ID); err != nil {
+				t.Errorf("imported one-time password does not work: %v", err)
+			}
+		case "Shop":
+			if cred.OTPAuth != "" {
+				t.Errorf("kept an invalid one-time password %q", cred.OTPAuth)
+			}
+		}
+	}
+}
+
+func TestImportCSVExtraColumns(t *testing.T) {
+	v, _ := newTestVault(t)
+
+	csv := "name,url,username,password,Security answer,Account number,Recovery,Shipping\n" +
+		"Bank,https://bank.example,me,secret,Fluffy,12-345,otpauth://totp/Bank:me?secret=JBSWY3DPEHPK3PXP,\n"
+
+	result, err := v.ImportCSV(csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 1 || len(result.Errors) != 0 {
+		t.Fatalf("import result %+v", result)
+	}
+
+	credentials, _ := v.Credentials()
+	fields := credentials[0].Fields
+	if len(fields) != 3 {
+		t.Fatalf("imported fields %+v", fields)
+	}
+	want := []struct{ label, kind string }{
+		{"Security answer", FieldTypeHidden},
+		{"Account number", FieldTypeText},
+		{"Recovery", FieldTypeTOTP},
+	}
+	for i, w := range want {
+		if fields[i].Label != w.label || fields[i].Type != w.kind || fields[i].ID == "" {
+			t.Errorf("field %d = %+v, want %s of type %s", i, fields[i], w.label, w.kind)
+		}
+	}
+}
+
+func TestExportCSVRoundTrip(t *testing.T) {
+	v, _ := newTestVault(t)
+	if _, err := v.AddCredential(Credential{ServiceName: "Quoted, \"name\"", URL: "https://example.com", Username: "me", Password: "p,w"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := v.AddCredential(Credential{ServiceName: "Bank", URL: "https://bank.example", Username: "me", Password: "pw", Fields: []CustomField{
+		{Label: "PIN", Type: FieldTypeHidden, Value: "1234"},
+		{Label: "PIN", Type: FieldTypeHidden, Value: "5678"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "export.csv")
+	result, err := v.ExportCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CredentialCount != 2 || result.Format != "csv" {
+		t.Errorf("export result = %+v", result)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), "name,url,username,password,PIN,PIN (2)\n") {
+		t.Fatalf("unexpected header in %q", content)
+	}
+	imported, err := ParseCSV(string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 2 || imported[0].ServiceName != "Quoted, \"name\"" || imported[0].Password != "p,w" {
+		t.Fatalf("re-imported %+v", imported)
+	}
+	if len(imported[0].Fields) != 0 || len(imported[1].Fields) != 2 || imported[1].Fields[1].Value != "5678" {
+		t.Fatalf("re-imported fields %+v and %+v", imported[0].Fields, imported[1].Fields)
+	}
+}
+
+func TestEncryptedBackupRoundTrip(t *testing.T) {
+	v, _ := newTestVault(t)
+	if _, err := v.AddCredential(Credential{ServiceName: "GitHub", URL: "https://github.com", Username: "octocat"}); err != nil {
+		t.Fatal(err)
+	}
+	note, err := v.AddSecureNote(SecureNote{Title: "License", Body: "ABCD-EFGH"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := v.AddCreditCard(CreditCard{CardName: "Personal", CardNumber: "4111111111111111"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := v.AddIdentity(Identity{Name: "Home", FirstName: "Jane", Address: Address{PostalCode: "12345"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.vzb")
+	exported, err := v.ExportEncryptedBackup(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported.CredentialCount != 1 || exported.CreditCardCount != 1 || exported.SecureNoteCount != 1 || exported.IdentityCount != 1 {
+		t.Errorf("export result = %+v", exported)
+	}
+
+	// The same vault already holds everything
+	result, err := v.ImportEncryptedBackup(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 0 || result.Skipped != 4 {
+		t.Fatalf("import into the same vault: %+v", result)
+	}
+
+	// After deleting them the backup brings them back
+	credentials, _ := v.Credentials()
+	if err := v.DeleteCredential(credentials[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.DeleteSecureNote(note.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.DeleteCreditCard(card.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.DeleteIdentity(identity.ID); err != nil {
+		t.Fatal(err)
+	}
+	result, err = v.ImportEncryptedBackup(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 4 {
+		t.Fatalf("import after delete: %+v", result)
+	}
+	if notes, _ := v.SecureNotes(); len(notes) != 1 || notes[0].Body != "ABCD-EFGH" {
+		t.Fatalf("restored notes %+v", notes)
+	}
+	if cards, _ := v.CreditCards(); len(cards) != 1 || cards[0].CardNumber != "4111111111111111" {
+		t.Fatalf("restored cards %+v", cards)
+	}
+	if identities, _ := v.Identities(); len(identities) != 1 || identities[0].Address.PostalCode != "12345" {
+		t.Fatalf("restored identities %+v", identities)
+	}
+
+	// Another vault has a different data key and needs the password
+	other, _ := newTestVault(t)
+	if _, err := other.ImportEncryptedBackup(path, ""); err == nil {
+		t.Error("imported another vault's backup without its password")
+	}
+	if _, err := other.ImportEncryptedBackup(path, "wrong password"); err == nil {
+		t.Error("imported another vault's backup with the wrong password")
+	}
+}
+
+func TestEncryptedBackupIntoNewVault(t *testing.T) {
+	dir := t.TempDir()
+	v := New(newTestStorage(t, dir))
+	if err := v.Create(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.AddCredential(Credential{ServiceName: "GitHub", URL: "https://github.com", Username: "octocat"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.AddSecureNote(SecureNote{Title: "License", Body: "ABCD-EFGH"}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "backup.vault")
+	if _, err := v.ExportEncryptedBackup(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// The vault file is lost; a new vault with the same password restores the backup
+	v.Lock()
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	fresh := New(newTestStorage(t, t.TempDir()))
+	if err := fresh.Create(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Lock()
+
+	result, err := fresh.ImportEncryptedBackup(path, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 {
+		t.Fatalf("import result %+v", result)
+	}
+	credentials, _ := fresh.Credentials()
+	if len(credentials) != 1 || credentials[0].Username != "octocat" {
+		t.Fatalf("restored credentials %+v", credentials)
+	}
+}
+
+func TestImportCredentialOnlyBackup(t *testing.T) {
+	v, _ := newTestVault(t)
+
+	// Backups from before secure notes are a bare array of credentials,
+	// encrypted with the data key and without a header
+	data, err := Encrypt([]byte(`[{"id":"a","serviceName":"Old","url":"https://old.example","username":"me"}]`), v.dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "old.vault")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := v.ImportEncryptedBackup(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 1 {
+		t.Fatalf("import result %+v", result)
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	password, err := GeneratePassword(PasswordGeneratorOptions{Length: 20, IncludeNumbers: true, ExcludeAmbiguous: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(password) != 20 || strings.Trim(password, "23456789") != "" {
+		t.Errorf("got %q, want 20 unambiguous digits", password)
+	}
+
+	if _, err := GeneratePassword(PasswordGeneratorOptions{Length: 20}); err == nil {
+		t.Error("generated a password without any character types")
+	}
+
+	if password, _ := GenerateStrongPassword(4); len(password) != 16 {
+		t.Errorf("short strong password has length %d, want the default 16", len(password))
+	}
+}
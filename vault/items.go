@@ -0,0 +1,400 @@
+package vault
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrCredentialNotFound is returned for an unknown credential ID
+	ErrCredentialNotFound = errors.New("credential not found")
+
+	// ErrCreditCardNotFound is returned for an unknown credit card ID
+	ErrCreditCardNotFound = errors.New("credit card not found")
+
+	// ErrSecureNoteNotFound is returned for an unknown secure note ID
+	ErrSecureNoteNotFound = errors.New("secure note not found")
+
+	// ErrIdentityNotFound is returned for an unknown identity ID
+	ErrIdentityNotFound = errors.New("identity not found")
+)
+
+// ============ Credentials ============
+
+// Credentials returns a copy of all credentials
+func (v *Vault) Credentials() ([]Credential, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return nil, ErrLocked
+	}
+	return append([]Credential{}, v.contents.Credentials...), nil
+}
+
+// Credential returns the credential with the given ID
+func (v *Vault) Credential(id string) (Credential, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return Credential{}, ErrLocked
+	}
+	for _, cred := range v.contents.Credentials {
+		if cred.ID == id {
+			return cred, nil
+		}
+	}
+	return Credential{}, ErrCredentialNotFound
+}
+
+// AddCredential stores a new credential under a fresh ID and returns it as
+// stored. Its creation time and icon are set here.
+func (v *Vault) AddCredential(cred Credential) (Credential, error) {
+	fields, err := NormalizeCustomFields(cred.Fields)
+	if err != nil {
+		return Credential{}, err
+	}
+	cred.Fields = fields
+	if err := cred.normalizeOTPAuth(); err != nil {
+		return Credential{}, err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	cred.ID = uuid.New().String()
+	cred.IconURL = FetchFavicon(cred.URL)
+	cred.CreatedAt = time.Now()
+
+	err = v.update(func(c *Contents) error {
+		c.Credentials = append(c.Credentials, cred)
+		return nil
+	})
+	if err != nil {
+		return Credential{}, err
+	}
+	return cred, nil
+}
+
+// UpdateCredential applies change to the credential with the given ID and
+// saves it. change may run twice if the vault has to be reloaded first.
+func (v *Vault) UpdateCredential(id string, change func(cred *Credential) error) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.update(func(c *Contents) error {
+		for i := range c.Credentials {
+			if c.Credentials[i].ID != id {
+				continue
+			}
+			cred := &c.Credentials[i]
+			otpAuth := cred.OTPAuth
+			fields := append([]CustomField{}, cred.Fields...)
+			if err := change(cred); err != nil {
+				return err
+			}
+			if err := normalizeChangedFields(&cred.Fields, fields); err != nil {
+				return err
+			}
+			// Only a changed one-time password is checked, so older
+			// entries stay editable
+			if cred.OTPAuth != otpAuth {
+				return cred.normalizeOTPAuth()
+			}
+			return nil
+		}
+		return ErrCredentialNotFound
+	})
+}
+
+// DeleteCredential removes a credential
+func (v *Vault) DeleteCredential(id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.update(func(c *Contents) error {
+		for i, cred := range c.Credentials {
+			if cred.ID == id {
+				c.Credentials = append(c.Credentials[:i], c.Credentials[i+1:]...)
+				return nil
+			}
+		}
+		return ErrCredentialNotFound
+	})
+}
+
+// ============ Credit Cards ============
+
+// CreditCards returns a copy of all credit cards
+func (v *Vault) CreditCards() ([]CreditCard, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return nil, ErrLocked
+	}
+	return append([]CreditCard{}, v.contents.CreditCards...), nil
+}
+
+// CreditCard returns the credit card with the given ID
+func (v *Vault) CreditCard(id string) (CreditCard, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return CreditCard{}, ErrLocked
+	}
+	for _, card := range v.contents.CreditCards {
+		if card.ID == id {
+			return card, nil
+		}
+	}
+	return CreditCard{}, ErrCreditCardNotFound
+}
+
+// AddCreditCard stores a new credit card under a fresh ID and returns it as stored
+func (v *Vault) AddCreditCard(card CreditCard) (CreditCard, error) {
+	fields, err := NormalizeCustomFields(card.Fields)
+	if err != nil {
+		return CreditCard{}, err
+	}
+	card.Fields = fields
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	card.ID = uuid.New().String()
+	card.CreatedAt = time.Now()
+
+	err = v.update(func(c *Contents) error {
+		c.CreditCards = append(c.CreditCards, card)
+		return nil
+	})
+	if err != nil {
+		return CreditCard{}, err
+	}
+	return card, nil
+}
+
+// UpdateCreditCard applies change to the credit card with the given ID and
+// saves it. change may run twice if the vault has to be reloaded first.
+func (v *Vault) UpdateCreditCard(id string, change func(card *CreditCard) error) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.update(func(c *Contents) error {
+		for i := range c.CreditCards {
+			if c.CreditCards[i].ID == id {
+				fields := append([]CustomField{}, c.CreditCards[i].Fields...)
+				if err := change(&c.CreditCards[i]); err != nil {
+					return err
+				}
+				return normalizeChangedFields(&c.CreditCards[i].Fields, fields)
+			}
+		}
+		return ErrCreditCardNotFound
+	})
+}
+
+// DeleteCreditCard removes a credit card
+func (v *Vault) DeleteCreditCard(id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.update(func(c *Contents) error {
+		for i, card := range c.CreditCards {
+			if card.ID == id {
+				c.CreditCards = append(c.CreditCards[:i], c.CreditCards[i+1:]...)
+				return nil
+			}
+		}
+		return ErrCreditCardNotFound
+	})
+}
+
+// ============ Secure Notes ============
+
+// SecureNotes returns a copy of all secure notes
+func (v *Vault) SecureNotes() ([]SecureNote, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return nil, ErrLocked
+	}
+	return append([]SecureNote{}, v.contents.SecureNotes...), nil
+}
+
+// SecureNote returns the secure note with the given ID
+func (v *Vault) SecureNote(id string) (SecureNote, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return SecureNote{}, ErrLocked
+	}
+	for _, note := range v.contents.SecureNotes {
+		if note.ID == id {
+			return note, nil
+		}
+	}
+	return SecureNote{}, ErrSecureNoteNotFound
+}
+
+// AddSecureNote stores a new secure note under a fresh ID and returns it as stored
+func (v *Vault) AddSecureNote(note SecureNote) (SecureNote, error) {
+	fields, err := NormalizeCustomFields(note.Fields)
+	if err != nil {
+		return SecureNote{}, err
+	}
+	note.Fields = fields
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	note.ID = uuid.New().String()
+	note.CreatedAt = time.Now()
+	note.UpdatedAt = note.CreatedAt
+
+	err = v.update(func(c *Contents) error {
+		c.SecureNotes = append(c.SecureNotes, note)
+		return nil
+	})
+	if err != nil {
+		return SecureNote{}, err
+	}
+	return note, nil
+}
+
+// UpdateSecureNote applies change to the secure note with the given ID,
+// stamps its update time and saves it. change may run twice if the vault
+// has to be reloaded first.
+func (v *Vault) UpdateSecureNote(id string, change func(note *SecureNote) error) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.update(func(c *Contents) error {
+		for i := range c.SecureNotes {
+			if c.SecureNotes[i].ID == id {
+				fields := append([]CustomField{}, c.SecureNotes[i].Fields...)
+				if err := change(&c.SecureNotes[i]); err != nil {
+					return err
+				}
+				if err := normalizeChangedFields(&c.SecureNotes[i].Fields, fields); err != nil {
+					return err
+				}
+				c.SecureNotes[i].UpdatedAt = time.Now()
+				return nil
+			}
+		}
+		return ErrSecureNoteNotFound
+	})
+}
+
+// DeleteSecureNote removes a secure note
+func (v *Vault) DeleteSecureNote(id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.update(func(c *Contents) error {
+		for i, note := range c.SecureNotes {
+			if note.ID == id {
+				c.SecureNotes = append(c.SecureNotes[:i], c.SecureNotes[i+1:]...)
+				return nil
+			}
+		}
+		return ErrSecureNoteNotFound
+	})
+}
+
+// ============ Identities ============
+
+// Identities returns a copy of all identities
+func (v *Vault) Identities() ([]Identity, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return nil, ErrLocked
+	}
+	return append([]Identity{}, v.contents.Identities...), nil
+}
+
+// Identity returns the identity with the given ID
+func (v *Vault) Identity(id string) (Identity, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return Identity{}, ErrLocked
+	}
+	for _, identity := range v.contents.Identities {
+		if identity.ID == id {
+			return identity, nil
+		}
+	}
+	return Identity{}, ErrIdentityNotFound
+}
+
+// AddIdentity stores a new identity under a fresh ID and returns it as stored
+func (v *Vault) AddIdentity(identity Identity) (Identity, error) {
+	fields, err := NormalizeCustomFields(identity.Fields)
+	if err != nil {
+		return Identity{}, err
+	}
+	identity.Fields = fields
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	identity.ID = uuid.New().String()
+	identity.CreatedAt = time.Now()
+
+	err = v.update(func(c *Contents) error {
+		c.Identities = append(c.Identities, identity)
+		return nil
+	})
+	if err != nil {
+		return Identity{}, err
+	}
+	return identity, nil
+}
+
+// UpdateIdentity applies change to the identity with the given ID and saves
+// it. change may run twice if the vault has to be reloaded first.
+func (v *Vault) UpdateIdentity(id string, change func(identity *Identity) error) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.update(func(c *Contents) error {
+		for i := range c.Identities {
+			if c.Identities[i].ID == id {
+				fields := append([]CustomField{}, c.Identities[i].Fields...)
+				if err := change(&c.Identities[i]); err != nil {
+					return err
+				}
+				return normalizeChangedFields(&c.Identities[i].Fields, fields)
+			}
+		}
+		return ErrIdentityNotFound
+	})
+}
+
+// DeleteIdentity removes an identity
+func (v *Vault) DeleteIdentity(id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.update(func(c *Contents) error {
+		for i, identity := range c.Identities {
+			if identity.ID == id {
+				c.Identities = append(c.Identities[:i], c.Identities[i+1:]...)
+				return nil
+			}
+		}
+		return ErrIdentityNotFound
+	})
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package vault
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// acquireFileLock takes a non-blocking exclusive flock on path. The kernel
+// drops the lock automatically if the process dies.
+func acquireFileLock(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, &VaultInUseError{PID: lockOwner(path)}
+		}
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// releaseFileLock drops the flock and closes the lock file
+func releaseFileLock(file *os.File) error {
+	syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	return file.Close()
+}
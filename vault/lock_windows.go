@@ -0,0 +1,44 @@
+//go:build windows
+
+package vault
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const errorSharingViolation syscall.Errno = 32
+
+// acquireFileLock opens path for writing while denying write access to
+// everyone else, which acts as an exclusive lock until the handle is closed.
+// Other processes can still open the file for reading to learn the owner.
+func acquireFileLock(path string) (*os.File, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := syscall.CreateFile(
+		pathPtr,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		syscall.FILE_SHARE_READ,
+		nil,
+		syscall.OPEN_ALWAYS,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0,
+	)
+	if err != nil {
+		if errors.Is(err, errorSharingViolation) {
+			return nil, &VaultInUseError{PID: lockOwner(path)}
+		}
+		return nil, err
+	}
+
+	return os.NewFile(uintptr(handle), path), nil
+}
+
+// releaseFileLock closes the lock file handle, which releases the lock
+func releaseFileLock(file *os.File) error {
+	return file.Close()
+}
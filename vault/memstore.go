@@ -0,0 +1,248 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MemoryStore is a VaultStore that keeps the encoded vault in memory. It
+// stores exactly what StorageManager writes to disk, so it exercises the same
+// encryption and conflict handling without touching the file system.
+type MemoryStore struct {
+	name     string
+	raw      []byte // encoded vault container, nil if there is no vault
+	backups  []memoryBackup
+	maxCount int
+	locked   bool
+	readOnly bool
+	revision string
+	records  *recordSet
+	nextID   int
+}
+
+// memoryBackup is one earlier generation held by a MemoryStore
+type memoryBackup struct {
+	id        string
+	createdAt time.Time
+	raw       []byte
+}
+
+// NewMemoryStore creates an empty in-memory vault store
+func NewMemoryStore(name string) *MemoryStore {
+	return &MemoryStore{
+		name:     name,
+		maxCount: DefaultBackupPolicy().MaxCount,
+	}
+}
+
+// Location returns the store's name
+func (ms *MemoryStore) Location() string {
+	return "memory:" + ms.name
+}
+
+// VaultExists reports whether a vault has been saved
+func (ms *MemoryStore) VaultExists() bool {
+	return ms.raw != nil
+}
+
+// LoadHeader returns the header of the stored vault
+func (ms *MemoryStore) LoadHeader() (*VaultHeader, error) {
+	file, err := ms.readVaultFile()
+	if err != nil {
+		return nil, err
+	}
+	return &file.VaultHeader, nil
+}
+
+// readVaultFile decodes the stored vault container
+func (ms *MemoryStore) readVaultFile() (*vaultFile, error) {
+	if ms.raw == nil {
+		return nil, errors.New("vault does not exist")
+	}
+	return decodeVaultFile(ms.raw)
+}
+
+// LoadVault decrypts the stored vault
+func (ms *MemoryStore) LoadVault(dataKey []byte) (*Contents, error) {
+	file, err := ms.readVaultFile()
+	if err != nil {
+		return nil, err
+	}
+
+	vault, records, err := decryptVault(file, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ms.revision = fileRevision(ms.raw)
+	ms.records = records
+	return vault, nil
+}
+
+// SaveVault encrypts and stores the vault
+func (ms *MemoryStore) SaveVault(vault *Contents, dataKey []byte) error {
+	file, records, err := sealVaultFile(vault, dataKey, ms.records)
+	if err != nil {
+		return err
+	}
+
+	if err := ms.writeVaultFile(file); err != nil {
+		return err
+	}
+
+	ms.records = records
+	return nil
+}
+
+// SaveHeader replaces the vault header while keeping the encrypted contents
+func (ms *MemoryStore) SaveHeader(header *VaultHeader) error {
+	file, err := ms.readVaultFile()
+	if err != nil {
+		return err
+	}
+	if err := replaceVaultHeader(file, header); err != nil {
+		return err
+	}
+	return ms.writeVaultFile(file)
+}
+
+// writeVaultFile stores a vault container, keeping the replaced one as a backup
+func (ms *MemoryStore) writeVaultFile(file *vaultFile) error {
+	if err := ms.checkWritable(); err != nil {
+		return err
+	}
+
+	current, _ := ms.Revision()
+	if current != ms.revision {
+		return ErrVaultConflict
+	}
+
+	raw, err := encodeVaultFile(file)
+	if err != nil {
+		return err
+	}
+
+	if ms.raw != nil && ms.maxCount > 0 {
+		ms.nextID++
+		backup := memoryBackup{
+			id:        fmt.Sprintf("generation-%d", ms.nextID),
+			createdAt: time.Now(),
+			raw:       ms.raw,
+		}
+		ms.backups = append([]memoryBackup{backup}, ms.backups...)
+		if len(ms.backups) > ms.maxCount {
+			ms.backups = ms.backups[:ms.maxCount]
+		}
+	}
+
+	ms.raw = raw
+	ms.revision = fileRevision(raw)
+	return nil
+}
+
+// Revision identifies the stored vault
+func (ms *MemoryStore) Revision() (string, error) {
+	if ms.raw == nil {
+		return "", nil
+	}
+	return fileRevision(ms.raw), nil
+}
+
+// Replace swaps in new vault contents as if another process had written
+// them, bypassing the lock and conflict checks
+func (ms *MemoryStore) Replace(raw []byte) {
+	ms.raw = raw
+}
+
+// Lock takes the store's write lock
+func (ms *MemoryStore) Lock() error {
+	ms.locked = true
+	ms.readOnly = false
+	return nil
+}
+
+// Unlock releases the write lock
+func (ms *MemoryStore) Unlock() error {
+	ms.locked = false
+	ms.readOnly = false
+	return nil
+}
+
+// SetReadOnly marks the vault as opened without the lock; all writes fail
+func (ms *MemoryStore) SetReadOnly(readOnly bool) {
+	ms.readOnly = readOnly
+}
+
+// IsReadOnly reports whether the vault was opened without the lock
+func (ms *MemoryStore) IsReadOnly() bool {
+	return ms.readOnly
+}
+
+// checkWritable rejects writes unless the lock is held
+func (ms *MemoryStore) checkWritable() error {
+	if ms.readOnly {
+		return errors.New("vault is open read-only")
+	}
+	if !ms.locked {
+		return errors.New("vault is not locked for writing")
+	}
+	return nil
+}
+
+// DamagedRecords returns the IDs of records in the loaded vault that could not be read
+func (ms *MemoryStore) DamagedRecords() []string {
+	ids := []string{}
+	if ms.records == nil {
+		return ids
+	}
+	for _, record := range ms.records.damaged {
+		ids = append(ids, record.entry.ID)
+	}
+	return ids
+}
+
+// DeleteVault drops the vault and its backups
+func (ms *MemoryStore) DeleteVault() error {
+	if err := ms.checkWritable(); err != nil {
+		return err
+	}
+
+	ms.raw = nil
+	ms.backups = nil
+	ms.revision = ""
+	ms.records = nil
+	return nil
+}
+
+// ListBackups returns the earlier generations, newest first
+func (ms *MemoryStore) ListBackups() ([]BackupInfo, error) {
+	backups := []BackupInfo{}
+	for _, backup := range ms.backups {
+		backups = append(backups, BackupInfo{
+			ID:        backup.id,
+			CreatedAt: backup.createdAt,
+		})
+	}
+	return backups, nil
+}
+
+// LoadBackup decrypts an earlier generation with the vault data key
+func (ms *MemoryStore) LoadBackup(id string, dataKey []byte) (*Contents, error) {
+	for _, backup := range ms.backups {
+		if backup.id != id {
+			continue
+		}
+
+		file, err := decodeVaultFile(backup.raw)
+		if err != nil {
+			return nil, err
+		}
+		vault, _, err := decryptVault(file, dataKey)
+		if err != nil {
+			return nil, errors.New("backup cannot be decrypted with the current vault key")
+		}
+		return vault, nil
+	}
+	return nil, errors.New("backup not found")
+}
@@ -0,0 +1,306 @@
+package vault
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// One-time password kinds, as named in otpauth:// URIs
+const (
+	OTPTypeTOTP = "totp" // time-based, RFC 6238
+	OTPTypeHOTP = "hotp" // counter-based, RFC 4226
+)
+
+// ErrNoOTP is returned when asking for a code of a credential without a one-time password
+var ErrNoOTP = errors.New("credential has no one-time password")
+
+// OTP is a one-time password generator as described by an otpauth:// URI,
+// the format authenticator apps scan from QR codes
+type OTP struct {
+	Type      string // OTPTypeTOTP or OTPTypeHOTP
+	Issuer    string
+	Account   string
+	Secret    []byte
+	Algorithm string // SHA1, SHA256 or SHA512
+	Digits    int    // 6 to 8
+	Period    int    // seconds a TOTP code is valid
+	Counter   uint64 // next HOTP counter value
+}
+
+// OneTimeCode is a generated one-time password. SecondsRemaining and Period
+// are zero for HOTP codes, which stay valid until they are used.
+type OneTimeCode struct {
+	Code             string `json:"code"`
+	Type             string `json:"type"`
+	SecondsRemaining int    `json:"secondsRemaining"`
+	Period           int    `json:"period"`
+}
+
+// ParseOTPAuthURI parses an otpauth://totp/... or otpauth://hotp/... URI.
+// Missing parameters take the defaults authenticator apps use: SHA1, six
+// digits and a 30 second period.
+func ParseOTPAuthURI(uri string) (*OTP, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil || !strings.EqualFold(u.Scheme, "otpauth") {
+		return nil, errors.New("not an otpauth:// URI")
+	}
+
+	otp := &OTP{
+		Type:      strings.ToLower(u.Host),
+		Algorithm: "SHA1",
+		Digits:    6,
+		Period:    30,
+	}
+	if otp.Type != OTPTypeTOTP && otp.Type != OTPTypeHOTP {
+		return nil, fmt.Errorf("unsupported one-time password type %q", u.Host)
+	}
+
+	// The label is "issuer:account" or just "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		otp.Issuer = strings.TrimSpace(issuer)
+		otp.Account = strings.TrimSpace(account)
+	} else {
+		otp.Account = label
+	}
+
+	query := u.Query()
+	if issuer := query.Get("issuer"); issuer != "" {
+		otp.Issuer = issuer
+	}
+
+	if otp.Secret, err = decodeOTPSecret(query.Get("secret")); err != nil {
+		return nil, err
+	}
+
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		otp.Algorithm = strings.ToUpper(algorithm)
+	}
+	if digits := query.Get("digits"); digits != "" {
+		if otp.Digits, err = strconv.Atoi(digits); err != nil {
+			return nil, errors.New("invalid number of digits")
+		}
+	}
+	if period := query.Get("period"); period != "" && otp.Type == OTPTypeTOTP {
+		if otp.Period, err = strconv.Atoi(period); err != nil {
+			return nil, errors.New("invalid period")
+		}
+	}
+	if otp.Type == OTPTypeHOTP {
+		otp.Period = 0
+		if otp.Counter, err = strconv.ParseUint(query.Get("counter"), 10, 64); err != nil {
+			return nil, errors.New("HOTP URI needs a counter")
+		}
+	}
+
+	if err := otp.validate(); err != nil {
+		return nil, err
+	}
+	return otp, nil
+}
+
+// ParseOTP reads a one-time password as users enter it: an otpauth:// URI,
+// or a bare base32 secret as sites show it for manual entry, which sets up
+// a TOTP for issuer and account
+func ParseOTP(value, issuer, account string) (*OTP, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(strings.ToLower(value), "otpauth:") {
+		return ParseOTPAuthURI(value)
+	}
+	return NewTOTP(value, issuer, account)
+}
+
+// NewTOTP creates a TOTP generator with the usual defaults from a base32
+// secret, as sites show it for entering by hand
+func NewTOTP(secret, issuer, account string) (*OTP, error) {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &OTP{
+		Type:      OTPTypeTOTP,
+		Issuer:    issuer,
+		Account:   account,
+		Secret:    key,
+		Algorithm: "SHA1",
+		Digits:    6,
+		Period:    30,
+	}, nil
+}
+
+// decodeOTPSecret decodes a base32 secret, tolerating the spaces, lower case
+// and missing padding common in secrets shown for manual entry
+func decodeOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	if secret == "" {
+		return nil, errors.New("one-time password secret is missing")
+	}
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, errors.New("one-time password secret is not valid base32")
+	}
+	return key, nil
+}
+
+// validate rejects parameters that no authenticator would produce codes for
+func (o *OTP) validate() error {
+	if o.hash() == nil {
+		return fmt.Errorf("unsupported one-time password algorithm %q", o.Algorithm)
+	}
+	if o.Digits < 6 || o.Digits > 8 {
+		return errors.New("one-time passwords must have 6 to 8 digits")
+	}
+	if o.Type == OTPTypeTOTP && (o.Period <= 0 || o.Period > 300) {
+		return errors.New("one-time password period must be between 1 and 300 seconds")
+	}
+	return nil
+}
+
+// hash returns the HMAC hash function for the algorithm, or nil if unknown
+func (o *OTP) hash() func() hash.Hash {
+	switch o.Algorithm {
+	case "SHA1":
+		return sha1.New
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	}
+	return nil
+}
+
+// URI encodes the generator as an otpauth:// URI
+func (o *OTP) URI() string {
+	label := o.Account
+	if o.Issuer != "" {
+		label = o.Issuer + ":" + o.Account
+	}
+
+	query := url.Values{}
+	query.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(o.Secret))
+	if o.Issuer != "" {
+		query.Set("issuer", o.Issuer)
+	}
+	query.Set("algorithm", o.Algorithm)
+	query.Set("digits", strconv.Itoa(o.Digits))
+	if o.Type == OTPTypeHOTP {
+		query.Set("counter", strconv.FormatUint(o.Counter, 10))
+	} else {
+		query.Set("period", strconv.Itoa(o.Period))
+	}
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     o.Type,
+		Path:     "/" + label,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// Code returns the TOTP code valid at the given time, or the HOTP code for
+// the current counter value
+func (o *OTP) Code(at time.Time) OneTimeCode {
+	if o.Type == OTPTypeHOTP {
+		return OneTimeCode{
+			Code: o.generate(o.Counter),
+			Type: OTPTypeHOTP,
+		}
+	}
+
+	period := int64(o.Period)
+	step := at.Unix() / period
+	return OneTimeCode{
+		Code:             o.generate(uint64(step)),
+		Type:             OTPTypeTOTP,
+		SecondsRemaining: int(period - at.Unix()%period),
+		Period:           o.Period,
+	}
+}
+
+// generate computes the HOTP value for a counter (RFC 4226 section 5.3)
+func (o *OTP) generate(counter uint64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+
+	mac := hmac.New(o.hash(), o.Secret)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for i := 0; i < o.Digits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", o.Digits, value%modulus)
+}
+
+// OneTimeCode generates the current one-time password of a credential.
+// Using an HOTP code advances the credential's counter, which is saved.
+func (v *Vault) OneTimeCode(id string) (OneTimeCode, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var code OneTimeCode
+	err := v.update(func(c *Contents) error {
+		for i := range c.Credentials {
+			cred := &c.Credentials[i]
+			if cred.ID != id {
+				continue
+			}
+			if cred.OTPAuth == "" {
+				return ErrNoOTP
+			}
+
+			otp, err := ParseOTPAuthURI(cred.OTPAuth)
+			if err != nil {
+				return err
+			}
+			code = otp.Code(time.Now())
+			if otp.Type != OTPTypeHOTP {
+				return errUnchanged
+			}
+
+			otp.Counter++
+			cred.OTPAuth = otp.URI()
+			return nil
+		}
+		return ErrCredentialNotFound
+	})
+	if err != nil {
+		return OneTimeCode{}, err
+	}
+
+	return code, nil
+}
+
+// normalizeOTPAuth checks a credential's one-time password before it is
+// stored, keeping it as an otpauth:// URI
+func (cred *Credential) normalizeOTPAuth() error {
+	if strings.TrimSpace(cred.OTPAuth) == "" {
+		cred.OTPAuth = ""
+		return nil
+	}
+	otp, err := ParseOTP(cred.OTPAuth, cred.ServiceName, cred.Username)
+	if err != nil {
+		return err
+	}
+	cred.OTPAuth = otp.URI()
+	return nil
+}
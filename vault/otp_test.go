@@ -0,0 +1,236 @@
+package vault
+
+import (
+	"encoding/base32"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// otpauthURI builds a URI with the secret base32 encoded
+func otpauthURI(kind, secret, params string) string {
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(secret))
+	return "otpauth://" + kind + "/Example:alice@example.com?secret=" + encoded + params
+}
+
+func TestTOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B
+	secrets := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	tests := []struct {
+		unix      int64
+		algorithm string
+		want      string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{20000000000, "SHA512", "47863826"},
+	}
+
+	for _, tt := range tests {
+		otp, err := ParseOTPAuthURI(otpauthURI("totp", secrets[tt.algorithm], "&digits=8&algorithm="+tt.algorithm))
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := otp.Code(time.Unix(tt.unix, 0))
+		if code.Code != tt.want {
+			t.Errorf("%s at %d = %s, want %s", tt.algorithm, tt.unix, code.Code, tt.want)
+		}
+	}
+}
+
+func TestTOTPPeriod(t *testing.T) {
+	otp, err := ParseOTPAuthURI(otpauthURI("totp", "12345678901234567890", "&period=60"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := otp.Code(time.Unix(130, 0))
+	if code.Period != 60 || code.SecondsRemaining != 50 {
+		t.Errorf("got period %d with %d seconds left, want 60 and 50", code.Period, code.SecondsRemaining)
+	}
+	if len(code.Code) != 6 {
+		t.Errorf("code %q does not have the default 6 digits", code.Code)
+	}
+	if later := otp.Code(time.Unix(179, 0)); later.Code != code.Code {
+		t.Error("code changed within its period")
+	}
+}
+
+func TestHOTPVectors(t *testing.T) {
+	// RFC 4226 appendix D
+	want := []string{"755224", "287082", "359152", "969429", "338314"}
+
+	for counter, code := range want {
+		otp, err := ParseOTPAuthURI(otpauthURI("hotp", "12345678901234567890", "&counter="+string(rune('0'+counter))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := otp.Code(time.Now()); got.Code != code || got.SecondsRemaining != 0 {
+			t.Errorf("counter %d = %+v, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestParseOTPAuthURI(t *testing.T) {
+	otp, err := ParseOTPAuthURI("otpauth://totp/ACME%20Co:john.doe@email.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&issuer=ACME%20Co&algorithm=SHA256&digits=7&period=45")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otp.Type != OTPTypeTOTP || otp.Issuer != "ACME Co" || otp.Account != "john.doe@email.com" ||
+		otp.Algorithm != "SHA256" || otp.Digits != 7 || otp.Period != 45 {
+		t.Fatalf("parsed %+v", otp)
+	}
+
+	again, err := ParseOTPAuthURI(otp.URI())
+	if err != nil {
+		t.Fatalf("URI() does not parse: %v", err)
+	}
+	if again.URI() != otp.URI() || string(again.Secret) != string(otp.Secret) {
+		t.Errorf("round trip changed %q to %q", otp.URI(), again.URI())
+	}
+
+	invalid := []string{
+		"https://example.com",
+		"otpauth://totp/x",
+		"otpauth://totp/x?secret=not-base32!",
+		"otpauth://motp/x?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&digits=9",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&period=0",
+		"otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP",
+	}
+	for _, uri := range invalid {
+		if _, err := ParseOTPAuthURI(uri); err == nil {
+			t.Errorf("accepted %s", uri)
+		}
+	}
+}
+
+func TestNewTOTPFromManualSecret(t *testing.T) {
+	otp, err := NewTOTP("jbsw y3dp ehpk 3pxp", "GitHub", "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(otp.Secret) != "Hello!\xde\xad\xbe\xef" {
+		t.Errorf("decoded secret %q", otp.Secret)
+	}
+	if !strings.HasPrefix(otp.URI(), "otpauth://totp/GitHub:octocat?") {
+		t.Errorf("URI = %s", otp.URI())
+	}
+}
+
+func TestVaultOneTimeCode(t *testing.T) {
+	v, store := newTestVault(t)
+
+	plain, _ := v.AddCredential(Credential{ServiceName: "no 2FA"})
+	if _, err := v.OneTimeCode(plain.ID); !errors.Is(err, ErrNoOTP) {
+		t.Errorf("code of a credential without OTP: got %v", err)
+	}
+
+	totp, _ := v.AddCredential(Credential{OTPAuth: otpauthURI("totp", "12345678901234567890", "")})
+	code, err := v.OneTimeCode(totp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code.Type != OTPTypeTOTP || len(code.Code) != 6 || code.SecondsRemaining < 1 || code.SecondsRemaining > 30 {
+		t.Errorf("TOTP code %+v", code)
+	}
+
+	// Every HOTP code is used once; the counter moves on and is saved
+	hotp, _ := v.AddCredential(Credential{OTPAuth: otpauthURI("hotp", "12345678901234567890", "&counter=0")})
+	for _, want := range []string{"755224", "287082"} {
+		code, err := v.OneTimeCode(hotp.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code.Code != want {
+			t.Errorf("HOTP code %s, want %s", code.Code, want)
+		}
+	}
+	saved, _ := v.Credential(hotp.ID)
+	if !strings.Contains(saved.OTPAuth, "counter=2") {
+		t.Errorf("saved URI %s does not carry the next counter", saved.OTPAuth)
+	}
+
+	// A code whose counter cannot be saved is not handed out or used up
+	store.SetReadOnly(true)
+	if _, err := v.OneTimeCode(hotp.ID); err == nil {
+		t.Error("got an HOTP code from a read-only vault")
+	}
+	if unsaved, _ := v.Credential(hotp.ID); unsaved.OTPAuth != saved.OTPAuth {
+		t.Errorf("counter moved on in memory to %s", unsaved.OTPAuth)
+	}
+}
+
+func TestParseOTP(t *testing.T) {
+	uri := otpauthURI("hotp", "12345678901234567890", "&counter=3")
+	otp, err := ParseOTP("  "+uri+"\n", "GitHub", "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otp.Type != OTPTypeHOTP || otp.Counter != 3 {
+		t.Errorf("parsed URI as %+v", otp)
+	}
+
+	// A bare secret sets up a TOTP named after the item
+	otp, err = ParseOTP(" jbsw y3dp ehpk 3pxp ", "GitHub", "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otp.Type != OTPTypeTOTP || otp.Issuer != "GitHub" || otp.Account != "octocat" {
+		t.Errorf("parsed secret as %+v", otp)
+	}
+
+	for _, value := range []string{"", "   ", "not base32!", "otpauth://totp/x", "OTPAUTH://steam/x?secret=JBSWY3DPEHPK3PXP"} {
+		if _, err := ParseOTP(value, "GitHub", "octocat"); err == nil {
+			t.Errorf("accepted %q", value)
+		}
+	}
+}
+
+func TestCredentialOTPIsValidated(t *testing.T) {
+	v, _ := newTestVault(t)
+
+	if _, err := v.AddCredential(Credential{ServiceName: "GitHub", OTPAuth: "not base32!"}); err == nil {
+		t.Error("added a credential with an invalid one-time password")
+	}
+
+	cred, err := v.AddCredential(Credential{ServiceName: "GitHub", Username: "octocat", OTPAuth: "jbsw y3dp ehpk 3pxp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(cred.OTPAuth, "otpauth://totp/GitHub:octocat?") {
+		t.Errorf("stored %s", cred.OTPAuth)
+	}
+
+	err = v.UpdateCredential(cred.ID, func(cred *Credential) error {
+		cred.OTPAuth = "otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP"
+		return nil
+	})
+	if err == nil {
+		t.Error("saved an HOTP URI without a counter")
+	}
+	if saved, _ := v.Credential(cred.ID); saved.OTPAuth != cred.OTPAuth {
+		t.Errorf("rejected change was kept: %s", saved.OTPAuth)
+	}
+
+	err = v.UpdateCredential(cred.ID, func(cred *Credential) error {
+		cred.OTPAuth = " "
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := v.Credential(cred.ID); saved.OTPAuth != "" {
+		t.Errorf("blank one-time password stored as %q", saved.OTPAuth)
+	}
+}
@@ -0,0 +1,301 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Vault contents are stored as one sealed record per item plus a sealed index
+// listing the records in order. Each record is encrypted with its item ID as
+// associated data, so records cannot be swapped between items, and the index
+// pins the hash of every record, so single records cannot be rolled back or
+// dropped unnoticed. A record that fails to open only loses that one item.
+
+const (
+	recordTypeCredential = "credential"
+	recordTypeCreditCard = "creditCard"
+	recordTypeSecureNote = "secureNote"
+	recordTypeIdentity   = "identity"
+
+	indexAAD = "vaultzero index"
+)
+
+// indexEntry describes one sealed record in the encrypted index
+type indexEntry struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Hash []byte `json:"hash"` // SHA-256 of the sealed record
+}
+
+// vaultIndex is the encrypted table of contents of a vault file
+type vaultIndex struct {
+	Items []indexEntry `json:"items"`
+}
+
+// sealedRecord is one encrypted item as stored in the vault file
+type sealedRecord struct {
+	entry     indexEntry
+	data      string
+	plainHash [32]byte // hash of the plaintext, to tell whether the item changed
+}
+
+// recordSet holds the sealed records of the last loaded or saved vault so
+// the next save only has to encrypt items that changed
+type recordSet struct {
+	keyCheck []byte                  // identifies the data key the records are sealed with
+	sealed   map[string]sealedRecord // by item ID
+	damaged  []sealedRecord          // records that could not be opened
+}
+
+// recordAAD binds a sealed record to its item ID
+func recordAAD(id string) []byte {
+	return []byte("vaultzero record " + id)
+}
+
+// sealVault encrypts every vault item into its own record. Items whose
+// plaintext is unchanged since prev keep their existing sealed record, and
+// damaged records from prev are carried forward untouched so that saving
+// never destroys what could not be read.
+func sealVault(vault *Contents, dataKey []byte, prev *recordSet) (*vaultFile, *recordSet, error) {
+	next := &recordSet{
+		keyCheck: KeyCheckValue(dataKey),
+		sealed:   make(map[string]sealedRecord),
+	}
+	if prev != nil && hmac.Equal(prev.keyCheck, next.keyCheck) {
+		next.damaged = prev.damaged
+	} else {
+		prev = nil
+	}
+
+	index := vaultIndex{Items: []indexEntry{}}
+	add := func(id, recordType string, item interface{}) error {
+		if id == "" {
+			return errors.New("vault item has no id")
+		}
+		if _, exists := next.sealed[id]; exists {
+			return fmt.Errorf("duplicate vault item id %s", id)
+		}
+
+		plaintext, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		plainHash := sha256.Sum256(plaintext)
+
+		record, ok := sealedRecord{}, false
+		if prev != nil {
+			record, ok = prev.sealed[id]
+		}
+		if !ok || record.plainHash != plainHash || record.entry.Type != recordType {
+			data, err := EncryptWithAAD(plaintext, dataKey, recordAAD(id))
+			if err != nil {
+				return err
+			}
+			hash := sha256.Sum256([]byte(data))
+			record = sealedRecord{
+				entry:     indexEntry{ID: id, Type: recordType, Hash: hash[:]},
+				data:      data,
+				plainHash: plainHash,
+			}
+		}
+
+		next.sealed[id] = record
+		index.Items = append(index.Items, record.entry)
+		return nil
+	}
+
+	for _, cred := range vault.Credentials {
+		if err := add(cred.ID, recordTypeCredential, cred); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, card := range vault.CreditCards {
+		if err := add(card.ID, recordTypeCreditCard, card); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, note := range vault.SecureNotes {
+		if err := add(note.ID, recordTypeSecureNote, note); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, identity := range vault.Identities {
+		if err := add(identity.ID, recordTypeIdentity, identity); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	records := make(map[string]string, len(next.sealed)+len(next.damaged))
+	for id, record := range next.sealed {
+		records[id] = record.data
+	}
+	for _, record := range next.damaged {
+		if _, exists := records[record.entry.ID]; exists {
+			continue
+		}
+		records[record.entry.ID] = record.data
+		index.Items = append(index.Items, record.entry)
+	}
+
+	indexData, err := json.Marshal(index)
+	if err != nil {
+		return nil, nil, err
+	}
+	sealedIndex, err := EncryptWithAAD(indexData, dataKey, []byte(indexAAD))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file := &vaultFile{
+		Index:   sealedIndex,
+		Records: records,
+	}
+	return file, next, nil
+}
+
+// openVault decrypts the index and every record it lists. Records that are
+// missing, altered or undecryptable are reported as damaged instead of
+// failing the whole vault; only an unreadable index is fatal.
+func openVault(file *vaultFile, dataKey []byte) (*Contents, *recordSet, error) {
+	indexData, err := DecryptWithAAD(file.Index, dataKey, []byte(indexAAD))
+	if err != nil {
+		return nil, nil, errors.New("invalid master password or corrupted vault")
+	}
+
+	var index vaultIndex
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return nil, nil, errors.New("vault index is corrupted")
+	}
+
+	vault := &Contents{
+		Credentials: []Credential{},
+		CreditCards: []CreditCard{},
+		SecureNotes: []SecureNote{},
+		Identities:  []Identity{},
+		Header:      &file.VaultHeader,
+	}
+	set := &recordSet{
+		keyCheck: KeyCheckValue(dataKey),
+		sealed:   make(map[string]sealedRecord),
+	}
+
+	for _, entry := range index.Items {
+		data := file.Records[entry.ID]
+		record := sealedRecord{entry: entry, data: data}
+
+		plaintext, err := openRecord(entry, data, dataKey)
+		if err == nil {
+			err = vault.addRecord(entry.Type, plaintext)
+		}
+		if err != nil {
+			// Skipped records are reported through DamagedRecords
+			set.damaged = append(set.damaged, record)
+			continue
+		}
+
+		record.plainHash = sha256.Sum256(plaintext)
+		set.sealed[entry.ID] = record
+	}
+
+	return vault, set, nil
+}
+
+// openRecord verifies a sealed record against its index entry and decrypts it
+func openRecord(entry indexEntry, data string, dataKey []byte) ([]byte, error) {
+	if data == "" {
+		return nil, errors.New("record is missing")
+	}
+
+	hash := sha256.Sum256([]byte(data))
+	if !bytes.Equal(hash[:], entry.Hash) {
+		return nil, errors.New("record does not match the index")
+	}
+
+	plaintext, err := DecryptWithAAD(data, dataKey, recordAAD(entry.ID))
+	if err != nil {
+		return nil, errors.New("record cannot be decrypted")
+	}
+	return plaintext, nil
+}
+
+// addRecord appends a decrypted record to the matching item list
+func (v *Contents) addRecord(recordType string, plaintext []byte) error {
+	switch recordType {
+	case recordTypeCredential:
+		var cred Credential
+		if err := json.Unmarshal(plaintext, &cred); err != nil {
+			return err
+		}
+		v.Credentials = append(v.Credentials, cred)
+
+	case recordTypeCreditCard:
+		var card CreditCard
+		if err := json.Unmarshal(plaintext, &card); err != nil {
+			return err
+		}
+		v.CreditCards = append(v.CreditCards, card)
+
+	case recordTypeSecureNote:
+		var note SecureNote
+		if err := json.Unmarshal(plaintext, &note); err != nil {
+			return err
+		}
+		v.SecureNotes = append(v.SecureNotes, note)
+
+	case recordTypeIdentity:
+		var identity Identity
+		if err := json.Unmarshal(plaintext, &identity); err != nil {
+			return err
+		}
+		v.Identities = append(v.Identities, identity)
+
+	default:
+		return fmt.Errorf("unknown record type %q", recordType)
+	}
+
+	return nil
+}
+
+// ensureUniqueIDs gives fresh IDs to items that lack one or share one with an
+// earlier item, which older vaults could contain after importing backups
+func (v *Contents) ensureUniqueIDs() {
+	seen := make(map[string]bool)
+	unique := func(id string) string {
+		if id == "" || seen[id] {
+			id = uuid.New().String()
+		}
+		seen[id] = true
+		return id
+	}
+
+	for i := range v.Credentials {
+		v.Credentials[i].ID = unique(v.Credentials[i].ID)
+	}
+	for i := range v.CreditCards {
+		v.CreditCards[i].ID = unique(v.CreditCards[i].ID)
+	}
+	for i := range v.SecureNotes {
+		v.SecureNotes[i].ID = unique(v.SecureNotes[i].ID)
+	}
+	for i := range v.Identities {
+		v.Identities[i].ID = unique(v.Identities[i].ID)
+	}
+}
+
+// DamagedRecords returns the IDs of records in the loaded vault that could not be read
+func (sm *StorageManager) DamagedRecords() []string {
+	ids := []string{}
+	if sm.records == nil {
+		return ids
+	}
+	for _, record := range sm.records.damaged {
+		ids = append(ids, record.entry.ID)
+	}
+	return ids
+}
@@ -0,0 +1,125 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const registryFileName = "vaults.json"
+
+// VaultInfo describes a vault known to the app
+type VaultInfo struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	LastOpened time.Time `json:"lastOpened"`
+	Exists     bool      `json:"exists"` // the vault file is present
+	Active     bool      `json:"active"` // the vault currently selected in the app
+}
+
+// registryEntry is a vault as remembered in the registry file
+type registryEntry struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	LastOpened time.Time `json:"lastOpened"`
+}
+
+// VaultRegistry remembers the vaults the user has opened and which one is
+// selected. It is stored unencrypted next to the default vault.
+type VaultRegistry struct {
+	path    string
+	Vaults  []registryEntry `json:"vaults"`
+	Current string          `json:"current"`
+}
+
+// LoadVaultRegistry reads the registry, starting with just the default vault
+// if there is none yet
+func LoadVaultRegistry() (*VaultRegistry, error) {
+	vaultDir, err := DefaultVaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &VaultRegistry{path: filepath.Join(vaultDir, registryFileName)}
+
+	data, err := os.ReadFile(registry.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, registry); err != nil {
+			return nil, errors.New("vault registry is corrupted")
+		}
+	}
+
+	if len(registry.Vaults) == 0 {
+		defaultPath, err := ResolveVaultPath("")
+		if err != nil {
+			return nil, err
+		}
+		registry.Add("Default", defaultPath)
+	}
+	if registry.Current == "" {
+		registry.Current = registry.Vaults[0].Path
+	}
+
+	return registry, nil
+}
+
+// Save writes the registry to disk
+func (r *VaultRegistry) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return err
+	}
+	return WriteFileAtomic(r.path, data, 0600)
+}
+
+// Add remembers a vault; a known vault keeps its name unless a new one is given
+func (r *VaultRegistry) Add(name, path string) {
+	if i := r.find(path); i >= 0 {
+		if name != "" {
+			r.Vaults[i].Name = name
+		}
+		return
+	}
+
+	if name == "" {
+		name = defaultVaultName(path)
+	}
+	r.Vaults = append(r.Vaults, registryEntry{Name: name, Path: path})
+}
+
+// SetCurrent selects a vault and records when it was opened
+func (r *VaultRegistry) SetCurrent(path string) {
+	r.Add("", path)
+	r.Vaults[r.find(path)].LastOpened = time.Now()
+	r.Current = path
+}
+
+// find returns the index of the vault with the given file path, or -1
+func (r *VaultRegistry) find(path string) int {
+	for i, entry := range r.Vaults {
+		if filepath.Clean(entry.Path) == filepath.Clean(path) {
+			return i
+		}
+	}
+	return -1
+}
+
+// defaultVaultName derives a display name from a vault file path, using the
+// directory name for files called vault.dat
+func defaultVaultName(path string) string {
+	base := filepath.Base(path)
+	if base == vaultFileName {
+		base = filepath.Base(filepath.Dir(path))
+	}
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
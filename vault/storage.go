@@ -0,0 +1,476 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	vaultDirName  = ".vaultzero"
+	vaultFileName = "vault.dat"
+)
+
+// StorageManager is the VaultStore that keeps a vault in a file on disk,
+// with its lock file, legacy salt file and rolling backups next to it
+type StorageManager struct {
+	// OnBackupError is called when a save could not keep the generation it
+	// replaces; the save itself goes ahead
+	OnBackupError func(err error)
+
+	vaultPath    string
+	saltPath     string
+	backupDir    string
+	backupPrefix string
+	backupPolicy BackupPolicy
+	lockPath     string
+	lockFile     *os.File
+	readOnly     bool
+	revision     string     // revision of the vault file the in-memory vault is based on
+	records      *recordSet // sealed records of that file
+}
+
+// ErrVaultConflict is returned by saves when the vault file changed on disk
+// after it was loaded
+var ErrVaultConflict = errors.New("vault was modified outside this session")
+
+// NewStorageManager creates a storage manager for the vault at location,
+// which may be a vault file or a directory holding vault.dat. An empty
+// location selects the default vault in ~/.vaultzero.
+func NewStorageManager(location string) (*StorageManager, error) {
+	vaultPath, err := ResolveVaultPath(location)
+	if err != nil {
+		return nil, err
+	}
+
+	vaultDir := filepath.Dir(vaultPath)
+	if err := os.MkdirAll(vaultDir, 0700); err != nil {
+		return nil, err
+	}
+
+	// Side files are named after the vault file so several vaults can share a directory
+	stem := strings.TrimSuffix(filepath.Base(vaultPath), filepath.Ext(vaultPath))
+
+	return &StorageManager{
+		vaultPath:    vaultPath,
+		saltPath:     filepath.Join(vaultDir, stem+".salt"),
+		backupDir:    filepath.Join(vaultDir, backupDirName),
+		backupPrefix: stem + "-",
+		backupPolicy: DefaultBackupPolicy(),
+		lockPath:     filepath.Join(vaultDir, stem+".lock"),
+	}, nil
+}
+
+// DefaultVaultDir returns the directory of the default vault, which also
+// holds the application's settings
+func DefaultVaultDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, vaultDirName), nil
+}
+
+// ResolveVaultPath turns a vault location into the absolute path of its
+// vault file. Directories, and paths without an extension that do not exist
+// yet, get vault.dat appended.
+func ResolveVaultPath(location string) (string, error) {
+	if location == "" {
+		vaultDir, err := DefaultVaultDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(vaultDir, vaultFileName), nil
+	}
+
+	path, err := filepath.Abs(location)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		return filepath.Join(path, vaultFileName), nil
+	case os.IsNotExist(err) && filepath.Ext(path) == "":
+		return filepath.Join(path, vaultFileName), nil
+	}
+
+	return path, nil
+}
+
+// LoadSalt loads the salt of a legacy headerless vault from disk
+func (sm *StorageManager) LoadSalt() ([]byte, error) {
+	if _, err := os.Stat(sm.saltPath); os.IsNotExist(err) {
+		return nil, errors.New("salt file does not exist")
+	}
+
+	encoded, err := os.ReadFile(sm.saltPath)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// LoadHeader reads the vault header, which holds the salt, KDF parameters
+// and wrapped data key
+func (sm *StorageManager) LoadHeader() (*VaultHeader, error) {
+	file, _, err := sm.readVaultFile()
+	if err != nil {
+		return nil, err
+	}
+	return &file.VaultHeader, nil
+}
+
+// readVaultFile parses the vault file and returns it with its revision.
+// Legacy vaults have no header; their salt lives in vault.salt and they
+// always used the legacy KDF parameters.
+func (sm *StorageManager) readVaultFile() (*vaultFile, string, error) {
+	raw, err := os.ReadFile(sm.vaultPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", errors.New("vault does not exist")
+		}
+		return nil, "", err
+	}
+	revision := fileRevision(raw)
+
+	if isVaultContainer(raw) {
+		file, err := decodeVaultFile(raw)
+		if err != nil {
+			return nil, "", err
+		}
+		return file, revision, nil
+	}
+
+	salt, err := sm.LoadSalt()
+	if err != nil {
+		return nil, "", err
+	}
+
+	file := &vaultFile{
+		VaultHeader: VaultHeader{
+			Magic: vaultMagic,
+			KDF:   LegacyKDFParams(),
+			Salt:  salt,
+		},
+		Data: string(raw),
+	}
+	return file, revision, nil
+}
+
+// Revision identifies the vault file currently on disk; it changes whenever
+// the file is rewritten. It is empty if there is no vault file.
+func (sm *StorageManager) Revision() (string, error) {
+	raw, err := os.ReadFile(sm.vaultPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return fileRevision(raw), nil
+}
+
+// fileRevision hashes raw vault file contents into a revision identifier
+func fileRevision(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// SaveVault encrypts the vault contents with the data key and saves them
+// to disk together with the vault's key header. Items are sealed one record
+// each; records of items that did not change since the last load or save are
+// written back as they were instead of being encrypted again.
+func (sm *StorageManager) SaveVault(vault *Contents, dataKey []byte) error {
+	file, records, err := sealVaultFile(vault, dataKey, sm.records)
+	if err != nil {
+		return err
+	}
+
+	if err := sm.writeVaultFile(file); err != nil {
+		return err
+	}
+
+	sm.records = records
+	return nil
+}
+
+// SaveHeader replaces the vault header while keeping the encrypted contents,
+// so changing the master password only rewraps the data key
+func (sm *StorageManager) SaveHeader(header *VaultHeader) error {
+	file, _, err := sm.readVaultFile()
+	if err != nil {
+		return err
+	}
+	if err := replaceVaultHeader(file, header); err != nil {
+		return err
+	}
+	return sm.writeVaultFile(file)
+}
+
+// writeVaultFile atomically writes a vault container to the vault file.
+// Salt, wrapped key and contents live in the same file, so they are always
+// committed together.
+func (sm *StorageManager) writeVaultFile(file *vaultFile) error {
+	if err := sm.checkWritable(); err != nil {
+		return err
+	}
+
+	// Refuse to overwrite changes someone else made since the vault was loaded
+	current, err := sm.Revision()
+	if err != nil {
+		return err
+	}
+	if current != sm.revision {
+		return ErrVaultConflict
+	}
+
+	raw, err := encodeVaultFile(file)
+	if err != nil {
+		return err
+	}
+
+	// Keep the generation we are about to replace
+	if err := sm.backupCurrentVault(); err != nil && sm.OnBackupError != nil {
+		sm.OnBackupError(errors.New("failed to back up vault before saving: " + err.Error()))
+	}
+
+	if err := WriteFileAtomic(sm.vaultPath, raw, 0600); err != nil {
+		return err
+	}
+	sm.revision = fileRevision(raw)
+
+	// The separate salt file is obsolete once the header has been committed
+	if err := os.Remove(sm.saltPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// LoadVault loads and decrypts the vault from disk
+func (sm *StorageManager) LoadVault(dataKey []byte) (*Contents, error) {
+	// Read header and encrypted data
+	file, revision, err := sm.readVaultFile()
+	if err != nil {
+		return nil, err
+	}
+
+	vault, records, err := decryptVault(file, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Later saves only go through if the file still has this revision
+	sm.revision = revision
+	sm.records = records
+	return vault, nil
+}
+
+// sealVaultFile builds the vault container for a save: the sealed records
+// plus the vault's key header in the current format
+func sealVaultFile(vault *Contents, dataKey []byte, prev *recordSet) (*vaultFile, *recordSet, error) {
+	if vault.Header == nil || vault.Header.WrappedKey == "" {
+		return nil, nil, errors.New("vault data key is not wrapped")
+	}
+
+	file, records, err := sealVault(vault, dataKey, prev)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Salt, KDF parameters and wrapped key travel in the header next to the records
+	file.VaultHeader = *vault.Header
+	file.Magic = vaultMagic
+	file.Version = vaultFormatVersion
+	return file, records, nil
+}
+
+// replaceVaultHeader swaps the key header of a container, keeping its contents
+func replaceVaultHeader(file *vaultFile, header *VaultHeader) error {
+	if header.WrappedKey == "" {
+		return errors.New("vault data key is not wrapped")
+	}
+	if file.WrappedKey == "" {
+		return errors.New("vault must be re-encrypted before its header can be replaced")
+	}
+
+	file.VaultHeader = *header
+	return nil
+}
+
+// decryptVault decrypts and deserializes vault contents. The record set is
+// nil for files that predate per-item records.
+func decryptVault(file *vaultFile, dataKey []byte) (*Contents, *recordSet, error) {
+	if file.Index != "" {
+		return openVault(file, dataKey)
+	}
+
+	// Decrypt
+	decrypted, err := Decrypt(file.Data, dataKey)
+	if err != nil {
+		return nil, nil, errors.New("invalid master password or corrupted vault")
+	}
+
+	// Deserialize - try new format first (with credit cards)
+	var vaultData struct {
+		Credentials []Credential `json:"credentials"`
+		CreditCards []CreditCard `json:"creditCards"`
+	}
+
+	vault := &Contents{Header: &file.VaultHeader}
+	if err := json.Unmarshal(decrypted, &vaultData); err != nil {
+		// Fall back to old format (credentials only) for backward compatibility
+		var credentials []Credential
+		if err := json.Unmarshal(decrypted, &credentials); err != nil {
+			return nil, nil, err
+		}
+		vault.Credentials = credentials
+		vault.CreditCards = []CreditCard{} // Empty credit cards for old vaults
+	} else {
+		vault.Credentials = vaultData.Credentials
+		vault.CreditCards = vaultData.CreditCards
+	}
+
+	// Secure notes and identities came after the single-blob format
+	vault.SecureNotes = []SecureNote{}
+	vault.Identities = []Identity{}
+
+	// Every item needs a distinct ID to become its own record
+	vault.ensureUniqueIDs()
+	return vault, nil, nil
+}
+
+// Location returns the path of the vault file
+func (sm *StorageManager) Location() string {
+	return sm.vaultPath
+}
+
+// VaultExists checks if a vault file exists
+func (sm *StorageManager) VaultExists() bool {
+	_, err := os.Stat(sm.vaultPath)
+	return !os.IsNotExist(err)
+}
+
+// DeleteVault removes the vault and salt files along with all backups
+func (sm *StorageManager) DeleteVault() error {
+	if err := sm.checkWritable(); err != nil {
+		return err
+	}
+
+	// Remove vault file
+	if err := os.Remove(sm.vaultPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Remove salt file
+	if err := os.Remove(sm.saltPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	sm.revision = ""
+	sm.records = nil
+
+	// Remove this vault's rolling backups
+	backups, err := sm.ListBackups()
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		if err := os.Remove(filepath.Join(sm.backupDir, backup.ID)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ImportEncryptedBackup reads the items of an encrypted backup file. header and dataKey are those of the importing
+// vault, which opens its own backups without a password; masterPassword is
+// the one in use when the backup was exported.
+func ImportEncryptedBackup(filePath, masterPassword string, header *VaultHeader, dataKey []byte) (*Contents, error) {
+	// Read encrypted backup file
+	raw, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, errors.New("failed to read backup file")
+	}
+
+	// Decrypt
+	decrypted, err := decryptBackup(raw, masterPassword, header, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deserialize - older backups are just the array of credentials
+	var backup backupFile
+	if err := json.Unmarshal(decrypted, &backup); err != nil {
+		if err := json.Unmarshal(decrypted, &backup.Credentials); err != nil {
+			return nil, errors.New("invalid backup file format")
+		}
+	}
+
+	return &Contents{
+		Credentials: backup.Credentials,
+		CreditCards: backup.CreditCards,
+		SecureNotes: backup.SecureNotes,
+		Identities:  backup.Identities,
+	}, nil
+}
+
+// decryptBackup tries the importing vault's data key first, then the keys
+// the master password unlocks. Backups with a header carry their own
+// wrapped data key; bare ones were encrypted with the data key of the vault
+// that made them or, before vaults had one, with the password key.
+func decryptBackup(raw []byte, masterPassword string, header *VaultHeader, dataKey []byte) ([]byte, error) {
+	failed := errors.New("failed to decrypt backup - wrong password or corrupted file")
+
+	encrypted := string(raw)
+	var backup backupContainer
+	if isVaultContainer(raw) {
+		if err := json.Unmarshal(raw, &backup); err != nil || backup.Magic != backupMagic {
+			return nil, errors.New("invalid backup file format")
+		}
+		encrypted = backup.Data
+	}
+
+	if dataKey != nil {
+		if decrypted, err := Decrypt(encrypted, dataKey); err == nil {
+			return decrypted, nil
+		}
+	}
+	if masterPassword == "" {
+		return nil, failed
+	}
+
+	var key []byte
+	var err error
+	switch {
+	case backup.WrappedKey != "":
+		key, err = backup.UnlockDataKey(masterPassword)
+	case backup.Magic == "" && header != nil && len(header.LegacySalt) > 0 && header.LegacyKDF != nil:
+		key, err = DeriveKey(masterPassword, header.LegacySalt, *header.LegacyKDF)
+	default:
+		return nil, failed
+	}
+	if err != nil {
+		return nil, failed
+	}
+
+	decrypted, err := Decrypt(encrypted, key)
+	if err != nil {
+		return nil, failed
+	}
+	return decrypted, nil
+}
@@ -0,0 +1,286 @@
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestStorage returns a storage manager for a vault file in a temporary directory
+func newTestStorage(t *testing.T, dir string) *StorageManager {
+	t.Helper()
+
+	store, err := NewStorageManager(filepath.Join(dir, "test.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestResolveVaultPath(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{dir, filepath.Join(dir, vaultFileName)},
+		{filepath.Join(dir, "new"), filepath.Join(dir, "new", vaultFileName)},
+		{filepath.Join(dir, "work.dat"), filepath.Join(dir, "work.dat")},
+	}
+	for _, tt := range tests {
+		got, err := ResolveVaultPath(tt.location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("ResolveVaultPath(%q) = %q, want %q", tt.location, got, tt.want)
+		}
+	}
+}
+
+func TestStorageRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	v := New(newTestStorage(t, dir))
+	if err := v.Create(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.AddCredential(Credential{ServiceName: "GitHub", Password: "hunter22"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.AddCreditCard(CreditCard{CardName: "Visa"}); err != nil {
+		t.Fatal(err)
+	}
+	v.Lock()
+
+	raw, err := os.ReadFile(filepath.Join(dir, "test.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isVaultContainer(raw) {
+		t.Fatal("vault file is not a vault container")
+	}
+
+	reopened := New(newTestStorage(t, dir))
+	if err := reopened.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Lock()
+
+	credentials, _ := reopened.Credentials()
+	cards, _ := reopened.CreditCards()
+	if len(credentials) != 1 || credentials[0].Password != "hunter22" || len(cards) != 1 {
+		t.Fatalf("got %+v and %+v", credentials, cards)
+	}
+}
+
+func TestSecondInstanceOpensReadOnly(t *testing.T) {
+	dir := t.TempDir()
+
+	first := New(newTestStorage(t, dir))
+	if err := first.Create(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	defer first.Lock()
+
+	second := New(newTestStorage(t, dir))
+	if err := second.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	defer second.Lock()
+
+	if !second.IsReadOnly() {
+		t.Fatal("second instance is not read-only")
+	}
+	var inUse *VaultInUseError
+	if !errors.As(second.ReadOnlyReason(), &inUse) {
+		t.Errorf("ReadOnlyReason = %v, want a VaultInUseError", second.ReadOnlyReason())
+	}
+	if _, err := second.AddCredential(Credential{ServiceName: "x"}); err == nil {
+		t.Error("read-only instance saved the vault")
+	}
+	// A change that could not be saved is not kept in memory either
+	if credentials, _ := second.Credentials(); len(credentials) != 0 {
+		t.Errorf("unsaved credential is listed: %+v", credentials)
+	}
+	if err := second.Delete(); err == nil {
+		t.Error("deleted a vault another instance has open")
+	}
+}
+
+func TestLegacyVaultMigration(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestStorage(t, dir)
+
+	// A headerless vault: credentials encrypted with the password key, salt on the side
+	salt, err := GenerateSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DeriveKey(testPassword, salt, LegacyKDFParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Encrypt([]byte(`[{"id":"a","serviceName":"Legacy"}]`), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "test.dat"), []byte(data), 0600)
+	os.WriteFile(filepath.Join(dir, "test.salt"), []byte(base64.StdEncoding.EncodeToString(salt)), 0600)
+
+	v := New(store)
+	if err := v.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	v.Lock()
+
+	if _, err := os.Stat(filepath.Join(dir, "test.salt")); !os.IsNotExist(err) {
+		t.Error("salt file was not removed after migration")
+	}
+	header, err := store.LoadHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.WrappedKey == "" || header.KDF != DefaultKDFParams() {
+		t.Errorf("migrated header = %+v", header)
+	}
+
+	if err := v.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	defer v.Lock()
+	credentials, _ := v.Credentials()
+	if len(credentials) != 1 || credentials[0].ServiceName != "Legacy" {
+		t.Fatalf("got %+v", credentials)
+	}
+	if notes, err := v.SecureNotes(); err != nil || notes == nil {
+		t.Fatalf("legacy vault has no secure note list: %v", err)
+	}
+	if _, err := v.AddSecureNote(SecureNote{Title: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if identities, err := v.Identities(); err != nil || identities == nil {
+		t.Fatalf("legacy vault has no identity list: %v", err)
+	}
+
+	// Backups exported before the migration are encrypted with the password key
+	backup, err := Encrypt([]byte(`[{"id":"b","serviceName":"Backup","url":"https://backup.example"}]`), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "old.vault")
+	os.WriteFile(path, []byte(backup), 0600)
+	if _, err := v.ImportEncryptedBackup(path, ""); err == nil {
+		t.Error("imported a password-key backup without the password")
+	}
+	if result, err := v.ImportEncryptedBackup(path, testPassword); err != nil || result.Imported != 1 {
+		t.Fatalf("import of a pre-migration backup: %+v, %v", result, err)
+	}
+}
+
+func TestBackupPruning(t *testing.T) {
+	store := newTestStorage(t, t.TempDir())
+	store.SetBackupPolicy(BackupPolicy{MaxCount: 2})
+
+	v := New(store)
+	if err := v.Create(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	defer v.Lock()
+
+	for i := 0; i < 4; i++ {
+		if _, err := v.AddCredential(Credential{ServiceName: "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := v.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("got %d backups, want 2", len(backups))
+	}
+	if backups[0].Credentials != 3 || backups[1].Credentials != 2 {
+		t.Errorf("backups hold %d and %d credentials, want 3 and 2", backups[0].Credentials, backups[1].Credentials)
+	}
+}
+
+func TestBackupFailureIsReported(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestStorage(t, dir)
+	var reported []error
+	store.OnBackupError = func(err error) {
+		reported = append(reported, err)
+	}
+
+	v := New(store)
+	if err := v.Create(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	defer v.Lock()
+
+	// A file where the backup directory belongs makes every backup fail
+	if err := os.WriteFile(filepath.Join(dir, backupDirName), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.AddCredential(Credential{ServiceName: "x"}); err != nil {
+		t.Fatalf("save failed with its backup: %v", err)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("reported %v, want one backup error", reported)
+	}
+	if credentials, _ := v.Credentials(); len(credentials) != 1 {
+		t.Errorf("vault holds %d credentials, want 1", len(credentials))
+	}
+}
+
+func TestDamagedRecordIsSkipped(t *testing.T) {
+	dir := t.TempDir()
+	v := New(newTestStorage(t, dir))
+	if err := v.Create(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	kept, err := v.AddCredential(Credential{ServiceName: "Kept"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	damaged, err := v.AddCredential(Credential{ServiceName: "Damaged"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Lock()
+
+	// Swap one record for the other's ciphertext
+	path := filepath.Join(dir, "test.dat")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := decodeVaultFile(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Records[damaged.ID] = file.Records[kept.ID]
+	if raw, err = encodeVaultFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v = New(newTestStorage(t, dir))
+	if err := v.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	defer v.Lock()
+	if credentials, _ := v.Credentials(); len(credentials) != 1 || credentials[0].ID != kept.ID {
+		t.Fatalf("got %+v, want only the intact credential", credentials)
+	}
+	if ids := v.DamagedRecords(); len(ids) != 1 || ids[0] != damaged.ID {
+		t.Fatalf("damaged records %v, want [%s]", ids, damaged.ID)
+	}
+}
@@ -0,0 +1,55 @@
+package vault
+
+// VaultStore persists one vault. Implementations keep the header and sealed
+// records together, refuse saves that would overwrite changes made since the
+// last load (ErrVaultConflict), and only write while holding the vault lock.
+type VaultStore interface {
+	// Location identifies the vault, e.g. the path of the vault file
+	Location() string
+
+	// VaultExists reports whether the store holds a vault
+	VaultExists() bool
+
+	// LoadHeader reads the vault's key header without decrypting anything
+	LoadHeader() (*VaultHeader, error)
+
+	// LoadVault decrypts the vault with its data key. Later saves are checked
+	// against the revision that was loaded.
+	LoadVault(dataKey []byte) (*Contents, error)
+
+	// SaveVault encrypts and stores the vault contents and header
+	SaveVault(vault *Contents, dataKey []byte) error
+
+	// SaveHeader replaces the key header while keeping the encrypted contents
+	SaveHeader(header *VaultHeader) error
+
+	// Revision identifies the stored vault; it changes on every write and is
+	// empty if there is no vault
+	Revision() (string, error)
+
+	// Lock takes the exclusive write lock, failing with *VaultInUseError if
+	// someone else holds it; Unlock releases it
+	Lock() error
+	Unlock() error
+
+	// SetReadOnly marks the vault as opened without the lock; all writes fail
+	SetReadOnly(readOnly bool)
+	IsReadOnly() bool
+
+	// DamagedRecords returns the IDs of records in the loaded vault that could not be read
+	DamagedRecords() []string
+
+	// DeleteVault removes the vault and everything stored with it
+	DeleteVault() error
+
+	// ListBackups returns earlier generations of the vault, newest first
+	ListBackups() ([]BackupInfo, error)
+
+	// LoadBackup decrypts one earlier generation with the vault data key
+	LoadBackup(id string, dataKey []byte) (*Contents, error)
+}
+
+var (
+	_ VaultStore = (*StorageManager)(nil)
+	_ VaultStore = (*MemoryStore)(nil)
+)
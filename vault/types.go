@@ -0,0 +1,104 @@
+package vault
+
+import (
+	"time"
+)
+
+// Credential represents a single password entry
+type Credential struct {
+	ID          string    `json:"id"`
+	ServiceName string    `json:"serviceName"`
+	URL         string    `json:"url"`
+	Username    string    `json:"username"`
+	Password    string    `json:"password"`
+	Category    string    `json:"category"`
+	IconURL     string    `json:"iconURL"`
+	IsFavorite  bool      `json:"isFavorite"`
+	CreatedAt   time.Time `json:"createdAt"`
+
+	// How the browser extension matches URL against pages: "base" (the
+	// default) by registrable domain, "exact" by host and port, or
+	// "equivalent", which also accepts the registrable domains listed in
+	// EquivalentDomains
+	MatchMode         string   `json:"matchMode,omitempty"`
+	EquivalentDomains []string `json:"equivalentDomains,omitempty"`
+
+	// otpauth:// URI of the account's one-time password, if it has one (see otp.go)
+	OTPAuth string `json:"otpAuth,omitempty"`
+
+	// Ordered custom fields (see fields.go)
+	Fields []CustomField `json:"fields,omitempty"`
+}
+
+// CreditCard represents a credit/debit card entry
+type CreditCard struct {
+	ID             string    `json:"id"`
+	CardName       string    `json:"cardName"`       // Nickname for the card (e.g., "Personal Visa")
+	CardholderName string    `json:"cardholderName"` // Name on card
+	CardNumber     string    `json:"cardNumber"`     // Full card number (encrypted)
+	ExpiryMonth    string    `json:"expiryMonth"`    // MM format
+	ExpiryYear     string    `json:"expiryYear"`     // YYYY format
+	CVV            string    `json:"cvv"`            // CVV/CVC code (encrypted)
+	CardType       string    `json:"cardType"`       // visa, mastercard, amex, discover, etc.
+	BillingZip     string    `json:"billingZip"`     // Optional billing zip code
+	IsFavorite     bool      `json:"isFavorite"`
+	CreatedAt      time.Time `json:"createdAt"`
+
+	Fields []CustomField `json:"fields,omitempty"` // Ordered custom fields
+}
+
+// SecureNote is free-form secret text such as recovery codes or license keys
+type SecureNote struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Body       string    `json:"body"` // Markdown
+	Category   string    `json:"category"`
+	IsFavorite bool      `json:"isFavorite"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+
+	Fields []CustomField `json:"fields,omitempty"` // Ordered custom fields
+}
+
+// Address is a postal address as filled into address forms
+type Address struct {
+	Street1    string `json:"street1"`
+	Street2    string `json:"street2"`
+	City       string `json:"city"`
+	Region     string `json:"region"` // State, province or county
+	PostalCode string `json:"postalCode"`
+	Country    string `json:"country"`
+}
+
+// Identity holds the personal details used to fill address and sign-up forms
+type Identity struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"` // Label for the identity (e.g., "Personal", "Work")
+	FirstName      string    `json:"firstName"`
+	MiddleName     string    `json:"middleName"`
+	LastName       string    `json:"lastName"`
+	Email          string    `json:"email"`
+	Phone          string    `json:"phone"`
+	DateOfBirth    string    `json:"dateOfBirth"` // YYYY-MM-DD format
+	Address        Address   `json:"address"`
+	PassportNumber string    `json:"passportNumber"`
+	IDNumber       string    `json:"idNumber"` // National ID or driver's license number
+	IsFavorite     bool      `json:"isFavorite"`
+	CreatedAt      time.Time `json:"createdAt"`
+
+	Fields []CustomField `json:"fields,omitempty"` // Ordered custom fields
+}
+
+// Contents is everything stored in a vault, decrypted
+type Contents struct {
+	Credentials []Credential `json:"credentials"`
+	CreditCards []CreditCard `json:"creditCards"`
+	SecureNotes []SecureNote `json:"secureNotes"`
+	Identities  []Identity   `json:"identities"`
+	Header      *VaultHeader `json:"-"`
+}
+
+// MasterKey holds the derived encryption key
+type MasterKey struct {
+	Key []byte
+}
\ No newline at end of file
@@ -0,0 +1,411 @@
+// Package vault implements VaultZero's encrypted vault: the file format and
+// its stores, key derivation and encryption, CSV import and export, the
+// password generator, and the Vault service that the desktop app and the
+// command line work through.
+package vault
+
+import (
+	"crypto/subtle"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrLocked is returned by operations that need the vault unlocked
+	ErrLocked = errors.New("vault is locked")
+
+	// ErrVaultExists is returned when creating a vault where one already exists
+	ErrVaultExists = errors.New("vault already exists")
+
+	// ErrVaultNotFound is returned when unlocking a vault that does not exist
+	ErrVaultNotFound = errors.New("vault does not exist")
+
+	// errUnchanged lets an update report that there is nothing to save
+	errUnchanged = errors.New("vault unchanged")
+)
+
+// Vault is an encrypted vault in a VaultStore. While unlocked it holds the
+// decrypted contents and the data key; every change is saved to the store
+// before it returns. A Vault is safe for concurrent use.
+type Vault struct {
+	// OnReload is called after the contents were reloaded because the store
+	// was changed elsewhere, and OnConflict when such a change could not be
+	// read with the current key. Both are called with the vault's mutex held
+	// and must not call back into the Vault.
+	OnReload   func()
+	OnConflict func(err error)
+
+	mu       sync.Mutex
+	store    VaultStore
+	contents *Contents
+	dataKey  []byte
+	inUse    *VaultInUseError // why the vault was opened read-only
+}
+
+// New returns a locked Vault backed by store
+func New(store VaultStore) *Vault {
+	return &Vault{store: store}
+}
+
+// Store returns the store the vault is kept in
+func (v *Vault) Store() VaultStore {
+	return v.store
+}
+
+// Location identifies the vault, e.g. the path of its file
+func (v *Vault) Location() string {
+	return v.store.Location()
+}
+
+// Exists reports whether the store holds a vault
+func (v *Vault) Exists() bool {
+	return v.store.VaultExists()
+}
+
+// Create initializes an empty vault protected by masterPassword and leaves
+// it unlocked. The store's lock is held until the vault is locked again.
+func (v *Vault) Create(masterPassword string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.store.VaultExists() {
+		return ErrVaultExists
+	}
+
+	// Hold the vault lock so no other instance can create or write it concurrently
+	if err := v.store.Lock(); err != nil {
+		return err
+	}
+
+	contents, dataKey, err := createContents(v.store, masterPassword)
+	if err != nil {
+		v.store.Unlock()
+		return err
+	}
+
+	v.contents = contents
+	v.dataKey = dataKey
+	return nil
+}
+
+// createContents saves an empty vault under a new data key wrapped by the master password
+func createContents(store VaultStore, masterPassword string) (*Contents, []byte, error) {
+	// Generate the random key that encrypts the vault contents
+	dataKey, err := GenerateDataKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Wrap it with a key derived from the master password
+	header, err := NewPasswordHeader(masterPassword, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contents := &Contents{
+		Credentials: []Credential{},
+		CreditCards: []CreditCard{},
+		SecureNotes: []SecureNote{},
+		Identities:  []Identity{},
+		Header:      header,
+	}
+	if err := store.SaveVault(contents, dataKey); err != nil {
+		return nil, nil, err
+	}
+
+	return contents, dataKey, nil
+}
+
+// Unlock decrypts the vault with the master password. If another process
+// holds the store's lock the vault is opened read-only; ReadOnlyReason then
+// says why.
+func (v *Vault) Unlock(masterPassword string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if !v.store.VaultExists() {
+		return ErrVaultNotFound
+	}
+
+	// Take the vault lock; if another instance holds it, open read-only
+	var inUse *VaultInUseError
+	if err := v.store.Lock(); err != nil {
+		if !errors.As(err, &inUse) {
+			return err
+		}
+		v.store.SetReadOnly(true)
+	}
+
+	contents, dataKey, err := unlockContents(v.store, masterPassword)
+	if err != nil {
+		v.store.Unlock()
+		return err
+	}
+
+	v.contents = contents
+	v.dataKey = dataKey
+	v.inUse = inUse
+	return nil
+}
+
+// unlockContents derives the keys and loads the vault once the lock state is settled
+func unlockContents(store VaultStore, masterPassword string) (*Contents, []byte, error) {
+	// Load the header first (salt, KDF parameters and wrapped key, unencrypted)
+	header, err := store.LoadHeader()
+	if err != nil {
+		return nil, nil, errors.New("vault corrupted: " + err.Error())
+	}
+
+	// Derive the key-encryption key and unwrap the data key
+	dataKey, err := header.UnlockDataKey(masterPassword)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Now load and decrypt the vault with the data key
+	contents, err := store.LoadVault(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Vaults from before the key hierarchy are encrypted with the password
+	// key itself; move them to a random data key once
+	if header.WrappedKey == "" {
+		if dataKey, err = migrateDataKey(store, contents, header, masterPassword); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return contents, dataKey, nil
+}
+
+// migrateDataKey re-encrypts a vault under a new random data key wrapped by
+// the master password, remembering how the old password key was derived so
+// backups encrypted with it can still be imported
+func migrateDataKey(store VaultStore, contents *Contents, legacy *VaultHeader, masterPassword string) ([]byte, error) {
+	dataKey, err := GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := NewPasswordHeader(masterPassword, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	legacyKDF := legacy.KDF
+	header.LegacySalt = legacy.Salt
+	header.LegacyKDF = &legacyKDF
+
+	contents.Header = header
+	if err := store.SaveVault(contents, dataKey); err != nil {
+		return nil, errors.New("failed to upgrade vault encryption: " + err.Error())
+	}
+
+	return dataKey, nil
+}
+
+// Lock clears the keys and contents from memory and releases the store's lock
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.contents = nil
+	v.dataKey = nil
+	v.inUse = nil
+	v.store.Unlock()
+}
+
+// IsUnlocked reports whether the vault is unlocked
+func (v *Vault) IsUnlocked() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.contents != nil
+}
+
+// IsReadOnly reports whether the vault was unlocked read-only because
+// another process holds it
+func (v *Vault) IsReadOnly() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.contents != nil && v.store.IsReadOnly()
+}
+
+// ReadOnlyReason returns why the vault was unlocked read-only, or nil
+func (v *Vault) ReadOnlyReason() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.inUse == nil {
+		return nil
+	}
+	return v.inUse
+}
+
+// DamagedRecords returns the IDs of records that could not be read when
+// the vault was unlocked
+func (v *Vault) DamagedRecords() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store.DamagedRecords()
+}
+
+// ChangeMasterPassword rewraps the data key under a new master password
+func (v *Vault) ChangeMasterPassword(currentPassword, newPassword string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.contents == nil {
+		return ErrLocked
+	}
+
+	// Verify current password
+	if !v.verifyMasterPassword(currentPassword) {
+		return errors.New("current password is incorrect")
+	}
+
+	// Validate new password
+	if len(newPassword) < 8 {
+		return errors.New("new password must be at least 8 characters")
+	}
+
+	// Wrap the existing data key under the new password, upgrading to the
+	// current KDF cost; the vault contents stay as they are
+	header, err := NewPasswordHeader(newPassword, v.dataKey)
+	if err != nil {
+		return errors.New("failed to derive new master key")
+	}
+	header.LegacySalt = v.contents.Header.LegacySalt
+	header.LegacyKDF = v.contents.Header.LegacyKDF
+
+	err = v.store.SaveHeader(header)
+	if errors.Is(err, ErrVaultConflict) {
+		// Pick up the newer contents first so later saves don't overwrite them
+		if err = v.reload(); err == nil {
+			err = v.store.SaveHeader(header)
+		}
+	}
+	if err != nil {
+		return errors.New("failed to save vault with new password")
+	}
+
+	// Update in-memory references
+	v.contents.Header = header
+
+	return nil
+}
+
+// verifyMasterPassword unwraps the data key with a candidate password and
+// compares it with the key the vault is currently unlocked with
+func (v *Vault) verifyMasterPassword(password string) bool {
+	header, err := v.store.LoadHeader()
+	if err != nil {
+		return false
+	}
+
+	dataKey, err := header.UnlockDataKey(password)
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(dataKey, v.dataKey) == 1
+}
+
+// Delete locks the vault and permanently removes it from the store along
+// with its backups. It fails if another process has the vault open.
+func (v *Vault) Delete() error {
+	v.Lock()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// Refuse if another process has the vault open
+	if err := v.store.Lock(); err != nil {
+		return err
+	}
+	defer v.store.Unlock()
+
+	return v.store.DeleteVault()
+}
+
+// update applies a change to the vault and saves it. The change is made to
+// a copy of the contents that replaces them only once it is saved, so a
+// failed save (a read-only vault, a full disk) leaves the vault as it was.
+// If the store was modified since the vault was loaded (by a restore, a sync
+// tool or another instance), the newer contents are reloaded and the change
+// is applied again on top of them instead of overwriting them. The caller
+// holds the mutex.
+func (v *Vault) update(mutate func(c *Contents) error) error {
+	if v.contents == nil {
+		return ErrLocked
+	}
+
+	for rebased := false; ; rebased = true {
+		next := v.contents.clone()
+		if err := mutate(next); err != nil {
+			if errors.Is(err, errUnchanged) {
+				return nil
+			}
+			return err
+		}
+
+		err := v.store.SaveVault(next, v.dataKey)
+		if err == nil {
+			v.contents = next
+			return nil
+		}
+		if rebased || !errors.Is(err, ErrVaultConflict) {
+			return err
+		}
+
+		// Merge: rebase the change onto what is in the store now
+		if err := v.reload(); err != nil {
+			return err
+		}
+	}
+}
+
+// reload replaces the contents with what is in the store. If that can no
+// longer be decrypted with the current key the contents are kept.
+func (v *Vault) reload() error {
+	contents, err := v.store.LoadVault(v.dataKey)
+	if err != nil {
+		if v.OnConflict != nil {
+			v.OnConflict(err)
+		}
+		return errors.New("vault was changed on disk and could not be reloaded: " + err.Error())
+	}
+
+	v.contents = contents
+	if v.OnReload != nil {
+		v.OnReload()
+	}
+	return nil
+}
+
+// clone copies the contents so they can be changed without affecting c.
+// Update functions change items in place through pointers, custom fields
+// and equivalent domains included, so those lists are copied too.
+func (c *Contents) clone() *Contents {
+	next := &Contents{
+		Credentials: append([]Credential{}, c.Credentials...),
+		CreditCards: append([]CreditCard{}, c.CreditCards...),
+		SecureNotes: append([]SecureNote{}, c.SecureNotes...),
+		Identities:  append([]Identity{}, c.Identities...),
+		Header:      c.Header,
+	}
+	for i := range next.Credentials {
+		cred := &next.Credentials[i]
+		cred.EquivalentDomains = append([]string(nil), cred.EquivalentDomains...)
+		cred.Fields = append([]CustomField(nil), cred.Fields...)
+	}
+	for i := range next.CreditCards {
+		next.CreditCards[i].Fields = append([]CustomField(nil), next.CreditCards[i].Fields...)
+	}
+	for i := range next.SecureNotes {
+		next.SecureNotes[i].Fields = append([]CustomField(nil), next.SecureNotes[i].Fields...)
+	}
+	for i := range next.Identities {
+		next.Identities[i].Fields = append([]CustomField(nil), next.Identities[i].Fields...)
+	}
+	return next
+}
@@ -0,0 +1,424 @@
+package vault
+
+import (
+	"errors"
+	"testing"
+)
+
+const testPassword = "correct horse"
+
+// newTestVault returns an unlocked vault in a fresh memory store
+func newTestVault(t *testing.T) (*Vault, *MemoryStore) {
+	t.Helper()
+
+	store := NewMemoryStore(t.Name())
+	v := New(store)
+	if err := v.Create(testPassword); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	t.Cleanup(v.Lock)
+	return v, store
+}
+
+func TestCreateAndUnlock(t *testing.T) {
+	v, store := newTestVault(t)
+
+	if !v.IsUnlocked() || v.IsReadOnly() {
+		t.Fatal("new vault should be unlocked and writable")
+	}
+	if err := v.Create(testPassword); !errors.Is(err, ErrVaultExists) {
+		t.Fatalf("second Create: got %v, want ErrVaultExists", err)
+	}
+	if _, err := v.AddCredential(Credential{ServiceName: "GitHub", Username: "octocat"}); err != nil {
+		t.Fatal(err)
+	}
+
+	v.Lock()
+	if v.IsUnlocked() {
+		t.Fatal("vault still unlocked after Lock")
+	}
+	if _, err := v.Credentials(); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Credentials while locked: got %v, want ErrLocked", err)
+	}
+
+	reopened := New(store)
+	if err := reopened.Unlock("wrong password"); err == nil {
+		t.Fatal("unlocked with the wrong password")
+	}
+	if err := reopened.Unlock(testPassword); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	defer reopened.Lock()
+
+	credentials, err := reopened.Credentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credentials) != 1 || credentials[0].Username != "octocat" {
+		t.Fatalf("got credentials %+v", credentials)
+	}
+}
+
+func TestUnlockMissingVault(t *testing.T) {
+	v := New(NewMemoryStore(t.Name()))
+	if err := v.Unlock(testPassword); !errors.Is(err, ErrVaultNotFound) {
+		t.Fatalf("got %v, want ErrVaultNotFound", err)
+	}
+}
+
+func TestCredentialCRUD(t *testing.T) {
+	v, _ := newTestVault(t)
+
+	added, err := v.AddCredential(Credential{
+		ID:          "ignored",
+		ServiceName: "GitHub",
+		URL:         "https://github.com/login",
+		Username:    "octocat",
+		Password:    "hunter22",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.ID == "" || added.ID == "ignored" {
+		t.Errorf("AddCredential kept ID %q", added.ID)
+	}
+	if added.CreatedAt.IsZero() || added.IconURL == "" {
+		t.Errorf("AddCredential did not fill in CreatedAt and IconURL: %+v", added)
+	}
+
+	err = v.UpdateCredential(added.ID, func(cred *Credential) error {
+		cred.Password = "new password"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := v.Credential(added.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Password != "new password" {
+		t.Errorf("password after update = %q", got.Password)
+	}
+
+	// A failing change leaves the credential alone
+	changeErr := errors.New("rejected")
+	err = v.UpdateCredential(added.ID, func(cred *Credential) error {
+		return changeErr
+	})
+	if !errors.Is(err, changeErr) {
+		t.Errorf("UpdateCredential returned %v, want the change's error", err)
+	}
+
+	if err := v.UpdateCredential("missing", func(*Credential) error { return nil }); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("update of unknown ID: got %v", err)
+	}
+
+	if err := v.DeleteCredential(added.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Credential(added.ID); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Credential after delete: got %v", err)
+	}
+	if err := v.DeleteCredential(added.ID); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("second delete: got %v", err)
+	}
+}
+
+func TestCredentialsReturnsCopy(t *testing.T) {
+	v, _ := newTestVault(t)
+	if _, err := v.AddCredential(Credential{ServiceName: "GitHub"}); err != nil {
+		t.Fatal(err)
+	}
+
+	credentials, _ := v.Credentials()
+	credentials[0].ServiceName = "changed"
+
+	again, _ := v.Credentials()
+	if again[0].ServiceName != "GitHub" {
+		t.Fatal("changing the returned slice changed the vault")
+	}
+}
+
+func TestFailedUpdateLeavesFieldsAlone(t *testing.T) {
+	v, store := newTestVault(t)
+	cred, err := v.AddCredential(Credential{
+		ServiceName: "GitHub",
+		Fields:      []CustomField{{Label: "Recovery", Value: "1234"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A change made in place is dropped with the update that failed to save
+	store.SetReadOnly(true)
+	err = v.UpdateCredential(cred.ID, func(cred *Credential) error {
+		cred.Fields[0].Value = "changed"
+		return nil
+	})
+	if err == nil {
+		t.Fatal("saved to a read-only vault")
+	}
+	if saved, _ := v.Credential(cred.ID); saved.Fields[0].Value != "1234" {
+		t.Errorf("failed update changed the field to %q", saved.Fields[0].Value)
+	}
+}
+
+func TestCreditCardCRUD(t *testing.T) {
+	v, _ := newTestVault(t)
+
+	card, err := v.AddCreditCard(CreditCard{CardName: "Visa", CardNumber: "4111111111111111"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.UpdateCreditCard(card.ID, func(card *CreditCard) error {
+		card.IsFavorite = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cards, err := v.CreditCards()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 1 || !cards[0].IsFavorite {
+		t.Fatalf("got cards %+v", cards)
+	}
+
+	if err := v.DeleteCreditCard(card.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.CreditCard(card.ID); !errors.Is(err, ErrCreditCardNotFound) {
+		t.Errorf("CreditCard after delete: got %v", err)
+	}
+}
+
+func TestSecureNoteCRUD(t *testing.T) {
+	v, store := newTestVault(t)
+
+	note, err := v.AddSecureNote(SecureNote{Title: "Recovery codes", Body: "- 1234\n- 5678", Category: "Work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.ID == "" || note.CreatedAt.IsZero() || !note.UpdatedAt.Equal(note.CreatedAt) {
+		t.Fatalf("AddSecureNote returned %+v", note)
+	}
+
+	err = v.UpdateSecureNote(note.ID, func(note *SecureNote) error {
+		note.Body += "\n- 9012"
+		note.IsFavorite = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := v.SecureNote(note.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.IsFavorite || !updated.UpdatedAt.After(note.CreatedAt) {
+		t.Errorf("after update got %+v", updated)
+	}
+
+	// Notes are saved alongside the other items
+	v.Lock()
+	if err := v.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := v.SecureNotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].Body != "- 1234\n- 5678\n- 9012" {
+		t.Fatalf("reloaded notes %+v", notes)
+	}
+	if len(store.DamagedRecords()) != 0 {
+		t.Fatal("note records were damaged")
+	}
+
+	if err := v.DeleteSecureNote(note.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.SecureNote(note.ID); !errors.Is(err, ErrSecureNoteNotFound) {
+		t.Errorf("SecureNote after delete: got %v", err)
+	}
+}
+
+func TestIdentityCRUD(t *testing.T) {
+	v, store := newTestVault(t)
+
+	identity, err := v.AddIdentity(Identity{
+		Name:        "Home",
+		FirstName:   "Jane",
+		LastName:    "Doe",
+		DateOfBirth: "1990-04-01",
+		Address:     Address{Street1: "1 Main St", City: "Springfield", PostalCode: "12345", Country: "US"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.ID == "" || identity.CreatedAt.IsZero() {
+		t.Fatalf("AddIdentity returned %+v", identity)
+	}
+
+	err = v.UpdateIdentity(identity.ID, func(identity *Identity) error {
+		identity.Address.Street2 = "Apt 4"
+		identity.PassportNumber = "X1234567"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Identities are saved alongside the other items
+	v.Lock()
+	if err := v.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	identities, err := v.Identities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identities) != 1 || identities[0].Address.Street2 != "Apt 4" ||
+		identities[0].PassportNumber != "X1234567" || identities[0].Address.City != "Springfield" {
+		t.Fatalf("reloaded identities %+v", identities)
+	}
+	if len(store.DamagedRecords()) != 0 {
+		t.Fatal("identity records were damaged")
+	}
+
+	if err := v.DeleteIdentity(identity.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Identity(identity.ID); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("Identity after delete: got %v", err)
+	}
+}
+
+func TestUpdateRebasesOnConflict(t *testing.T) {
+	v, store := newTestVault(t)
+	if _, err := v.AddCredential(Credential{ServiceName: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	earlier := store.raw
+	if _, err := v.AddCredential(Credential{ServiceName: "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A sync tool puts the earlier generation back behind the vault's back
+	store.Replace(earlier)
+	reloaded := false
+	v.OnReload = func() { reloaded = true }
+
+	if _, err := v.AddCredential(Credential{ServiceName: "third"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded {
+		t.Error("OnReload was not called")
+	}
+
+	// The change is applied on top of what is in the store now
+	credentials, _ := v.Credentials()
+	var names []string
+	for _, cred := range credentials {
+		names = append(names, cred.ServiceName)
+	}
+	if len(names) != 2 || names[0] != "first" || names[1] != "third" {
+		t.Fatalf("got credentials %v after rebase, want [first third]", names)
+	}
+}
+
+func TestConflictWithUnreadableChange(t *testing.T) {
+	v, store := newTestVault(t)
+
+	// Someone recreated the vault under a different data key
+	replacement := New(NewMemoryStore("replacement"))
+	if err := replacement.Create(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	store.Replace(replacement.store.(*MemoryStore).raw)
+
+	var conflict error
+	v.OnConflict = func(err error) { conflict = err }
+
+	if _, err := v.AddCredential(Credential{ServiceName: "lost"}); err == nil {
+		t.Fatal("saved over a vault encrypted with another key")
+	}
+	if conflict == nil {
+		t.Error("OnConflict was not called")
+	}
+}
+
+func TestChangeMasterPassword(t *testing.T) {
+	v, store := newTestVault(t)
+	if _, err := v.AddCredential(Credential{ServiceName: "GitHub"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.ChangeMasterPassword("wrong password", "another password"); err == nil {
+		t.Error("changed the password without the current one")
+	}
+	if err := v.ChangeMasterPassword(testPassword, "short"); err == nil {
+		t.Error("accepted a short new password")
+	}
+	if err := v.ChangeMasterPassword(testPassword, "another password"); err != nil {
+		t.Fatal(err)
+	}
+	v.Lock()
+
+	reopened := New(store)
+	if err := reopened.Unlock(testPassword); err == nil {
+		t.Fatal("old password still unlocks the vault")
+	}
+	if err := reopened.Unlock("another password"); err != nil {
+		t.Fatalf("Unlock with the new password: %v", err)
+	}
+	defer reopened.Lock()
+	if credentials, _ := reopened.Credentials(); len(credentials) != 1 {
+		t.Fatalf("got %d credentials, want 1", len(credentials))
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	v, _ := newTestVault(t)
+	first, err := v.AddCredential(Credential{ServiceName: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.AddSecureNote(SecureNote{Title: "later"}); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := v.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("got %d backups, want 2", len(backups))
+	}
+	// Newest first: the generation holding only the first credential
+	if !backups[0].Readable || backups[0].Credentials != 1 || backups[0].SecureNotes != 0 {
+		t.Fatalf("newest backup = %+v", backups[0])
+	}
+
+	if err := v.RestoreBackup(backups[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	credentials, _ := v.Credentials()
+	notes, _ := v.SecureNotes()
+	if len(credentials) != 1 || credentials[0].ID != first.ID || len(notes) != 0 {
+		t.Fatalf("after restore got %+v and %+v", credentials, notes)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	v, _ := newTestVault(t)
+	if err := v.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if v.IsUnlocked() || v.Exists() {
+		t.Fatal("vault still unlocked or present after Delete")
+	}
+}
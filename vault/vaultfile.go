@@ -0,0 +1,146 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	vaultMagic         = "VAULTZERO"
+	vaultFormatVersion = 3
+)
+
+// VaultHeader is the unencrypted, self-describing part of a vault file.
+// The vault contents are encrypted with a random data key; the header
+// carries everything needed to re-derive the key-encryption key from the
+// master password and unwrap that data key.
+type VaultHeader struct {
+	Magic   string    `json:"magic"`
+	Version int       `json:"version"`
+	KDF     KDFParams `json:"kdf"`
+	Salt    []byte    `json:"salt"`
+
+	// KeyCheck lets a candidate master key be verified without decrypting
+	// the vault. Headers written before it existed leave it empty.
+	KeyCheck []byte `json:"keyCheck,omitempty"`
+
+	// WrappedKey is the data key encrypted with the key-encryption key.
+	// Version 1 headers have none; their contents are encrypted with the
+	// password-derived key directly.
+	WrappedKey string `json:"wrappedKey,omitempty"`
+
+	// LegacySalt and LegacyKDF are what the password key was derived with
+	// before the vault moved to a data key. Encrypted backups exported then
+	// are sealed with that key, so they are kept to import those backups.
+	LegacySalt []byte     `json:"legacySalt,omitempty"`
+	LegacyKDF  *KDFParams `json:"legacyKdf,omitempty"`
+}
+
+// vaultFile is the on-disk container: header plus encrypted vault contents.
+// Version 3 files seal every item separately (see records.go); older files
+// hold all items in a single encrypted Data blob.
+type vaultFile struct {
+	VaultHeader
+	Data    string            `json:"data,omitempty"`
+	Index   string            `json:"index,omitempty"`
+	Records map[string]string `json:"records,omitempty"`
+}
+
+// NewVaultHeader creates a header for the current format version
+func NewVaultHeader(salt []byte, kdf KDFParams) *VaultHeader {
+	return &VaultHeader{
+		Magic:   vaultMagic,
+		Version: vaultFormatVersion,
+		KDF:     kdf,
+		Salt:    salt,
+	}
+}
+
+// NewPasswordHeader derives a fresh key-encryption key from the master
+// password and wraps the data key with it
+func NewPasswordHeader(password string, dataKey []byte) (*VaultHeader, error) {
+	salt, err := GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	kdf := DefaultKDFParams()
+	kek, err := DeriveKey(password, salt, kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := WrapKey(dataKey, kek)
+	if err != nil {
+		return nil, err
+	}
+
+	header := NewVaultHeader(salt, kdf)
+	header.KeyCheck = KeyCheckValue(kek)
+	header.WrappedKey = wrapped
+	return header, nil
+}
+
+// UnlockDataKey derives the key-encryption key from the master password and
+// returns the data key that encrypts the vault contents
+func (h *VaultHeader) UnlockDataKey(password string) ([]byte, error) {
+	kek, err := DeriveKey(password, h.Salt, h.KDF)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject a wrong password up front when the header carries a key check
+	if len(h.KeyCheck) > 0 && !VerifyKeyCheck(kek, h.KeyCheck) {
+		return nil, errors.New("invalid master password")
+	}
+
+	// Older vaults encrypt their contents with the derived key itself
+	if h.WrappedKey == "" {
+		return kek, nil
+	}
+
+	dataKey, err := UnwrapKey(h.WrappedKey, kek)
+	if err != nil {
+		return nil, errors.New("invalid master password or corrupted vault key")
+	}
+	return dataKey, nil
+}
+
+// isVaultContainer reports whether raw file data uses the header format.
+// Legacy vault.dat files are a bare base64 string and never start with '{'.
+func isVaultContainer(raw []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{"))
+}
+
+// encodeVaultFile serializes a vault container
+func encodeVaultFile(file *vaultFile) ([]byte, error) {
+	return json.Marshal(file)
+}
+
+// decodeVaultFile parses and validates a vault container
+func decodeVaultFile(raw []byte) (*vaultFile, error) {
+	var file vaultFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, errors.New("vault header is corrupted")
+	}
+
+	if file.Magic != vaultMagic {
+		return nil, errors.New("not a VaultZero vault file")
+	}
+	if file.Version < 1 || file.Version > vaultFormatVersion {
+		return nil, fmt.Errorf("unsupported vault format version %d", file.Version)
+	}
+	if file.Data == "" && file.Index == "" {
+		return nil, errors.New("vault file has no contents")
+	}
+	if len(file.Salt) == 0 {
+		return nil, errors.New("vault header has no salt")
+	}
+	if err := file.KDF.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}